@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type AttendeeStatus string
+
+const (
+	StatusPending   AttendeeStatus = "pending"
+	StatusAccepted  AttendeeStatus = "accepted"
+	StatusDeclined  AttendeeStatus = "declined"
+	StatusTentative AttendeeStatus = "tentative"
+)
+
+var (
+	ErrNotOrganizer          = errors.New("only the organizer can modify the event")
+	ErrAttendeeStatusInvalid = errors.New("invalid attendee status")
+)
+
+func (s AttendeeStatus) valid() bool {
+	switch s {
+	case StatusPending, StatusAccepted, StatusDeclined, StatusTentative:
+		return true
+	}
+	return false
+}
+
+type Attendee struct {
+	UserID int            `json:"user_id"`
+	Status AttendeeStatus `json:"status"`
+}
+
+// WithAttendees приглашает пользователей на событие. Новые участники получают
+// статус pending, у уже приглашённых UpdateEvent сохраняет ответ. Организатор
+// и повторы в списке пропускаются.
+func WithAttendees(userIDs ...int) EventOption {
+	return func(e *Event) {
+		e.Attendees = make([]Attendee, 0, len(userIDs))
+		seen := make(map[int]bool, len(userIDs))
+		for _, userID := range userIDs {
+			if userID == e.UserID || seen[userID] {
+				continue
+			}
+			seen[userID] = true
+			e.Attendees = append(e.Attendees, Attendee{UserID: userID, Status: StatusPending})
+		}
+	}
+}
+
+// keepResponses переносит ответы участников из previous в attendees.
+func keepResponses(attendees, previous []Attendee) {
+	for i := range attendees {
+		for _, p := range previous {
+			if p.UserID == attendees[i].UserID {
+				attendees[i].Status = p.Status
+				break
+			}
+		}
+	}
+}
+
+func (e Event) attendee(userID int) (Attendee, bool) {
+	for _, a := range e.Attendees {
+		if a.UserID == userID {
+			return a, true
+		}
+	}
+	return Attendee{}, false
+}
+
+// viewFor - событие глазами приглашённого: с его ответом в ResponseStatus.
+func (e Event) viewFor(userID int) Event {
+	if a, ok := e.attendee(userID); ok {
+		e.ResponseStatus = a.Status
+	}
+	return e
+}
+
+// RespondToInvitation записывает ответ участника. Ответ на серию применяется
+// и к её отделённым вхождениям, где участник тоже приглашён.
+func (c *Calendar) RespondToInvitation(id, userID int, status AttendeeStatus) (Event, error) {
+	if !status.valid() {
+		return Event{}, fmt.Errorf("%w: %q", ErrAttendeeStatusInvalid, status)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	event, ok := c.invitation(id, userID)
+	if !ok {
+		return Event{}, ErrEventNotFound
+	}
+
+	c.actor = userID
+	defer func() { c.actor = 0 }()
+
+	updated, err := c.setResponse(event, userID, status)
+	if err != nil {
+		return Event{}, err
+	}
+
+	if event.Recurrence != nil {
+		organizer := c.users[event.UserID]
+		for _, detachedID := range append([]int(nil), organizer.byUID[event.UID]...) {
+			detached := organizer.byID[detachedID]
+			if _, invited := detached.attendee(userID); !invited || detached.SeriesID != event.ID {
+				continue
+			}
+			if _, err := c.setResponse(detached, userID, status); err != nil {
+				return Event{}, err
+			}
+		}
+	}
+	return updated.viewFor(userID), nil
+}
+
+func (c *Calendar) setResponse(event Event, userID int, status AttendeeStatus) (Event, error) {
+	updated := event
+	updated.Attendees = append([]Attendee(nil), event.Attendees...)
+	for i := range updated.Attendees {
+		if updated.Attendees[i].UserID == userID {
+			updated.Attendees[i].Status = status
+		}
+	}
+	return c.replace(event, updated)
+}
+
+// Invitations возвращает события, куда приглашён пользователь, без
+// разворачивания серий; пустой status - с любым ответом.
+func (c *Calendar) Invitations(userID int, status AttendeeStatus) []Event {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	user, ok := c.users[userID]
+	if !ok {
+		return nil
+	}
+
+	var result []Event
+	for id := range user.invited {
+		event, _ := c.invitation(id, userID)
+		if event = event.viewFor(userID); status == "" || event.ResponseStatus == status {
+			result = append(result, event)
+		}
+	}
+	sortEvents(result)
+	return result
+}
+
+// invitation ищет чужое событие, на которое приглашён пользователь.
+func (c *Calendar) invitation(id, userID int) (Event, bool) {
+	user, ok := c.users[userID]
+	if !ok {
+		return Event{}, false
+	}
+	organizerID, ok := user.invited[id]
+	if !ok {
+		return Event{}, false
+	}
+	return c.find(id, organizerID)
+}
+
+// invitedBetween разворачивает приглашения пользователя в [from, to).
+func (c *Calendar) invitedBetween(user *userEvents, userID int, from, to time.Time) []Event {
+	var result []Event
+	for id, organizerID := range user.invited {
+		event, ok := c.find(id, organizerID)
+		if !ok || !c.users[organizerID].mayOverlap(event, from, to) {
+			continue
+		}
+		for _, e := range expand(event, from, to) {
+			result = append(result, e.viewFor(userID))
+		}
+	}
+	return result
+}
+
+// notFound объясняет, почему пользователь не может изменить событие: оно
+// либо не существует, либо принадлежит другому организатору.
+func (c *Calendar) notFound(id, userID int) error {
+	if _, invited := c.invitation(id, userID); invited {
+		return ErrNotOrganizer
+	}
+	return ErrEventNotFound
+}
+
+func (c *Calendar) addInvites(event Event) {
+	for _, a := range event.Attendees {
+		c.user(a.UserID).invited[event.ID] = event.UserID
+	}
+}
+
+func (c *Calendar) removeInvites(event Event) {
+	for _, a := range event.Attendees {
+		if user, ok := c.users[a.UserID]; ok {
+			delete(user.invited, event.ID)
+		}
+	}
+}
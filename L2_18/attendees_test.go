@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCalendar_Invitations(t *testing.T) {
+	calendar := NewCalendar()
+	date := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	event, err := calendar.CreateEvent(1, date, "Planning", WithDuration(time.Hour), WithAttendees(2, 3, 2, 1))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(event.Attendees) != 2 {
+		t.Fatalf("Expected organizer and duplicates to be skipped, got %+v", event.Attendees)
+	}
+
+	events := calendar.GetEventsForDay(2, date)
+	if len(events) != 1 || events[0].ResponseStatus != StatusPending {
+		t.Fatalf("Expected pending invitation in attendee's day, got %+v", events)
+	}
+
+	if _, err := calendar.RespondToInvitation(event.ID, 2, StatusAccepted); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := calendar.RespondToInvitation(event.ID, 4, StatusAccepted); err != ErrEventNotFound {
+		t.Errorf("Expected ErrEventNotFound for uninvited user, got %v", err)
+	}
+	if _, err := calendar.RespondToInvitation(event.ID, 2, "maybe"); err == nil {
+		t.Error("Expected error for invalid status")
+	}
+
+	// редактировать может только организатор
+	if _, err := calendar.UpdateEvent(event.ID, 2, date, "Hijacked"); err != ErrNotOrganizer {
+		t.Errorf("Expected ErrNotOrganizer, got %v", err)
+	}
+	if err := calendar.DeleteEvent(event.ID, 3); err != ErrNotOrganizer {
+		t.Errorf("Expected ErrNotOrganizer on delete, got %v", err)
+	}
+
+	// организатор меняет время: ответы сохраняются, убранный участник теряет событие
+	updated, err := calendar.UpdateEvent(event.ID, 1, date.Add(time.Hour), "Planning", WithAttendees(2))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if a, _ := updated.attendee(2); a.Status != StatusAccepted {
+		t.Errorf("Expected accepted response to be kept, got %s", a.Status)
+	}
+	if events := calendar.GetEventsForDay(3, date); len(events) != 0 {
+		t.Errorf("Expected removed attendee to lose the event, got %d", len(events))
+	}
+	if invitations := calendar.Invitations(2, StatusAccepted); len(invitations) != 1 {
+		t.Errorf("Expected 1 accepted invitation, got %d", len(invitations))
+	}
+
+	calendar.DeleteEvent(event.ID, 1)
+	if events := calendar.GetEventsForDay(2, date.Add(time.Hour)); len(events) != 0 {
+		t.Errorf("Expected deleted event to disappear for attendee, got %d", len(events))
+	}
+}
+
+func TestCalendar_SeriesInvitation(t *testing.T) {
+	calendar := NewCalendar()
+	date := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	series, _ := calendar.CreateEvent(1, date, "Standup", WithDuration(15*time.Minute),
+		WithRecurrence(&Recurrence{Freq: FreqDaily}), WithAttendees(2))
+	detached, err := calendar.UpdateOccurrence(series.ID, 1, date.AddDate(0, 0, 1), date.AddDate(0, 0, 1).Add(time.Hour), "Late standup")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(detached.Attendees) != 1 {
+		t.Fatalf("Expected detached occurrence to inherit attendees, got %+v", detached.Attendees)
+	}
+
+	calendar.RespondToInvitation(series.ID, 2, StatusDeclined)
+
+	events := calendar.GetEventsForWeek(2, date)
+	if len(events) != 7 {
+		t.Fatalf("Expected 7 occurrences for attendee, got %d", len(events))
+	}
+	for _, e := range events {
+		if e.ResponseStatus != StatusDeclined {
+			t.Errorf("Expected declined on %s, got %s", e.Date, e.ResponseStatus)
+		}
+	}
+}
+
+func TestCalendar_InvitationsAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	storage, _ := NewFileStorage(dir, 100)
+	calendar, _ := NewCalendarWithStorage(storage)
+
+	date := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(1, date, "Review", WithAttendees(2))
+	calendar.Close()
+
+	storage, _ = NewFileStorage(dir, 100)
+	calendar, err := NewCalendarWithStorage(storage)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if events := calendar.GetEventsForDay(2, date); len(events) != 1 {
+		t.Errorf("Expected invitation to survive restart, got %d", len(events))
+	}
+}
+
+func TestHandler_RSVP(t *testing.T) {
+	calendar := NewCalendar()
+	r := mux.NewRouter()
+	NewHandler(calendar).RegisterRoutes(r)
+
+	post := func(path string, form url.Values) int {
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := post("/create_event", url.Values{"user_id": {"1"}, "date": {"2024-01-01"}, "title": {"Offsite"}, "attendees": {"2,3"}}); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if code := post("/rsvp", url.Values{"id": {"1"}, "user_id": {"2"}, "status": {"tentative"}}); code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", code)
+	}
+	if code := post("/update_event", url.Values{"id": {"1"}, "user_id": {"2"}, "date": {"2024-01-02"}, "title": {"x"}}); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for attendee update, got %d", code)
+	}
+
+	if invitations := calendar.Invitations(2, StatusTentative); len(invitations) != 1 {
+		t.Errorf("Expected tentative invitation, got %d", len(invitations))
+	}
+}
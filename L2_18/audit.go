@@ -0,0 +1,350 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type AuditAction string
+
+const (
+	AuditCreated  AuditAction = "created"
+	AuditUpdated  AuditAction = "updated"
+	AuditDeleted  AuditAction = "deleted"
+	AuditRestored AuditAction = "restored"
+	AuditReverted AuditAction = "reverted"
+)
+
+var (
+	ErrEventNotDeleted = errors.New("event is not deleted")
+	ErrVersionNotFound = errors.New("event version not found in history")
+)
+
+// AuditEntry - одно изменение события: кто, когда и что было до и после.
+// Before пуст у созданий и восстановлений, After - у удалений; после очистки
+// корзины снимков нет ни у одной записи события.
+type AuditEntry struct {
+	Seq     int64       `json:"seq"`
+	Action  AuditAction `json:"action"`
+	EventID int         `json:"event_id"`
+	UserID  int         `json:"user_id"`
+	Actor   int         `json:"actor"`
+	At      time.Time   `json:"at"`
+	Before  *Event      `json:"before,omitempty"`
+	After   *Event      `json:"after,omitempty"`
+}
+
+// auditEntry готовит запись аудита для мутации; в историю она попадает через
+// addHistory, когда мутация записана.
+func (c *Calendar) auditEntry(action AuditAction, before, after *Event) *AuditEntry {
+	event := after
+	if event == nil {
+		event = before
+	}
+	actor := c.actor
+	if actor == 0 {
+		actor = event.UserID
+	}
+	return &AuditEntry{
+		Seq:     c.auditSeq + 1,
+		Action:  action,
+		EventID: event.ID,
+		UserID:  event.UserID,
+		Actor:   actor,
+		At:      c.now(),
+		Before:  before,
+		After:   after,
+	}
+}
+
+func (c *Calendar) addHistory(entry *AuditEntry) {
+	c.auditSeq = entry.Seq
+	c.history[entry.EventID] = append(c.history[entry.EventID], *entry)
+	c.onRollback(func() {
+		c.auditSeq--
+		entries := c.history[entry.EventID]
+		if len(entries) == 1 {
+			delete(c.history, entry.EventID)
+		} else {
+			c.history[entry.EventID] = entries[:len(entries)-1]
+		}
+	})
+}
+
+// History возвращает изменения события от старых к новым. История удалённых
+// событий тоже доступна владельцу.
+func (c *Calendar) History(id, userID int) ([]AuditEntry, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entries := c.history[id]
+	if len(entries) == 0 || entries[0].UserID != userID {
+		return nil, c.notFound(id, userID)
+	}
+	return append([]AuditEntry(nil), entries...), nil
+}
+
+// RestoreEvent возвращает удалённое событие из корзины в том виде, каким оно
+// было перед удалением, с прежним ID. Вместе с серией восстанавливаются отделённые
+// вхождения, удалённые вместе с ней.
+func (c *Calendar) RestoreEvent(id, userID int) (Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.find(id, userID); ok {
+		return Event{}, ErrEventNotDeleted
+	}
+	deletion, ok := c.lastDeletion(id, userID)
+	if !ok || c.expired(deletion.At, c.now()) {
+		return Event{}, ErrEventNotFound
+	}
+
+	event, err := snapshotEvent(*deletion.Before)
+	if err != nil {
+		return Event{}, err
+	}
+	if event.SeriesID != 0 {
+		if _, ok := c.find(event.SeriesID, userID); !ok {
+			// вхождение без серии не имеет смысла: восстанавливать нужно серию
+			return Event{}, ErrEventNotFound
+		}
+	} else if _, exists := c.masterByUID(userID, event.UID); exists {
+		return Event{}, ErrUIDConflict
+	}
+
+	restored, err := c.reinsert(event)
+	if err != nil {
+		return Event{}, err
+	}
+	if restored.Recurrence == nil {
+		return restored, nil
+	}
+
+	var detached []AuditEntry
+	for detachedID := range c.history {
+		if entry, ok := c.lastDeletion(detachedID, userID); ok && entry.Before.SeriesID == id && entry.Seq > deletion.Seq {
+			detached = append(detached, entry)
+		}
+	}
+	sort.Slice(detached, func(i, j int) bool {
+		return detached[i].Seq < detached[j].Seq
+	})
+	for _, entry := range detached {
+		event, err := snapshotEvent(*entry.Before)
+		if err != nil {
+			return Event{}, err
+		}
+		if _, err := c.reinsert(event); err != nil {
+			return Event{}, err
+		}
+	}
+	return restored, nil
+}
+
+// lastDeletion возвращает запись об удалении события, если событие удалено
+// последним изменением.
+func (c *Calendar) lastDeletion(id, userID int) (AuditEntry, bool) {
+	entries := c.history[id]
+	if len(entries) == 0 {
+		return AuditEntry{}, false
+	}
+	last := entries[len(entries)-1]
+	if last.Action != AuditDeleted || last.UserID != userID || last.Before == nil {
+		return AuditEntry{}, false
+	}
+	return last, true
+}
+
+// RevertEvent возвращает событию содержимое его версии version. Откат - это
+// новая редакция с новой версией; ответы участников, исключённые из серии
+// даты и календарь сохраняются текущими.
+func (c *Calendar) RevertEvent(id, userID, version int) (Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current, ok := c.find(id, userID)
+	if !ok {
+		return Event{}, c.notFound(id, userID)
+	}
+	if version == current.Version {
+		return current, nil
+	}
+
+	var snapshot *Event
+	for _, entry := range c.history[id] {
+		if entry.After != nil && entry.After.Version == version {
+			snapshot = entry.After
+		}
+	}
+	if snapshot == nil {
+		return Event{}, ErrVersionNotFound
+	}
+
+	reverted, err := snapshotEvent(*snapshot)
+	if err != nil {
+		return Event{}, err
+	}
+	if reverted.UID != current.UID {
+		if _, exists := c.masterByUID(userID, reverted.UID); exists {
+			return Event{}, ErrUIDConflict
+		}
+	}
+	reverted.Attendees = append([]Attendee(nil), reverted.Attendees...)
+	keepResponses(reverted.Attendees, current.Attendees)
+	reverted.SeriesID = current.SeriesID
+	reverted.RecurrenceID = current.RecurrenceID
+	reverted.CalendarID = current.CalendarID
+	if reverted.Recurrence != nil && current.Recurrence != nil {
+		reverted.Recurrence = reverted.Recurrence.clone()
+		for _, ex := range current.Recurrence.ExDates {
+			if !reverted.Recurrence.isExcluded(ex) {
+				reverted.Recurrence.ExDates = append(reverted.Recurrence.ExDates, ex)
+			}
+		}
+	}
+	return c.replaceAs(AuditReverted, current, reverted)
+}
+
+// snapshotEvent готовит снимок из истории к повторному сохранению: после
+// загрузки из хранилища у его дат нет часового пояса события.
+func snapshotEvent(event Event) (Event, error) {
+	event.Recurrence = event.Recurrence.clone()
+	if err := event.normalizeLoaded(); err != nil {
+		return Event{}, err
+	}
+	return event, nil
+}
+
+// reinsert сохраняет удалённое событие под прежним ID.
+func (c *Calendar) reinsert(event Event) (Event, error) {
+	event.Version++
+	if _, ok := c.calendars[event.CalendarID]; !ok {
+		// календарь удалили, пока событие было удалено
+		event.CalendarID = 0
+	}
+	entry := c.auditEntry(AuditRestored, nil, &event)
+	if err := c.apply(Mutation{Op: OpPut, Event: event, NextID: c.nextID, Audit: entry}); err != nil {
+		return Event{}, err
+	}
+	c.addHistory(entry)
+	c.user(event.UserID).add(event)
+	c.addInvites(event)
+	c.publish(ChangeCreated, event)
+	c.onRollback(func() {
+		c.user(event.UserID).remove(event)
+		c.removeInvites(event)
+	})
+	return event, nil
+}
+
+// eventHistory - GET /events/history?user_id=&id=.
+func (h *Handler) eventHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userID, err := strconv.Atoi(query.Get("user_id"))
+	if err != nil {
+		writeCalendarError(w, r, invalidField("user_id", errors.New("must be an integer")))
+		return
+	}
+	id, err := strconv.Atoi(query.Get("id"))
+	if err != nil {
+		writeCalendarError(w, r, invalidField("id", errors.New("must be an integer")))
+		return
+	}
+
+	entries, err := h.calendar.History(id, userID)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeJSON(w, response{Result: entries}, http.StatusOK)
+}
+
+// restoreEvent - POST /events/restore с user_id и id удалённого события.
+func (h *Handler) restoreEvent(w http.ResponseWriter, r *http.Request) {
+	var req eventRequest
+	if err := parseRequest(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	event, err := h.calendar.RestoreEvent(req.ID, req.UserID)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeJSON(w, response{Result: event}, http.StatusOK)
+}
+
+// revertEvent - POST /events/revert с user_id, id и version, к которой
+// вернуть событие.
+func (h *Handler) revertEvent(w http.ResponseWriter, r *http.Request) {
+	var req eventRequest
+	if err := parseRequest(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if req.Version < 1 {
+		writeCalendarError(w, r, invalidField("version", errors.New("must be a positive integer")))
+		return
+	}
+
+	event, err := h.calendar.RevertEvent(req.ID, req.UserID, req.Version)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeJSON(w, response{Result: event}, http.StatusOK)
+}
+
+func (h *Handler) v2EventHistory(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["uid"])
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	entries, err := h.calendar.History(id, userID)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeJSON(w, entries, http.StatusOK)
+}
+
+func (h *Handler) v2RestoreEvent(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["uid"])
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	event, err := h.calendar.RestoreEvent(id, userID)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeEventV2(w, event, http.StatusOK)
+}
+
+func (h *Handler) v2RevertEvent(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["uid"])
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var req struct {
+		Version int `json:"version"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if req.Version < 1 {
+		writeCalendarError(w, r, invalidField("version", errors.New("must be a positive integer")))
+		return
+	}
+
+	event, err := h.calendar.RevertEvent(id, userID, req.Version)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeEventV2(w, event, http.StatusOK)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCalendar_HistoryAndRevert(t *testing.T) {
+	calendar := NewCalendar()
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	event, _ := calendar.CreateEvent(1, date, "Draft", WithAttendees(2))
+	calendar.UpdateEvent(event.ID, 1, date, "Final", WithAttendees(2))
+	calendar.RespondToInvitation(event.ID, 2, StatusAccepted)
+
+	history, err := calendar.History(event.ID, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(history))
+	}
+	if history[0].Action != AuditCreated || history[0].Before != nil || history[0].After.Title != "Draft" {
+		t.Errorf("Unexpected create entry: %+v", history[0])
+	}
+	if history[1].Before.Title != "Draft" || history[1].After.Title != "Final" || history[1].Actor != 1 {
+		t.Errorf("Unexpected update entry: %+v", history[1])
+	}
+	if history[2].Actor != 2 {
+		t.Errorf("Expected RSVP to be attributed to the attendee, got actor %d", history[2].Actor)
+	}
+	if _, err := calendar.History(event.ID, 3); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound for another user, got %v", err)
+	}
+
+	reverted, err := calendar.RevertEvent(event.ID, 1, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if reverted.Title != "Draft" || reverted.Version != 4 {
+		t.Errorf("Expected Draft at version 4, got %q v%d", reverted.Title, reverted.Version)
+	}
+	if reverted.Attendees[0].Status != StatusAccepted {
+		t.Errorf("Expected attendee response to survive revert, got %q", reverted.Attendees[0].Status)
+	}
+	if _, err := calendar.RevertEvent(event.ID, 1, 42); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("Expected ErrVersionNotFound, got %v", err)
+	}
+}
+
+func TestCalendar_RestoreSeries(t *testing.T) {
+	calendar := NewCalendar()
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	series, _ := calendar.CreateEvent(1, start, "Standup", WithRecurrence(&Recurrence{Freq: FreqDaily, Count: 5}))
+	detached, _ := calendar.UpdateOccurrence(series.ID, 1, start.AddDate(0, 0, 1), start.AddDate(0, 0, 1).Add(time.Hour), "Late standup")
+	calendar.DeleteEvent(series.ID, 1)
+
+	if _, err := calendar.RestoreEvent(detached.ID, 1); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected occurrence restore without series to fail, got %v", err)
+	}
+
+	restored, err := calendar.RestoreEvent(series.ID, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if restored.ID != series.ID {
+		t.Errorf("Expected original ID %d, got %d", series.ID, restored.ID)
+	}
+	if events := calendar.GetEventsInRange(1, start, start.AddDate(0, 0, 5)); len(events) != 5 || events[1].Title != "Late standup" {
+		t.Errorf("Expected series with its detached occurrence back, got %+v", events)
+	}
+	if _, err := calendar.RestoreEvent(series.ID, 1); !errors.Is(err, ErrEventNotDeleted) {
+		t.Errorf("Expected ErrEventNotDeleted, got %v", err)
+	}
+
+	history, _ := calendar.History(series.ID, 1)
+	if last := history[len(history)-1]; last.Action != AuditRestored {
+		t.Errorf("Expected restored entry, got %s", last.Action)
+	}
+}
+
+func TestCalendar_BatchRollbackLeavesNoHistory(t *testing.T) {
+	calendar := NewCalendar()
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	_, err := calendar.Batch([]BatchOp{
+		{Op: BatchCreate, UserID: 1, Date: date, Title: "A"},
+		{Op: BatchDelete, ID: 99, UserID: 1},
+	})
+	if err == nil {
+		t.Fatal("Expected batch to fail")
+	}
+	if _, err := calendar.History(1, 1); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected no history after rollback, got %v", err)
+	}
+
+	event, _ := calendar.CreateEvent(1, date, "B")
+	if history, _ := calendar.History(event.ID, 1); len(history) != 1 || history[0].Seq != 1 {
+		t.Errorf("Expected audit sequence to restart at 1, got %+v", history)
+	}
+}
+
+func TestFileStorage_HistorySurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.FixedZone("", 0))
+
+	storage, _ := NewFileStorage(dir, 2)
+	calendar, _ := NewCalendarWithStorage(storage)
+	event, _ := calendar.CreateEvent(1, date, "Keep me", WithTimezone("Europe/Berlin"))
+	calendar.UpdateEvent(event.ID, 1, date, "Keep me too", WithTimezone("Europe/Berlin"))
+	calendar.DeleteEvent(event.ID, 1)
+	calendar.Close()
+
+	storage, _ = NewFileStorage(dir, 2)
+	calendar, err := NewCalendarWithStorage(storage)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer calendar.Close()
+
+	if history, _ := calendar.History(event.ID, 1); len(history) != 3 {
+		t.Fatalf("Expected 3 entries after restart, got %d", len(history))
+	}
+	restored, err := calendar.RestoreEvent(event.ID, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if restored.Title != "Keep me too" || restored.Date.Location().String() != "Europe/Berlin" {
+		t.Errorf("Expected last version in its timezone, got %q at %v", restored.Title, restored.Date)
+	}
+}
+
+func TestHandler_RestoreEvent(t *testing.T) {
+	calendar := NewCalendar()
+	event, _ := calendar.CreateEvent(1, time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), "Oops")
+	calendar.DeleteEvent(event.ID, 1)
+
+	r := mux.NewRouter()
+	NewHandler(calendar).RegisterRoutes(r)
+
+	form := url.Values{"user_id": {"1"}, "id": {"1"}}
+	req := httptest.NewRequest("POST", "/events/restore", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v2/users/1/events/1/history", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"action":"restored"`) {
+		t.Errorf("Expected history with restore, got %d: %s", rec.Code, rec.Body)
+	}
+
+	req = httptest.NewRequest("POST", "/api/v2/users/1/events/1/revert", strings.NewReader(`{"version":7}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown version, got %d: %s", rec.Code, rec.Body)
+	}
+}
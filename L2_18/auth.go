@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	ErrUnauthenticated = errors.New("missing or invalid bearer token")
+	ErrForbidden       = errors.New("user_id does not match authenticated user")
+)
+
+// maxAuthPeekBody - сколько тела запроса middleware готов прочитать, чтобы
+// найти в JSON user_id.
+const maxAuthPeekBody = 1 << 20
+
+type Principal struct {
+	UserID int
+}
+
+type Authenticator interface {
+	Authenticate(token string) (Principal, error)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// AuthMiddleware требует заголовок Authorization: Bearer <token> и отклоняет
+// запросы, в которых user_id (в query, форме, JSON-теле или пути) не совпадает
+// с аутентифицированным пользователем. Пути из public пропускаются без проверки.
+func AuthMiddleware(auth Authenticator, public ...string) mux.MiddlewareFunc {
+	skip := make(map[string]bool, len(public))
+	for _, path := range public {
+		skip[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := bearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="calendar"`)
+				writeError(w, ErrUnauthenticated.Error(), http.StatusUnauthorized)
+				return
+			}
+			principal, err := auth.Authenticate(token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="calendar", error="invalid_token"`)
+				writeError(w, ErrUnauthenticated.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			userIDs, err := requestUserIDs(r)
+			if err != nil {
+				writeBodyError(w, err)
+				return
+			}
+			for _, userID := range userIDs {
+				if userID != principal.UserID {
+					writeError(w, ErrForbidden.Error(), http.StatusForbidden)
+					return
+				}
+			}
+			if len(userIDs) == 0 {
+				withPrincipalUser(r, principal.UserID)
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// bearerToken достаёт токен из Authorization. GET-запросы могут передать его
+// в access_token (RFC 6750): EventSource и WebSocket в браузере не умеют
+// выставлять заголовки.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Method == http.MethodGet {
+			return token, true
+		}
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// requestUserIDs собирает все user_id, которые клиент передал в запросе.
+// JSON-тело читается и подкладывается обратно, форма остаётся разобранной в
+// r.PostForm, так что обработчики видят запрос без изменений. Тело разбирается
+// так же строго, как decodeJSON: иначе обработчик мог бы принять то, в чём
+// middleware не нашла user_id.
+func requestUserIDs(r *http.Request) ([]int, error) {
+	var raw []string
+	if uid, ok := mux.Vars(r)["uid"]; ok {
+		raw = append(raw, uid)
+	}
+	raw = append(raw, r.URL.Query()["user_id"]...)
+
+	if r.Body != nil && (r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch) {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "application/x-www-form-urlencoded" {
+			if err := r.ParseForm(); err != nil {
+				return nil, err
+			}
+			raw = append(raw, r.PostForm["user_id"]...)
+		} else {
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxAuthPeekBody+1))
+			if err != nil {
+				return nil, err
+			}
+			if len(body) > maxAuthPeekBody {
+				return nil, &http.MaxBytesError{Limit: maxAuthPeekBody}
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// тело может быть и не JSON (iCalendar в /import); тогда его
+			// не примет и decodeJSON обработчика
+			var value json.RawMessage
+			err = decodeSingle(json.NewDecoder(bytes.NewReader(body)), &value)
+			if err != nil && mediaType == "application/json" {
+				return nil, err
+			}
+			var peek struct {
+				UserID *int `json:"user_id"`
+			}
+			if err == nil && bytes.HasPrefix(value, []byte("{")) {
+				if err := json.Unmarshal(value, &peek); err != nil {
+					return nil, err
+				}
+				if peek.UserID != nil {
+					raw = append(raw, strconv.Itoa(*peek.UserID))
+				}
+			}
+		}
+	}
+
+	userIDs := make([]int, 0, len(raw))
+	for _, s := range raw {
+		userID, err := strconv.Atoi(s)
+		if err != nil {
+			// нечисловой user_id отвергнет валидация обработчика
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, nil
+}
+
+// withPrincipalUser подставляет пользователя из токена в запрос, где клиент
+// не указал user_id: иначе обработчик работал бы от имени пользователя 0.
+func withPrincipalUser(r *http.Request, userID int) {
+	id := strconv.Itoa(userID)
+	query := r.URL.Query()
+	query.Set("user_id", id)
+	r.URL.RawQuery = query.Encode()
+	if r.Form != nil {
+		r.Form.Set("user_id", id)
+	}
+}
+
+// principalUserID возвращает user_id запроса, а если клиент его не передал -
+// пользователя из токена.
+func principalUserID(r *http.Request, userID int) int {
+	if principal, ok := PrincipalFromContext(r.Context()); ok && userID == 0 {
+		return principal.UserID
+	}
+	return userID
+}
+
+type apiKeyEntry struct {
+	Key    string `json:"key"`
+	UserID int    `json:"user_id"`
+	Name   string `json:"name,omitempty"`
+}
+
+// APIKeyAuthenticator сверяет статические ключи из конфигурационного файла.
+// Ключи хранятся как SHA-256, чтобы поиск не зависел от совпадающего префикса.
+type APIKeyAuthenticator struct {
+	keys map[[sha256.Size]byte]Principal
+}
+
+func NewAPIKeyAuthenticator(entries []apiKeyEntry) (*APIKeyAuthenticator, error) {
+	a := &APIKeyAuthenticator{keys: make(map[[sha256.Size]byte]Principal, len(entries))}
+	for i, entry := range entries {
+		if entry.Key == "" {
+			return nil, fmt.Errorf("api key #%d is empty", i+1)
+		}
+		a.keys[sha256.Sum256([]byte(entry.Key))] = Principal{UserID: entry.UserID}
+	}
+	return a, nil
+}
+
+// LoadAPIKeys читает файл вида [{"key": "...", "user_id": 1, "name": "ops"}].
+func LoadAPIKeys(path string) (*APIKeyAuthenticator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []apiKeyEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return NewAPIKeyAuthenticator(entries)
+}
+
+func (a *APIKeyAuthenticator) Authenticate(token string) (Principal, error) {
+	p, ok := a.keys[sha256.Sum256([]byte(token))]
+	if !ok {
+		return Principal{}, ErrUnauthenticated
+	}
+	return p, nil
+}
+
+// JWTAuthenticator проверяет JWT с подписью HS256. Пользователь берётся из
+// claim sub (строка с числом) или user_id; exp и nbf проверяются, если заданы.
+type JWTAuthenticator struct {
+	secret []byte
+	now    func() time.Time
+}
+
+func NewJWTAuthenticator(secret []byte) *JWTAuthenticator {
+	return &JWTAuthenticator{secret: secret, now: time.Now}
+}
+
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	UserID    *int   `json:"user_id"`
+	ExpiresAt *int64 `json:"exp"`
+	NotBefore *int64 `json:"nbf"`
+}
+
+func (a *JWTAuthenticator) Authenticate(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, ErrUnauthenticated
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return Principal{}, ErrUnauthenticated
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, ErrUnauthenticated
+	}
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return Principal{}, ErrUnauthenticated
+	}
+
+	var claims jwtClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return Principal{}, ErrUnauthenticated
+	}
+
+	now := a.now().Unix()
+	if claims.ExpiresAt != nil && now >= *claims.ExpiresAt {
+		return Principal{}, ErrUnauthenticated
+	}
+	if claims.NotBefore != nil && now < *claims.NotBefore {
+		return Principal{}, ErrUnauthenticated
+	}
+
+	if claims.UserID != nil {
+		return Principal{UserID: *claims.UserID}, nil
+	}
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return Principal{}, ErrUnauthenticated
+	}
+	return Principal{UserID: userID}, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// SignJWT выпускает HS256-токен; используется в тестах и для выдачи токенов
+// служебным клиентам.
+func SignJWT(secret []byte, userID int, ttl time.Duration) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]interface{}{
+		"sub": strconv.Itoa(userID),
+		"exp": time.Now().Add(ttl).Unix(),
+	})
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func authRouter(auth Authenticator) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(AuthMiddleware(auth))
+	NewHandler(NewCalendar()).RegisterRoutes(router)
+	return router
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	secret := []byte("test-secret")
+	auth := NewJWTAuthenticator(secret)
+
+	p, err := auth.Authenticate(SignJWT(secret, 42, time.Hour))
+	if err != nil || p.UserID != 42 {
+		t.Errorf("Expected user 42, got %+v (%v)", p, err)
+	}
+
+	invalid := []string{
+		SignJWT([]byte("other-secret"), 42, time.Hour),
+		SignJWT(secret, 42, -time.Hour),
+		"not.a.jwt",
+		"garbage",
+	}
+	for _, token := range invalid {
+		if _, err := auth.Authenticate(token); err != ErrUnauthenticated {
+			t.Errorf("Expected ErrUnauthenticated for %q, got %v", token, err)
+		}
+	}
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	os.WriteFile(path, []byte(`[{"key": "alice-key", "user_id": 1}, {"key": "bob-key", "user_id": 2, "name": "bob"}]`), 0o600)
+
+	auth, err := LoadAPIKeys(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p, err := auth.Authenticate("bob-key"); err != nil || p.UserID != 2 {
+		t.Errorf("Expected user 2, got %+v (%v)", p, err)
+	}
+	if _, err := auth.Authenticate("mallory-key"); err != ErrUnauthenticated {
+		t.Errorf("Expected ErrUnauthenticated, got %v", err)
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	auth, _ := NewAPIKeyAuthenticator([]apiKeyEntry{{Key: "alice-key", UserID: 1}})
+	router := authRouter(auth)
+
+	tests := []struct {
+		name        string
+		method, url string
+		contentType string
+		body        string
+		token       string
+		want        int
+	}{
+		{"no token", "GET", "/events_for_day?user_id=1&date=2024-01-01", "", "", "", http.StatusUnauthorized},
+		{"bad token", "GET", "/events_for_day?user_id=1&date=2024-01-01", "", "", "nope", http.StatusUnauthorized},
+		{"own events", "GET", "/events_for_day?user_id=1&date=2024-01-01", "", "", "alice-key", http.StatusOK},
+		{"foreign query", "GET", "/events_for_month?user_id=2&date=2024-01-01", "", "", "alice-key", http.StatusForbidden},
+		{"foreign json", "POST", "/update_event", "application/json", `{"id": 1, "user_id": 2, "date": "2024-01-01"}`, "alice-key", http.StatusForbidden},
+		{"foreign form", "POST", "/delete_event", "application/x-www-form-urlencoded", "id=1&user_id=2", "alice-key", http.StatusForbidden},
+		{"own json", "POST", "/create_event", "application/json", `{"user_id": 1, "date": "2024-01-01", "title": "Mine"}`, "alice-key", http.StatusOK},
+		{"own form", "POST", "/create_event", "application/x-www-form-urlencoded", "user_id=1&date=2024-01-01&title=Mine", "alice-key", http.StatusOK},
+		{"trailing data", "POST", "/update_event", "application/json", `{"user_id": 2, "id": 1, "date": "2024-01-02", "title": "pwned"} x`, "alice-key", http.StatusBadRequest},
+		{"foreign json with charset", "POST", "/create_event", "application/json; charset=utf-8", `{"user_id": 2, "date": "2024-01-01"}`, "alice-key", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("Expected %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_DefaultsToPrincipal(t *testing.T) {
+	auth, _ := NewAPIKeyAuthenticator([]apiKeyEntry{{Key: "alice-key", UserID: 1}})
+	calendar := NewCalendar()
+	router := mux.NewRouter()
+	router.Use(AuthMiddleware(auth))
+	NewHandler(calendar).RegisterRoutes(router)
+
+	// без user_id событие создаётся от имени владельца токена, а не пользователя 0
+	for _, tt := range []struct{ contentType, body string }{
+		{"application/json", `{"date": "2024-01-01", "title": "JSON"}`},
+		{"application/x-www-form-urlencoded", "date=2024-01-01&title=Form"},
+	} {
+		req := httptest.NewRequest("POST", "/create_event", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		req.Header.Set("Authorization", "Bearer alice-key")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected 200 for %s, got %d: %s", tt.contentType, rec.Code, rec.Body)
+		}
+	}
+	if events := calendar.GetEvents(1); len(events) != 2 {
+		t.Errorf("Expected both events for user 1, got %v", events)
+	}
+	if events := calendar.GetEvents(0); len(events) != 0 {
+		t.Errorf("Expected no events for user 0, got %v", events)
+	}
+}
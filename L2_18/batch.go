@@ -0,0 +1,291 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// maxBatchSize ограничивает число операций в одном пакете: пакет выполняется
+// под эксклюзивной блокировкой календаря.
+const maxBatchSize = 1000
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+var ErrBatchInvalid = errors.New("invalid batch")
+
+// BatchOp - одна операция пакета. Для update и delete с ненулевым Occurrence
+// операция относится к одному вхождению серии.
+type BatchOp struct {
+	Op         string
+	ID         int
+	UserID     int
+	Date       time.Time
+	Title      string
+	Occurrence time.Time
+	Options    []EventOption
+}
+
+// BatchError сообщает, какая операция пакета не выполнилась; пакет при этом
+// откатывается целиком.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+type pendingChange struct {
+	typ      ChangeType
+	event    Event
+	audience []int
+}
+
+// calendarTx копит мутации и уведомления пакета и умеет откатить изменения
+// индекса в памяти.
+type calendarTx struct {
+	mutations []Mutation
+	changes   []pendingChange
+	rollback  []func()
+}
+
+func (c *Calendar) publish(typ ChangeType, event Event) {
+	c.publishTo(typ, event, c.audience(event))
+}
+
+// publishRevoked сообщает об удалении тем, кто видел old, но не видит event:
+// исключённым из приглашённых и потерявшим доступ при переносе события в
+// другой календарь.
+func (c *Calendar) publishRevoked(old, event Event) {
+	current := c.audience(event)
+	var lost []int
+	for _, userID := range c.audience(old) {
+		if !containsInt(current, userID) && !containsInt(lost, userID) {
+			lost = append(lost, userID)
+		}
+	}
+	if len(lost) > 0 {
+		c.publishTo(ChangeDeleted, old, lost)
+	}
+}
+
+func (c *Calendar) publishTo(typ ChangeType, event Event, audience []int) {
+	if c.tx != nil {
+		c.tx.changes = append(c.tx.changes, pendingChange{typ: typ, event: event, audience: audience})
+		return
+	}
+	c.changes.publish(typ, event, audience)
+}
+
+func (c *Calendar) onRollback(undo func()) {
+	if c.tx != nil {
+		c.tx.rollback = append(c.tx.rollback, undo)
+	}
+}
+
+// Batch выполняет операции атомарно: либо все, либо ни одной. Пакет пишется в
+// хранилище одной мутацией, а подписчики получают уведомления только после
+// успешной фиксации. Результат delete - пустое событие.
+func (c *Calendar) Batch(ops []BatchOp) ([]Event, error) {
+	if len(ops) == 0 || len(ops) > maxBatchSize {
+		return nil, fmt.Errorf("%w: must contain between 1 and %d operations", ErrBatchInvalid, maxBatchSize)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tx := &calendarTx{}
+	c.tx = tx
+	defer func() { c.tx = nil }()
+
+	results := make([]Event, len(ops))
+	for i, op := range ops {
+		event, err := c.applyBatchOp(op)
+		if err != nil {
+			tx.undo()
+			return nil, &BatchError{Index: i, Err: err}
+		}
+		results[i] = event
+	}
+
+	c.tx = nil
+	if len(tx.mutations) > 0 {
+		if err := c.apply(Mutation{Op: OpBatch, Batch: tx.mutations, NextID: c.nextID}); err != nil {
+			tx.undo()
+			return nil, err
+		}
+	}
+	for _, change := range tx.changes {
+		c.changes.publish(change.typ, change.event, change.audience)
+	}
+	return results, nil
+}
+
+func (c *Calendar) applyBatchOp(op BatchOp) (Event, error) {
+	switch op.Op {
+	case BatchCreate:
+		return c.createEvent(op.UserID, op.Date, op.Title, op.Options)
+	case BatchUpdate:
+		if !op.Occurrence.IsZero() {
+			return c.updateOccurrence(op.ID, op.UserID, 0, op.Occurrence, op.Date, op.Title, op.Options)
+		}
+		return c.updateEvent(op.ID, op.UserID, op.Date, op.Title, op.Options)
+	case BatchDelete:
+		if !op.Occurrence.IsZero() {
+			return Event{}, c.deleteOccurrence(op.ID, op.UserID, 0, op.Occurrence)
+		}
+		return Event{}, c.deleteEvent(op.ID, op.UserID, 0)
+	default:
+		return Event{}, fmt.Errorf("%w: unknown op %q", ErrBatchInvalid, op.Op)
+	}
+}
+
+func (tx *calendarTx) undo() {
+	for i := len(tx.rollback) - 1; i >= 0; i-- {
+		tx.rollback[i]()
+	}
+}
+
+// maxBatchBody - предельный размер тела /events/batch; он же порог, до
+// которого AuthMiddleware читает JSON-тело.
+const maxBatchBody = maxAuthPeekBody
+
+type batchOperation struct {
+	Op string `json:"op"`
+	eventRequest
+}
+
+type batchRequest struct {
+	Operations []batchOperation `json:"operations"`
+}
+
+const (
+	batchApplied    = "applied"
+	batchFailed     = "failed"
+	batchRolledBack = "rolled_back"
+	batchSkipped    = "skipped"
+)
+
+type batchItemResult struct {
+	Index   int          `json:"index"`
+	Op      string       `json:"op"`
+	Status  string       `json:"status"`
+	Event   *Event       `json:"event,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Code    ErrorCode    `json:"code,omitempty"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// eventsBatch применяет пакет операций create/update/delete. При ошибке
+// ничего не меняется, а в result видно, какая операция её вызвала.
+func (h *Handler) eventsBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBody)
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchSize {
+		writeCalendarError(w, r, invalidField("operations", fmt.Errorf("must contain between 1 and %d operations", maxBatchSize)))
+		return
+	}
+
+	// AuthMiddleware не видит user_id внутри массива, поэтому проверяем здесь
+	principal, authenticated := PrincipalFromContext(r.Context())
+
+	ops := make([]BatchOp, len(req.Operations))
+	for i, item := range req.Operations {
+		if authenticated && item.UserID != principal.UserID {
+			writeBatchError(w, r, req.Operations, &BatchError{Index: i, Err: ErrForbidden})
+			return
+		}
+		op, err := parseBatchOp(item)
+		if err != nil {
+			writeBatchError(w, r, req.Operations, &BatchError{Index: i, Err: err})
+			return
+		}
+		ops[i] = op
+	}
+
+	events, err := h.calendar.Batch(ops)
+	if err != nil {
+		writeBatchError(w, r, req.Operations, err)
+		return
+	}
+
+	results := make([]batchItemResult, len(events))
+	for i := range events {
+		results[i] = batchItemResult{Index: i, Op: ops[i].Op, Status: batchApplied}
+		if ops[i].Op != BatchDelete {
+			results[i].Event = &events[i]
+		}
+	}
+	writeJSON(w, response{Result: results}, http.StatusOK)
+}
+
+func parseBatchOp(item batchOperation) (BatchOp, error) {
+	op := BatchOp{Op: item.Op, ID: item.ID, UserID: item.UserID, Title: item.Title}
+
+	if item.Occurrence != "" {
+		occurrence, err := parseOccurrence(&item.eventRequest)
+		if err != nil {
+			return BatchOp{}, err
+		}
+		op.Occurrence = occurrence
+	}
+
+	switch item.Op {
+	case BatchCreate, BatchUpdate:
+		date, opts, err := parseEventRequest(&item.eventRequest)
+		if err != nil {
+			return BatchOp{}, err
+		}
+		op.Date, op.Options = date, opts
+	case BatchDelete:
+	default:
+		return BatchOp{}, invalidField("op", fmt.Errorf("must be %s, %s or %s", BatchCreate, BatchUpdate, BatchDelete))
+	}
+	return op, nil
+}
+
+// writeBatchError отвечает статусом ошибки операции и результатами по всем
+// операциям: до неё - откачены, после - не выполнялись.
+func writeBatchError(w http.ResponseWriter, r *http.Request, operations []batchOperation, err error) {
+	status, resp := errorResponse(r.Context(), err)
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		writeJSON(w, resp, status)
+		return
+	}
+
+	_, itemResp := errorResponse(r.Context(), batchErr.Err)
+	results := make([]batchItemResult, len(operations))
+	for i, item := range operations {
+		results[i] = batchItemResult{Index: i, Op: item.Op}
+		switch {
+		case i < batchErr.Index:
+			results[i].Status = batchRolledBack
+		case i == batchErr.Index:
+			results[i].Status = batchFailed
+			results[i].Error = itemResp.Error
+			results[i].Code = itemResp.Code
+			results[i].Details = itemResp.Details
+		default:
+			results[i].Status = batchSkipped
+		}
+	}
+	resp.Result = results
+	writeJSON(w, resp, status)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCalendar_Batch(t *testing.T) {
+	dir := t.TempDir()
+	storage, _ := NewFileStorage(dir, 100)
+	calendar, _ := NewCalendarWithStorage(storage)
+
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	existing, _ := calendar.CreateEvent(1, date, "Existing")
+
+	events, err := calendar.Batch([]BatchOp{
+		{Op: BatchCreate, UserID: 1, Date: date, Title: "First"},
+		{Op: BatchCreate, UserID: 1, Date: date.Add(time.Hour), Title: "Second"},
+		{Op: BatchUpdate, ID: existing.ID, UserID: 1, Date: date, Title: "Renamed"},
+		{Op: BatchDelete, ID: 2, UserID: 1},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if events[1].ID != 3 || events[2].Version != 2 {
+		t.Errorf("Unexpected results %+v", events)
+	}
+
+	calendar.Close()
+	storage, _ = NewFileStorage(dir, 100)
+	calendar, _ = NewCalendarWithStorage(storage)
+	got := calendar.GetEventsForDay(1, date)
+	if len(got) != 2 || got[0].Title != "Renamed" || got[1].Title != "Second" {
+		t.Errorf("Expected batch to survive restart, got %+v", got)
+	}
+}
+
+func TestCalendar_BatchRollback(t *testing.T) {
+	calendar := NewCalendar()
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	existing, _ := calendar.CreateEvent(1, date, "Existing")
+
+	sub, _, _ := calendar.Subscribe(1, "", 0)
+	defer sub.Close()
+
+	_, err := calendar.Batch([]BatchOp{
+		{Op: BatchCreate, UserID: 1, Date: date, Title: "New"},
+		{Op: BatchDelete, ID: existing.ID, UserID: 1},
+		{Op: BatchUpdate, ID: 42, UserID: 1, Date: date, Title: "Missing"},
+	})
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 2 || !errors.Is(err, ErrEventNotFound) {
+		t.Fatalf("Expected BatchError at index 2, got %v", err)
+	}
+
+	events := calendar.GetEventsForDay(1, date)
+	if len(events) != 1 || events[0].ID != existing.ID {
+		t.Errorf("Expected calendar unchanged, got %+v", events)
+	}
+	if event, _ := calendar.CreateEvent(1, date, "Next"); event.ID != 2 {
+		t.Errorf("Expected ID sequence to be rolled back, got %d", event.ID)
+	}
+
+	// подписчик видит только событие, созданное после неудачного пакета
+	if change := <-sub.C; change.Event.Title != "Next" {
+		t.Errorf("Expected no notifications from rolled back batch, got %+v", change)
+	}
+
+	if _, err := calendar.Batch(nil); !errors.Is(err, ErrBatchInvalid) {
+		t.Errorf("Expected ErrBatchInvalid for empty batch, got %v", err)
+	}
+}
+
+func TestHandler_EventsBatch(t *testing.T) {
+	secret := []byte("secret")
+	r := mux.NewRouter()
+	r.Use(AuthMiddleware(NewJWTAuthenticator(secret)))
+	NewHandler(NewCalendar()).RegisterRoutes(r)
+
+	post := func(body string) (int, response) {
+		req := httptest.NewRequest("POST", "/events/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+SignJWT(secret, 1, time.Hour))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		var resp response
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec.Code, resp
+	}
+
+	status, resp := post(`{"operations": [
+		{"op": "create", "user_id": 1, "date": "2024-01-01", "title": "Mine"},
+		{"op": "create", "user_id": 2, "date": "2024-01-01", "title": "Not mine"}
+	]}`)
+	if status != http.StatusForbidden {
+		t.Fatalf("Expected 403 for foreign user_id in batch, got %d", status)
+	}
+	if items := resp.Result.([]interface{}); items[1].(map[string]interface{})["status"] != batchFailed {
+		t.Errorf("Expected second item to be marked failed, got %+v", items)
+	}
+
+	status, resp = post(`{"operations": [
+		{"op": "create", "user_id": 1, "date": "2024-01-01", "title": "A"},
+		{"op": "create", "user_id": 1, "date": "2024-01-02", "title": "B"}
+	]}`)
+	if status != http.StatusOK || len(resp.Result.([]interface{})) != 2 {
+		t.Errorf("Expected 200 with 2 results, got %d %+v", status, resp)
+	}
+}
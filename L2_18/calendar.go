@@ -2,14 +2,16 @@ package main
 
 import (
 	"errors"
-	"sort"
+	"fmt"
 	"sync"
 	"time"
 )
 
 var (
-	ErrEventNotFound = errors.New("event not found")
-	ErrDateInvalid   = errors.New("invalid date")
+	ErrEventNotFound   = errors.New("event not found")
+	ErrDateInvalid     = errors.New("invalid date")
+	ErrVersionConflict = errors.New("event version does not match")
+	ErrUIDConflict     = errors.New("event with this uid already exists")
 )
 
 type Event struct {
@@ -17,119 +19,702 @@ type Event struct {
 	UserID int       `json:"user_id"`
 	Date   time.Time `json:"date"`
 	Title  string    `json:"title"`
+	// CalendarID - именованный календарь владельца, 0 - календарь по умолчанию.
+	CalendarID int `json:"calendar_id,omitempty"`
+	// Date и End - начало и конец события в часовом поясе Timezone (IANA).
+	// У событий на весь день (AllDay) это полуночи, End не включается.
+	End      time.Time `json:"end"`
+	Timezone string    `json:"timezone"`
+	AllDay   bool      `json:"all_day,omitempty"`
+
+	Description string `json:"description,omitempty"`
+	Location    string `json:"location,omitempty"`
+	// Tags хранятся в нижнем регистре без ведущего #.
+	Tags []string `json:"tags,omitempty"`
+
+	Reminders []Reminder `json:"reminders,omitempty"`
+	// Attendees - приглашённые пользователи; организатор события - UserID.
+	// ResponseStatus заполняется только в выдаче для приглашённого.
+	Attendees      []Attendee     `json:"attendees,omitempty"`
+	ResponseStatus AttendeeStatus `json:"response_status,omitempty"`
+	// UID - стабильный идентификатор для обмена с другими календарями (iCalendar UID).
+	UID string `json:"uid"`
+	// Version растёт при каждом изменении события и служит основой ETag.
+	Version int `json:"version"`
+
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	// SeriesID и RecurrenceID связывают вхождение с серией: у развёрнутых
+	// вхождений RecurrenceID - исходная дата вхождения, у отредактированных
+	// отдельно вхождений SeriesID - ID серии.
+	SeriesID     int        `json:"series_id,omitempty"`
+	RecurrenceID *time.Time `json:"recurrence_id,omitempty"`
+
+	// strict - запрос проверки пересечений (RejectConflicts), не сохраняется
+	strict bool
+	// calendarSet - календарь задан явно (InCalendar), не сохраняется
+	calendarSet bool
+}
+
+type EventOption func(*Event)
+
+func WithUID(uid string) EventOption {
+	return func(e *Event) {
+		e.UID = uid
+	}
+}
+
+// IfVersion делает UpdateEvent условным: событие изменится, только если его
+// текущая версия равна version, иначе вернётся ErrVersionConflict.
+func IfVersion(version int) EventOption {
+	return func(e *Event) {
+		e.Version = version
+	}
+}
+
+func WithEnd(end time.Time) EventOption {
+	return func(e *Event) {
+		e.End = end
+	}
+}
+
+func WithDuration(d time.Duration) EventOption {
+	return func(e *Event) {
+		e.End = e.Date.Add(d)
+	}
+}
+
+func WithTimezone(name string) EventOption {
+	return func(e *Event) {
+		e.Timezone = name
+	}
+}
+
+func WithAllDay() EventOption {
+	return func(e *Event) {
+		e.AllDay = true
+	}
+}
+
+func WithRecurrence(r *Recurrence) EventOption {
+	return func(e *Event) {
+		e.Recurrence = r.clone()
+	}
 }
 
 type Calendar struct {
-	mu     sync.RWMutex
-	events []Event
-	nextID int
+	mu      sync.RWMutex
+	users   map[int]*userEvents
+	nextID  int
+	storage Storage
+	changes *changeHub
+	// tx - открытый пакет изменений, см. Batch
+	tx *calendarTx
+
+	// calendars - именованные календари всех пользователей по ID; ID берутся
+	// из той же последовательности, что и у событий
+	calendars map[int]UserCalendar
+
+	// history - журнал аудита по ID события, в том числе удалённого
+	history  map[int][]AuditEntry
+	auditSeq int64
+	// retention - сколько удалённые события лежат в корзине, 0 - бессрочно
+	retention time.Duration
+	// actor - кто выполняет текущую операцию, если это не владелец события
+	actor int
+	now   func() time.Time
 }
 
 func NewCalendar() *Calendar {
 	return &Calendar{
-		events: make([]Event, 0),
-		nextID: 1,
+		users:     make(map[int]*userEvents),
+		nextID:    1,
+		storage:   NewMemoryStorage(),
+		changes:   newChangeHub(),
+		calendars: make(map[int]UserCalendar),
+		history:   make(map[int][]AuditEntry),
+		now:       time.Now,
 	}
 }
 
-func (c *Calendar) CreateEvent(userID int, date time.Time, title string) (Event, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func NewCalendarWithStorage(storage Storage) (*Calendar, error) {
+	state, err := storage.Load()
+	if err != nil {
+		return nil, err
+	}
 
+	nextID := state.NextID
+	for _, event := range state.Events {
+		if event.ID >= nextID {
+			nextID = event.ID + 1
+		}
+	}
+	for _, cal := range state.Calendars {
+		if cal.ID >= nextID {
+			nextID = cal.ID + 1
+		}
+	}
+
+	c := &Calendar{
+		users:     make(map[int]*userEvents),
+		nextID:    nextID,
+		storage:   storage,
+		changes:   newChangeHub(),
+		calendars: make(map[int]UserCalendar),
+		history:   make(map[int][]AuditEntry),
+		now:       time.Now,
+	}
+	for _, cal := range state.Calendars {
+		c.calendars[cal.ID] = cal
+		for _, share := range cal.Shares {
+			c.user(share.UserID).shared[cal.ID] = share.Permission
+		}
+	}
+	for _, entry := range state.Audit {
+		c.history[entry.EventID] = append(c.history[entry.EventID], entry)
+		c.auditSeq = entry.Seq
+	}
+	if state.AuditSeq > c.auditSeq {
+		c.auditSeq = state.AuditSeq
+	}
+	for _, event := range state.Events {
+		if event.UID == "" {
+			event.UID = defaultUID(event.ID)
+		}
+		if err := event.normalizeLoaded(); err != nil {
+			return nil, fmt.Errorf("event %d: %w", event.ID, err)
+		}
+		c.user(event.UserID).add(event)
+		c.addInvites(event)
+	}
+
+	return c, nil
+}
+
+func newEvent(id, userID int, date time.Time, title string, opts []EventOption) (Event, error) {
 	if date.IsZero() {
 		return Event{}, ErrDateInvalid
 	}
 
-	event := Event{c.nextID, userID, date, title}
-	c.nextID++
-	c.events = append(c.events, event)
+	event := Event{ID: id, UserID: userID, Date: date, Title: title}
+	for _, opt := range opts {
+		opt(&event)
+	}
 
+	if err := event.normalizeTime(); err != nil {
+		return Event{}, err
+	}
+	if err := validateReminders(event.Reminders); err != nil {
+		return Event{}, err
+	}
+	if err := validateTags(event.Tags); err != nil {
+		return Event{}, err
+	}
+	if event.Recurrence != nil {
+		if err := event.Recurrence.validate(); err != nil {
+			return Event{}, err
+		}
+	}
 	return event, nil
 }
 
-func (c *Calendar) UpdateEvent(id, userID int, date time.Time, title string) (Event, error) {
+func (c *Calendar) CreateEvent(userID int, date time.Time, title string, opts ...EventOption) (Event, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.createEvent(userID, date, title, opts)
+}
 
+func (c *Calendar) createEvent(userID int, date time.Time, title string, opts []EventOption) (Event, error) {
+	event, err := newEvent(c.nextID, userID, date, title, opts)
+	if err != nil {
+		return Event{}, err
+	}
+	if event.UserID, err = c.calendarOwner(event.CalendarID, userID); err != nil {
+		return Event{}, err
+	}
+	defer c.actAs(userID)()
+
+	if event.UID == "" {
+		event.UID = defaultUID(event.ID)
+	} else if _, exists := c.masterByUID(event.UserID, event.UID); exists {
+		return Event{}, ErrUIDConflict
+	}
+	if event.strict {
+		if err := c.checkConflicts(event); err != nil {
+			return Event{}, err
+		}
+	}
+
+	return c.insert(event)
+}
+
+// UpdateEvent заменяет событие (для серии - всю серию целиком). Исключённые
+// ранее вхождения серии сохраняются. Событие из чужого календаря можно
+// изменить, если календарь открыт пользователю на запись.
+func (c *Calendar) UpdateEvent(id, userID int, date time.Time, title string, opts ...EventOption) (Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.updateEvent(id, userID, date, title, opts)
+}
+
+func (c *Calendar) updateEvent(id, userID int, date time.Time, title string, opts []EventOption) (Event, error) {
 	if date.IsZero() {
 		return Event{}, ErrDateInvalid
 	}
 
-	for i, event := range c.events {
-		if event.ID == id && event.UserID == userID {
-			updatedEvent := Event{id, userID, date, title}
-			c.events[i] = updatedEvent
+	old, err := c.editable(id, userID)
+	if err != nil {
+		return Event{}, err
+	}
+	defer c.actAs(userID)()
 
-			return updatedEvent, nil
+	updatedEvent, err := newEvent(id, old.UserID, date, title, opts)
+	if err != nil {
+		return Event{}, err
+	}
+	if updatedEvent.Version != 0 && updatedEvent.Version != old.Version {
+		return Event{}, ErrVersionConflict
+	}
+	if !updatedEvent.calendarSet {
+		updatedEvent.CalendarID = old.CalendarID
+	} else if updatedEvent.CalendarID != old.CalendarID {
+		owner, err := c.calendarOwner(updatedEvent.CalendarID, userID)
+		if err != nil {
+			return Event{}, err
+		}
+		if owner != old.UserID {
+			return Event{}, ErrCalendarMismatch
 		}
 	}
-	return Event{}, ErrEventNotFound
+	if updatedEvent.UID == "" {
+		updatedEvent.UID = old.UID
+	} else if _, exists := c.masterByUID(old.UserID, updatedEvent.UID); exists && updatedEvent.UID != old.UID {
+		return Event{}, ErrUIDConflict
+	}
+	if updatedEvent.Attendees == nil {
+		updatedEvent.Attendees = old.Attendees
+	} else {
+		keepResponses(updatedEvent.Attendees, old.Attendees)
+	}
+	updatedEvent.SeriesID = old.SeriesID
+	updatedEvent.RecurrenceID = old.RecurrenceID
+	if updatedEvent.Recurrence != nil && old.Recurrence != nil {
+		for _, ex := range old.Recurrence.ExDates {
+			if !updatedEvent.Recurrence.isExcluded(ex) {
+				updatedEvent.Recurrence.ExDates = append(updatedEvent.Recurrence.ExDates, ex)
+			}
+		}
+	}
+	if updatedEvent.strict {
+		if err := c.checkConflicts(updatedEvent); err != nil {
+			return Event{}, err
+		}
+	}
+
+	return c.replace(old, updatedEvent)
+}
+
+// UpdateOccurrence отделяет одно вхождение серии в самостоятельное событие:
+// дата вхождения исключается из серии, а вместо неё создаётся новое событие.
+func (c *Calendar) UpdateOccurrence(id, userID int, occurrence, date time.Time, title string, opts ...EventOption) (Event, error) {
+	return c.UpdateOccurrenceIfVersion(id, userID, 0, occurrence, date, title, opts...)
+}
+
+// UpdateOccurrenceIfVersion отделяет вхождение, только если версия серии
+// равна version; нулевая version снимает проверку. IfVersion в opts тут не
+// поможет: он относится к новому событию.
+func (c *Calendar) UpdateOccurrenceIfVersion(id, userID, version int, occurrence, date time.Time, title string, opts ...EventOption) (Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.updateOccurrence(id, userID, version, occurrence, date, title, opts)
+}
+
+func (c *Calendar) updateOccurrence(id, userID, version int, occurrence, date time.Time, title string, opts []EventOption) (Event, error) {
+	series, occurrence, err := c.findOccurrence(id, userID, version, occurrence)
+	if err != nil {
+		return Event{}, err
+	}
+	defer c.actAs(userID)()
+
+	detached, err := newEvent(c.nextID, series.UserID, date, title, opts)
+	if err != nil {
+		return Event{}, err
+	}
+	// вхождение остаётся в календаре серии
+	detached.CalendarID = series.CalendarID
+	detached.Recurrence = nil
+	if detached.Attendees == nil {
+		detached.Attendees = series.Attendees
+	} else {
+		keepResponses(detached.Attendees, series.Attendees)
+	}
+	detached.UID = series.UID
+	detached.SeriesID = id
+	detached.RecurrenceID = &occurrence
+
+	detached, err = c.insert(detached)
+	if err != nil {
+		return Event{}, err
+	}
+	if err := c.excludeOccurrence(series, occurrence); err != nil {
+		return Event{}, err
+	}
+	return detached, nil
+}
+
+func (c *Calendar) DeleteOccurrence(id, userID int, occurrence time.Time) error {
+	return c.DeleteOccurrenceIfVersion(id, userID, 0, occurrence)
+}
+
+// DeleteOccurrenceIfVersion удаляет вхождение, только если версия серии равна
+// version; нулевая version снимает проверку.
+func (c *Calendar) DeleteOccurrenceIfVersion(id, userID, version int, occurrence time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deleteOccurrence(id, userID, version, occurrence)
 }
 
+func (c *Calendar) deleteOccurrence(id, userID, version int, occurrence time.Time) error {
+	series, occurrence, err := c.findOccurrence(id, userID, version, occurrence)
+	if err != nil {
+		return err
+	}
+	defer c.actAs(userID)()
+	return c.excludeOccurrence(series, occurrence)
+}
+
+// DeleteEvent удаляет событие, а для серии - ещё и все отделённые от неё вхождения.
 func (c *Calendar) DeleteEvent(id, userID int) error {
+	return c.DeleteEventIfVersion(id, userID, 0)
+}
+
+// DeleteEventIfVersion удаляет событие, только если его версия равна version;
+// нулевая version снимает проверку.
+func (c *Calendar) DeleteEventIfVersion(id, userID, version int) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.deleteEvent(id, userID, version)
+}
+
+func (c *Calendar) deleteEvent(id, userID, version int) error {
+	event, err := c.editable(id, userID)
+	if err != nil {
+		return err
+	}
+	if version != 0 && version != event.Version {
+		return ErrVersionConflict
+	}
+	defer c.actAs(userID)()
+	if err := c.remove(event); err != nil {
+		return err
+	}
 
-	for i, event := range c.events {
-		if event.ID == id && event.UserID == userID {
-			c.events = append(c.events[:i], c.events[i+1:]...)
-			return nil
+	// отделённые вхождения носят UID серии
+	user := c.users[event.UserID]
+	for _, detachedID := range append([]int(nil), user.byUID[event.UID]...) {
+		if detached := user.byID[detachedID]; detached.SeriesID == id {
+			if err := c.remove(detached); err != nil {
+				return err
+			}
 		}
 	}
-	return ErrEventNotFound
+	return nil
 }
 
-func (c *Calendar) GetEventsForDay(userID int, date time.Time) []Event {
+// EventCounts возвращает число хранимых событий каждого пользователя; серия
+// считается одним событием.
+func (c *Calendar) EventCounts() map[int]int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	var result []Event
-	for _, event := range c.events {
-		if event.UserID == userID && isSameDay(event.Date, date) {
-			result = append(result, event)
+	counts := make(map[int]int, len(c.users))
+	for userID, user := range c.users {
+		if len(user.byID) > 0 {
+			counts[userID] = len(user.byID)
 		}
 	}
-	return result
+	return counts
+}
+
+// Ping проверяет доступность хранилища.
+func (c *Calendar) Ping() error {
+	if err := c.storage.Ping(); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageUnavailable, err)
+	}
+	return nil
+}
+
+func (c *Calendar) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.storage.Close()
 }
 
-func (c *Calendar) GetEventsForWeek(userID int, date time.Time) []Event {
+func (c *Calendar) GetEvent(id, userID int) (Event, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	var result []Event
-	year, week := date.ISOWeek()
-	for _, event := range c.events {
-		if event.UserID == userID {
-			eYear, eWeek := event.Date.ISOWeek()
-			if eYear == year && eWeek == week {
-				result = append(result, event)
-			}
+	if event, ok := c.find(id, userID); ok {
+		return event, nil
+	}
+	if event, ok := c.invitation(id, userID); ok {
+		return event.viewFor(userID), nil
+	}
+	if event, ok := c.sharedEvent(id, userID); ok {
+		return event, nil
+	}
+	return Event{}, ErrEventNotFound
+}
+
+// GetEvents возвращает все события, организованные пользователем, без
+// разворачивания серий, без приглашений и чужих календарей. calendarIDs
+// ограничивает выборку перечисленными календарями (0 - по умолчанию).
+func (c *Calendar) GetEvents(userID int, calendarIDs ...int) []Event {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	user, ok := c.users[userID]
+	if !ok {
+		return nil
+	}
+	return filterCalendars(user.all(), userID, calendarIDs)
+}
+
+// EventByUID ищет серию или обычное событие по UID, а при заданном
+// recurrenceID - отдельно изменённое вхождение этой серии.
+func (c *Calendar) EventByUID(userID int, uid string, recurrenceID *time.Time) (Event, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if recurrenceID == nil {
+		return c.masterByUID(userID, uid)
+	}
+
+	user, ok := c.users[userID]
+	if !ok {
+		return Event{}, false
+	}
+
+	for _, id := range user.byUID[uid] {
+		event := user.byID[id]
+		if event.SeriesID != 0 && event.RecurrenceID != nil && event.RecurrenceID.Equal(*recurrenceID) {
+			return event, true
 		}
 	}
-	return result
+	return Event{}, false
+}
+
+// GetEventsForDay и остальные выборки по периоду возвращают собственные
+// события, приглашения и события открытых пользователю календарей;
+// calendarIDs оставляет только перечисленные календари (0 - по умолчанию).
+func (c *Calendar) GetEventsForDay(userID int, date time.Time, calendarIDs ...int) []Event {
+	from := startOfDay(date)
+	return c.eventsBetween(userID, from, from.AddDate(0, 0, 1), calendarIDs)
+}
+
+func (c *Calendar) GetEventsForWeek(userID int, date time.Time, calendarIDs ...int) []Event {
+	from := startOfDay(date)
+	from = from.AddDate(0, 0, -((int(from.Weekday()) + 6) % 7))
+	return c.eventsBetween(userID, from, from.AddDate(0, 0, 7), calendarIDs)
 }
 
-func (c *Calendar) GetEventsForMonth(userID int, date time.Time) []Event {
+func (c *Calendar) GetEventsForMonth(userID int, date time.Time, calendarIDs ...int) []Event {
+	year, month, _ := date.Date()
+	from := time.Date(year, month, 1, 0, 0, 0, 0, date.Location())
+	return c.eventsBetween(userID, from, from.AddDate(0, 1, 0), calendarIDs)
+}
+
+// GetEventsInRange возвращает события, пересекающиеся с [from, to), в порядке
+// начала, а при равенстве - ID.
+func (c *Calendar) GetEventsInRange(userID int, from, to time.Time, calendarIDs ...int) []Event {
+	return c.eventsBetween(userID, from, to, calendarIDs)
+}
+
+// eventsBetween возвращает события, пересекающиеся с [from, to), разворачивая
+// серии во вхождения. Границы берутся в часовом поясе from.
+func (c *Calendar) eventsBetween(userID int, from, to time.Time, calendarIDs []int) []Event {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	var result []Event
-	year, month := date.Year(), date.Month()
-	for _, event := range c.events {
-		if event.UserID == userID && event.Date.Year() == year && event.Date.Month() == month {
-			result = append(result, event)
+	result := c.visibleBetween(userID, from, to)
+	if user, ok := c.users[userID]; ok && len(user.shared) > 0 {
+		result = append(result, c.sharedBetween(user, from, to)...)
+		sortEvents(result)
+	}
+	return filterCalendars(result, userID, calendarIDs)
+}
+
+// visibleBetween - eventsBetween без блокировки: собственные события и
+// приглашения пользователя.
+func (c *Calendar) visibleBetween(userID int, from, to time.Time) []Event {
+	user, ok := c.users[userID]
+	if !ok {
+		return nil
+	}
+
+	result := user.between(from, to)
+	if len(user.invited) > 0 {
+		result = append(result, c.invitedBetween(user, userID, from, to)...)
+		sortEvents(result)
+	}
+	return result
+}
+
+func (e Event) occurrence(at time.Time) Event {
+	occurrence := e
+	occurrence.Date = at
+	if e.AllDay {
+		occurrence.End = at.AddDate(0, 0, daysBetween(e.Date, e.End))
+	} else {
+		occurrence.End = at.Add(e.End.Sub(e.Date))
+	}
+	occurrence.RecurrenceID = &at
+	return occurrence
+}
+
+func (c *Calendar) user(userID int) *userEvents {
+	user, ok := c.users[userID]
+	if !ok {
+		user = newUserEvents()
+		c.users[userID] = user
+	}
+	return user
+}
+
+// masterByUID ищет событие или серию (но не отделённое вхождение) с данным UID.
+func (c *Calendar) masterByUID(userID int, uid string) (Event, bool) {
+	user, ok := c.users[userID]
+	if !ok {
+		return Event{}, false
+	}
+	for _, id := range user.byUID[uid] {
+		if event := user.byID[id]; event.SeriesID == 0 {
+			return event, true
 		}
 	}
+	return Event{}, false
+}
+
+func (c *Calendar) find(id, userID int) (Event, bool) {
+	user, ok := c.users[userID]
+	if !ok {
+		return Event{}, false
+	}
+	event, ok := user.byID[id]
+	return event, ok
+}
 
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].Date.Before(result[j].Date)
+// findOccurrence проверяет, что occurrence - действующее вхождение серии, а
+// серия всё ещё в версии version (если она не нулевая). Для серий на весь
+// день occurrence трактуется как календарная дата в поясе серии.
+func (c *Calendar) findOccurrence(id, userID, version int, occurrence time.Time) (Event, time.Time, error) {
+	event, err := c.editable(id, userID)
+	if err != nil {
+		return Event{}, time.Time{}, err
+	}
+	if version != 0 && version != event.Version {
+		return Event{}, time.Time{}, ErrVersionConflict
+	}
+	if event.Recurrence == nil {
+		return Event{}, time.Time{}, ErrNotRecurring
+	}
+	if event.AllDay {
+		occurrence = floatDate(occurrence, event.Date.Location())
+	}
+	found, err := event.Recurrence.Occurrences(event.Date, occurrence, occurrence.Add(time.Nanosecond))
+	if err != nil {
+		return Event{}, time.Time{}, err
+	}
+	if len(found) == 0 {
+		return Event{}, time.Time{}, ErrOccurrenceNotFound
+	}
+	return event, occurrence, nil
+}
+
+func (c *Calendar) excludeOccurrence(series Event, occurrence time.Time) error {
+	updated := series
+	updated.Recurrence = series.Recurrence.clone()
+	updated.Recurrence.ExDates = append(updated.Recurrence.ExDates, occurrence)
+	_, err := c.replace(series, updated)
+	return err
+}
+
+// insert сохраняет новое событие с версией 1 и возвращает его.
+func (c *Calendar) insert(event Event) (Event, error) {
+	event.Version = 1
+	entry := c.auditEntry(AuditCreated, nil, &event)
+	if err := c.apply(Mutation{Op: OpPut, Event: event, NextID: c.nextID + 1, Audit: entry}); err != nil {
+		return Event{}, err
+	}
+	c.nextID++
+	c.addHistory(entry)
+	c.user(event.UserID).add(event)
+	c.addInvites(event)
+	c.publish(ChangeCreated, event)
+	c.onRollback(func() {
+		c.nextID--
+		c.user(event.UserID).remove(event)
+		c.removeInvites(event)
 	})
+	return event, nil
+}
 
-	return result
+// replace сохраняет новую редакцию события, увеличивая его версию.
+func (c *Calendar) replace(old, event Event) (Event, error) {
+	return c.replaceAs(AuditUpdated, old, event)
+}
+
+func (c *Calendar) replaceAs(action AuditAction, old, event Event) (Event, error) {
+	event.Version = old.Version + 1
+	entry := c.auditEntry(action, &old, &event)
+	if err := c.apply(Mutation{Op: OpPut, Event: event, NextID: c.nextID, Audit: entry}); err != nil {
+		return Event{}, err
+	}
+	c.addHistory(entry)
+	user := c.user(event.UserID)
+	user.remove(old)
+	user.add(event)
+	c.removeInvites(old)
+	c.addInvites(event)
+	c.publish(ChangeUpdated, event)
+	c.publishRevoked(old, event)
+	c.onRollback(func() {
+		user.remove(event)
+		user.add(old)
+		c.removeInvites(event)
+		c.addInvites(old)
+	})
+	return event, nil
+}
+
+func (c *Calendar) remove(event Event) error {
+	entry := c.auditEntry(AuditDeleted, &event, nil)
+	if err := c.apply(Mutation{Op: OpDelete, Event: event, NextID: c.nextID, Audit: entry}); err != nil {
+		return err
+	}
+	c.addHistory(entry)
+	c.user(event.UserID).remove(event)
+	c.removeInvites(event)
+	c.publish(ChangeDeleted, event)
+	c.onRollback(func() {
+		c.user(event.UserID).add(event)
+		c.addInvites(event)
+	})
+	return nil
+}
+
+func defaultUID(id int) string {
+	return fmt.Sprintf("%d@l2-18.calendar", id)
 }
 
-func isSameDay(a, b time.Time) bool {
-	y1, m1, d1 := a.Date()
-	y2, m2, d2 := b.Date()
-	return y1 == y2 && m1 == m2 && d1 == d2
+// apply записывает мутацию в хранилище, а внутри пакета (Batch) - лишь
+// откладывает её до фиксации.
+func (c *Calendar) apply(m Mutation) error {
+	if c.tx != nil {
+		c.tx.mutations = append(c.tx.mutations, m)
+		return nil
+	}
+	if err := c.storage.Apply(m); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageUnavailable, err)
+	}
+	return nil
 }
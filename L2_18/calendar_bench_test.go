@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// benchCalendar заполняет календарь users*perUser событиями, разбросанными по году.
+func benchCalendar(b *testing.B, users, perUser int) *Calendar {
+	b.Helper()
+	calendar := NewCalendar()
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	for i := 0; i < users*perUser; i++ {
+		userID := i%users + 1
+		date := start.Add(time.Duration(i*7919%(365*24)) * time.Hour)
+		if _, err := calendar.CreateEvent(userID, date, "Event", WithDuration(time.Hour)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return calendar
+}
+
+var benchSizes = []struct{ users, perUser int }{
+	{100, 100},
+	{100, 1000},
+	{10, 5000},
+}
+
+func BenchmarkCalendar_GetEventsForDay(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("users=%d/events=%d", size.users, size.users*size.perUser), func(b *testing.B) {
+			calendar := benchCalendar(b, size.users, size.perUser)
+			day := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				calendar.GetEventsForDay(i%size.users+1, day)
+			}
+		})
+	}
+}
+
+func BenchmarkCalendar_GetEventsForMonth(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("users=%d/events=%d", size.users, size.users*size.perUser), func(b *testing.B) {
+			calendar := benchCalendar(b, size.users, size.perUser)
+			month := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				calendar.GetEventsForMonth(i%size.users+1, month)
+			}
+		})
+	}
+}
+
+// linearScan - выборка без индекса: перебор всех событий пользователя.
+// Служит базовой линией для GetEventsForDay и GetEventsForMonth.
+func linearScan(calendar *Calendar, userID int, from, to time.Time) []Event {
+	calendar.mu.RLock()
+	defer calendar.mu.RUnlock()
+
+	var result []Event
+	for _, event := range calendar.users[userID].byID {
+		if event.overlaps(from, to) {
+			result = append(result, event)
+		}
+	}
+	sortEvents(result)
+	return result
+}
+
+func BenchmarkLinearScan_Day(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("users=%d/events=%d", size.users, size.users*size.perUser), func(b *testing.B) {
+			calendar := benchCalendar(b, size.users, size.perUser)
+			day := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				linearScan(calendar, i%size.users+1, day, day.AddDate(0, 0, 1))
+			}
+		})
+	}
+}
+
+func BenchmarkLinearScan_Month(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("users=%d/events=%d", size.users, size.users*size.perUser), func(b *testing.B) {
+			calendar := benchCalendar(b, size.users, size.perUser)
+			month := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				linearScan(calendar, i%size.users+1, month, month.AddDate(0, 1, 0))
+			}
+		})
+	}
+}
+
+func BenchmarkCalendar_UpdateEvent(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("users=%d/events=%d", size.users, size.users*size.perUser), func(b *testing.B) {
+			calendar := benchCalendar(b, size.users, size.perUser)
+			total := size.users * size.perUser
+			date := time.Date(2024, 6, 15, 9, 0, 0, 0, time.UTC)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				id := i*7%total + 1
+				userID := (id-1)%size.users + 1
+				if _, err := calendar.UpdateEvent(id, userID, date.Add(time.Duration(i%48)*time.Hour), "Updated"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCalendar_DeleteEvent(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("users=%d/events=%d", size.users, size.users*size.perUser), func(b *testing.B) {
+			calendar := benchCalendar(b, size.users, size.perUser)
+			date := time.Date(2024, 6, 15, 9, 0, 0, 0, time.UTC)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				userID := i%size.users + 1
+				event, _ := calendar.CreateEvent(userID, date, "Temporary")
+				if err := calendar.DeleteEvent(event.ID, userID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -158,3 +158,53 @@ func TestCalendar_Concurrency(t *testing.T) {
 		t.Errorf("Expected 10 events, got %d", len(events))
 	}
 }
+
+func TestCalendar_UpdateEventMovesBetweenDays(t *testing.T) {
+	calendar := NewCalendar()
+
+	date := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	newDate := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	first, _ := calendar.CreateEvent(1, date, "First")
+	second, _ := calendar.CreateEvent(1, date, "Second")
+	calendar.UpdateEvent(first.ID, 1, newDate, "First moved")
+
+	events := calendar.GetEventsForDay(1, date)
+	if len(events) != 1 || events[0].ID != second.ID {
+		t.Errorf("Expected only second event on old day, got %+v", events)
+	}
+	events = calendar.GetEventsForDay(1, newDate)
+	if len(events) != 1 || events[0].Title != "First moved" {
+		t.Errorf("Expected moved event on new day, got %+v", events)
+	}
+
+	if _, err := calendar.UpdateEvent(first.ID, 2, newDate, "Wrong user"); err != ErrEventNotFound {
+		t.Errorf("Expected ErrEventNotFound, got %v", err)
+	}
+}
+
+func TestCalendar_LongEventsAndFiniteSeries(t *testing.T) {
+	calendar := NewCalendar()
+
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	trip, _ := calendar.CreateEvent(1, start, "Trip", WithDuration(10*24*time.Hour))
+	calendar.CreateEvent(1, start.AddDate(0, 0, 5), "Meeting", WithDuration(time.Hour))
+	calendar.CreateEvent(1, start, "Sprint", WithDuration(time.Hour), WithRecurrence(&Recurrence{Freq: FreqDaily, Count: 3}))
+
+	middle := start.AddDate(0, 0, 5)
+	if events := calendar.GetEventsForDay(1, middle); len(events) != 2 {
+		t.Fatalf("Expected trip and meeting on a middle day, got %+v", events)
+	}
+	if events := calendar.GetEventsForDay(1, start.AddDate(0, 0, 2)); len(events) != 2 {
+		t.Errorf("Expected trip and last sprint occurrence, got %+v", events)
+	}
+
+	calendar.DeleteEvent(trip.ID, 1)
+	events := calendar.GetEventsForDay(1, middle)
+	if len(events) != 1 || events[0].Title != "Meeting" {
+		t.Errorf("Expected only the meeting after deleting the trip, got %+v", events)
+	}
+	if user := calendar.users[1]; len(user.long) != 0 || len(user.seriesEnd) != 1 {
+		t.Errorf("Expected index without the trip, got long=%v seriesEnd=%v", user.long, user.seriesEnd)
+	}
+}
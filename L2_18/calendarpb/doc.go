@@ -0,0 +1,5 @@
+// Package calendarpb - сообщения и сервис gRPC календаря, сгенерированные из
+// calendar.proto.
+package calendarpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative calendar.proto
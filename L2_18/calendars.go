@@ -0,0 +1,595 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+const maxCalendarName = 100
+
+var (
+	ErrCalendarNotFound    = errors.New("calendar not found")
+	ErrCalendarNameInvalid = errors.New("calendar name must be 1 to 100 characters")
+	ErrCalendarNameTaken   = errors.New("calendar with this name already exists")
+	ErrCalendarNotEmpty    = errors.New("calendar still has events")
+	ErrCalendarReadOnly    = errors.New("calendar is shared read-only")
+	ErrNotCalendarOwner    = errors.New("only the calendar owner can do this")
+	ErrCalendarMismatch    = errors.New("calendar must belong to the event owner")
+	ErrPermissionInvalid   = errors.New(`permission must be "read" or "write"`)
+	ErrShareWithOwner      = errors.New("calendar cannot be shared with its owner")
+)
+
+type Permission string
+
+const (
+	PermissionRead  Permission = "read"
+	PermissionWrite Permission = "write"
+	// PermissionOwner встречается только в Access: владельцу доступно всё
+	PermissionOwner Permission = "owner"
+)
+
+func (p Permission) canWrite() bool {
+	return p == PermissionWrite || p == PermissionOwner
+}
+
+type Share struct {
+	UserID     int        `json:"user_id"`
+	Permission Permission `json:"permission"`
+}
+
+// UserCalendar - именованный календарь пользователя (Работа, Дежурства,
+// Личное). События без CalendarID лежат в календаре по умолчанию, который
+// есть у каждого пользователя и не расшаривается.
+type UserCalendar struct {
+	ID      int     `json:"id"`
+	OwnerID int     `json:"owner_id"`
+	Name    string  `json:"name"`
+	Shares  []Share `json:"shares,omitempty"`
+	// Access - права пользователя, запросившего календарь; не хранится
+	Access Permission `json:"access,omitempty"`
+}
+
+// viewFor готовит календарь для выдачи пользователю: список доступов виден
+// только владельцу.
+func (cal UserCalendar) viewFor(userID int, access Permission) UserCalendar {
+	cal.Access = access
+	if cal.OwnerID != userID {
+		cal.Shares = nil
+	}
+	return cal
+}
+
+// InCalendar помещает событие в календарь; 0 - календарь по умолчанию. Без
+// этой опции UpdateEvent оставляет событие в прежнем календаре.
+func InCalendar(id int) EventOption {
+	return func(e *Event) {
+		e.CalendarID = id
+		e.calendarSet = true
+	}
+}
+
+func (c *Calendar) CreateCalendar(userID int, name string) (UserCalendar, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name, err := c.calendarName(userID, 0, name)
+	if err != nil {
+		return UserCalendar{}, err
+	}
+
+	cal := UserCalendar{ID: c.nextID, OwnerID: userID, Name: name}
+	if err := c.apply(Mutation{Op: OpPutCalendar, Calendar: &cal, NextID: c.nextID + 1}); err != nil {
+		return UserCalendar{}, err
+	}
+	c.nextID++
+	c.calendars[cal.ID] = cal
+	return cal.viewFor(userID, PermissionOwner), nil
+}
+
+func (c *Calendar) RenameCalendar(id, userID int, name string) (UserCalendar, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cal, err := c.ownCalendar(id, userID)
+	if err != nil {
+		return UserCalendar{}, err
+	}
+	if cal.Name, err = c.calendarName(userID, id, name); err != nil {
+		return UserCalendar{}, err
+	}
+	if err := c.putCalendar(cal); err != nil {
+		return UserCalendar{}, err
+	}
+	return cal.viewFor(userID, PermissionOwner), nil
+}
+
+// DeleteCalendar удаляет пустой календарь; события из него нужно сначала
+// удалить или перенести.
+func (c *Calendar) DeleteCalendar(id, userID int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cal, err := c.ownCalendar(id, userID)
+	if err != nil {
+		return err
+	}
+	for _, event := range c.user(userID).byID {
+		if event.CalendarID == id {
+			return ErrCalendarNotEmpty
+		}
+	}
+
+	if err := c.apply(Mutation{Op: OpDeleteCalendar, Calendar: &cal, NextID: c.nextID}); err != nil {
+		return err
+	}
+	delete(c.calendars, id)
+	for _, share := range cal.Shares {
+		delete(c.users[share.UserID].shared, id)
+	}
+	return nil
+}
+
+// ShareCalendar открывает календарь пользователю withUserID на чтение или
+// запись; повторный вызов меняет права.
+func (c *Calendar) ShareCalendar(id, userID, withUserID int, permission Permission) (UserCalendar, error) {
+	if permission != PermissionRead && permission != PermissionWrite {
+		return UserCalendar{}, ErrPermissionInvalid
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cal, err := c.ownCalendar(id, userID)
+	if err != nil {
+		return UserCalendar{}, err
+	}
+	if withUserID == userID {
+		return UserCalendar{}, ErrShareWithOwner
+	}
+
+	shares := make([]Share, 0, len(cal.Shares)+1)
+	for _, share := range cal.Shares {
+		if share.UserID != withUserID {
+			shares = append(shares, share)
+		}
+	}
+	shares = append(shares, Share{UserID: withUserID, Permission: permission})
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].UserID < shares[j].UserID
+	})
+	cal.Shares = shares
+
+	if err := c.putCalendar(cal); err != nil {
+		return UserCalendar{}, err
+	}
+	c.user(withUserID).shared[id] = permission
+	return cal.viewFor(userID, PermissionOwner), nil
+}
+
+// UnshareCalendar закрывает пользователю доступ к календарю.
+func (c *Calendar) UnshareCalendar(id, userID, withUserID int) (UserCalendar, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cal, err := c.ownCalendar(id, userID)
+	if err != nil {
+		return UserCalendar{}, err
+	}
+
+	var shares []Share
+	for _, share := range cal.Shares {
+		if share.UserID != withUserID {
+			shares = append(shares, share)
+		}
+	}
+	if len(shares) == len(cal.Shares) {
+		return cal.viewFor(userID, PermissionOwner), nil
+	}
+	cal.Shares = shares
+
+	if err := c.putCalendar(cal); err != nil {
+		return UserCalendar{}, err
+	}
+	delete(c.users[withUserID].shared, id)
+	return cal.viewFor(userID, PermissionOwner), nil
+}
+
+// Calendars возвращает собственные календари пользователя и открытые ему
+// чужие в порядке ID.
+func (c *Calendar) Calendars(userID int) []UserCalendar {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := []UserCalendar{}
+	for _, cal := range c.calendars {
+		if access := c.access(cal.ID, userID); access != "" {
+			result = append(result, cal.viewFor(userID, access))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+func (c *Calendar) GetCalendar(id, userID int) (UserCalendar, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	access := c.access(id, userID)
+	if access == "" {
+		return UserCalendar{}, ErrCalendarNotFound
+	}
+	return c.calendars[id].viewFor(userID, access), nil
+}
+
+func (c *Calendar) putCalendar(cal UserCalendar) error {
+	if err := c.apply(Mutation{Op: OpPutCalendar, Calendar: &cal, NextID: c.nextID}); err != nil {
+		return err
+	}
+	c.calendars[cal.ID] = cal
+	return nil
+}
+
+// access возвращает права пользователя на календарь; пустая строка - доступа
+// нет или календаря не существует.
+func (c *Calendar) access(calendarID, userID int) Permission {
+	cal, ok := c.calendars[calendarID]
+	if !ok {
+		return ""
+	}
+	if cal.OwnerID == userID {
+		return PermissionOwner
+	}
+	if user, ok := c.users[userID]; ok {
+		return user.shared[calendarID]
+	}
+	return ""
+}
+
+func (c *Calendar) ownCalendar(id, userID int) (UserCalendar, error) {
+	switch c.access(id, userID) {
+	case PermissionOwner:
+		return c.calendars[id], nil
+	case "":
+		return UserCalendar{}, ErrCalendarNotFound
+	default:
+		return UserCalendar{}, ErrNotCalendarOwner
+	}
+}
+
+// calendarName проверяет название календаря: оно не пустое и не совпадает
+// (без учёта регистра) с другим календарём того же владельца.
+func (c *Calendar) calendarName(ownerID, id int, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxCalendarName {
+		return "", ErrCalendarNameInvalid
+	}
+	for _, cal := range c.calendars {
+		if cal.OwnerID == ownerID && cal.ID != id && strings.EqualFold(cal.Name, name) {
+			return "", ErrCalendarNameTaken
+		}
+	}
+	return name, nil
+}
+
+// calendarOwner проверяет, что userID может класть события в календарь, и
+// возвращает его владельца: событие в общем календаре принадлежит ему.
+func (c *Calendar) calendarOwner(calendarID, userID int) (int, error) {
+	if calendarID == 0 {
+		return userID, nil
+	}
+	switch access := c.access(calendarID, userID); {
+	case access == "":
+		return 0, ErrCalendarNotFound
+	case !access.canWrite():
+		return 0, ErrCalendarReadOnly
+	}
+	return c.calendars[calendarID].OwnerID, nil
+}
+
+// editable ищет событие, которое пользователь может изменить: своё или из
+// календаря, открытого ему на запись.
+func (c *Calendar) editable(id, userID int) (Event, error) {
+	if event, ok := c.find(id, userID); ok {
+		return event, nil
+	}
+	if event, ok := c.sharedEvent(id, userID); ok {
+		if !c.access(event.CalendarID, userID).canWrite() {
+			return Event{}, ErrCalendarReadOnly
+		}
+		return event, nil
+	}
+	return Event{}, c.notFound(id, userID)
+}
+
+// sharedEvent ищет событие в календарях, открытых пользователю.
+func (c *Calendar) sharedEvent(id, userID int) (Event, bool) {
+	user, ok := c.users[userID]
+	if !ok {
+		return Event{}, false
+	}
+	for calendarID := range user.shared {
+		if event, ok := c.find(id, c.calendars[calendarID].OwnerID); ok && event.CalendarID == calendarID {
+			return event, true
+		}
+	}
+	return Event{}, false
+}
+
+// sharedBetween разворачивает события открытых пользователю календарей в
+// [from, to). События, куда он приглашён, уже есть среди приглашений.
+func (c *Calendar) sharedBetween(user *userEvents, from, to time.Time) []Event {
+	var result []Event
+	for calendarID := range user.shared {
+		owner := c.users[c.calendars[calendarID].OwnerID]
+		for _, event := range owner.between(from, to) {
+			if _, invited := user.invited[event.ID]; event.CalendarID == calendarID && !invited {
+				result = append(result, event)
+			}
+		}
+	}
+	return result
+}
+
+// actAs записывает в аудит userID как автора изменений, которые он делает в
+// чужом календаре; возвращает функцию сброса.
+func (c *Calendar) actAs(userID int) func() {
+	c.actor = userID
+	return func() { c.actor = 0 }
+}
+
+// filterCalendars оставляет события из перечисленных календарей; 0 означает
+// календарь пользователя по умолчанию, пустой список - все календари.
+func filterCalendars(events []Event, userID int, calendarIDs []int) []Event {
+	if len(calendarIDs) == 0 {
+		return events
+	}
+	wanted := make(map[int]bool, len(calendarIDs))
+	for _, id := range calendarIDs {
+		wanted[id] = true
+	}
+
+	result := events[:0]
+	for _, event := range events {
+		if wanted[event.CalendarID] && (event.CalendarID != 0 || event.UserID == userID) {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// parseCalendarIDs читает фильтр calendar_id: параметр можно повторять или
+// перечислять ID через запятую.
+func parseCalendarIDs(query url.Values) ([]int, error) {
+	var ids []int
+	for _, value := range query["calendar_id"] {
+		for _, idStr := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(idStr))
+			if err != nil || id < 0 {
+				return nil, invalidField("calendar_id", errors.New("must be a list of calendar IDs"))
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+type calendarRequest struct {
+	UserID int    `json:"user_id"`
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	// ShareWith и Permission - для /calendars/share; permission "none"
+	// закрывает доступ.
+	ShareWith  int        `json:"share_with"`
+	Permission Permission `json:"permission"`
+}
+
+func parseCalendarRequest(r *http.Request, req *calendarRequest) error {
+	if r.Header.Get("Content-Type") == "application/json" {
+		if err := decodeJSON(r, req); err != nil {
+			return err
+		}
+		req.UserID = principalUserID(r, req.UserID)
+		return nil
+	}
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	req.UserID, _ = strconv.Atoi(r.FormValue("user_id"))
+	req.ID, _ = strconv.Atoi(r.FormValue("id"))
+	req.ShareWith, _ = strconv.Atoi(r.FormValue("share_with"))
+	req.Name = r.FormValue("name")
+	req.Permission = Permission(r.FormValue("permission"))
+	return nil
+}
+
+// listCalendars - GET /calendars?user_id=.
+func (h *Handler) listCalendars(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		writeCalendarError(w, r, invalidField("user_id", errors.New("must be an integer")))
+		return
+	}
+	writeJSON(w, response{Result: h.calendar.Calendars(userID)}, http.StatusOK)
+}
+
+func (h *Handler) createCalendar(w http.ResponseWriter, r *http.Request) {
+	var req calendarRequest
+	if err := parseCalendarRequest(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	cal, err := h.calendar.CreateCalendar(req.UserID, req.Name)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeJSON(w, response{Result: cal}, http.StatusOK)
+}
+
+func (h *Handler) renameCalendar(w http.ResponseWriter, r *http.Request) {
+	var req calendarRequest
+	if err := parseCalendarRequest(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	cal, err := h.calendar.RenameCalendar(req.ID, req.UserID, req.Name)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeJSON(w, response{Result: cal}, http.StatusOK)
+}
+
+func (h *Handler) deleteCalendar(w http.ResponseWriter, r *http.Request) {
+	var req calendarRequest
+	if err := parseCalendarRequest(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	if err := h.calendar.DeleteCalendar(req.ID, req.UserID); err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeJSON(w, response{Result: "calendar deleted"}, http.StatusOK)
+}
+
+// shareCalendar - POST /calendars/share с user_id владельца, id календаря,
+// share_with и permission.
+func (h *Handler) shareCalendar(w http.ResponseWriter, r *http.Request) {
+	var req calendarRequest
+	if err := parseCalendarRequest(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	var cal UserCalendar
+	var err error
+	if req.Permission == "none" {
+		cal, err = h.calendar.UnshareCalendar(req.ID, req.UserID, req.ShareWith)
+	} else {
+		cal, err = h.calendar.ShareCalendar(req.ID, req.UserID, req.ShareWith, req.Permission)
+	}
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeJSON(w, response{Result: cal}, http.StatusOK)
+}
+
+func (h *Handler) v2ListCalendars(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["uid"])
+	writeJSON(w, h.calendar.Calendars(userID), http.StatusOK)
+}
+
+func (h *Handler) v2CreateCalendar(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["uid"])
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	cal, err := h.calendar.CreateCalendar(userID, req.Name)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/api/v2/users/"+strconv.Itoa(userID)+"/calendars/"+strconv.Itoa(cal.ID))
+	writeJSON(w, cal, http.StatusCreated)
+}
+
+func (h *Handler) v2GetCalendar(w http.ResponseWriter, r *http.Request) {
+	userID, id := calendarVars(r)
+
+	cal, err := h.calendar.GetCalendar(id, userID)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeJSON(w, cal, http.StatusOK)
+}
+
+func (h *Handler) v2RenameCalendar(w http.ResponseWriter, r *http.Request) {
+	userID, id := calendarVars(r)
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	cal, err := h.calendar.RenameCalendar(id, userID, req.Name)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeJSON(w, cal, http.StatusOK)
+}
+
+func (h *Handler) v2DeleteCalendar(w http.ResponseWriter, r *http.Request) {
+	userID, id := calendarVars(r)
+
+	if err := h.calendar.DeleteCalendar(id, userID); err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// v2ShareCalendar - PUT /users/{uid}/calendars/{cid}/shares/{sid} с телом
+// {"permission": "read"}.
+func (h *Handler) v2ShareCalendar(w http.ResponseWriter, r *http.Request) {
+	userID, id := calendarVars(r)
+	shareWith, _ := strconv.Atoi(mux.Vars(r)["sid"])
+
+	var req struct {
+		Permission Permission `json:"permission"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	cal, err := h.calendar.ShareCalendar(id, userID, shareWith, req.Permission)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeJSON(w, cal, http.StatusOK)
+}
+
+func (h *Handler) v2UnshareCalendar(w http.ResponseWriter, r *http.Request) {
+	userID, id := calendarVars(r)
+	shareWith, _ := strconv.Atoi(mux.Vars(r)["sid"])
+
+	if _, err := h.calendar.UnshareCalendar(id, userID, shareWith); err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func calendarVars(r *http.Request) (userID, id int) {
+	vars := mux.Vars(r)
+	userID, _ = strconv.Atoi(vars["uid"])
+	id, _ = strconv.Atoi(vars["cid"])
+	return userID, id
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCalendar_NamedCalendars(t *testing.T) {
+	calendar := NewCalendar()
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	work, err := calendar.CreateCalendar(1, " Работа ")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if work.Name != "Работа" || work.OwnerID != 1 {
+		t.Errorf("Unexpected calendar %+v", work)
+	}
+	if _, err := calendar.CreateCalendar(1, "работа"); !errors.Is(err, ErrCalendarNameTaken) {
+		t.Errorf("Expected ErrCalendarNameTaken, got %v", err)
+	}
+	if _, err := calendar.CreateCalendar(2, "Работа"); err != nil {
+		t.Errorf("Expected other user to reuse the name, got %v", err)
+	}
+	if _, err := calendar.CreateCalendar(1, ""); !errors.Is(err, ErrCalendarNameInvalid) {
+		t.Errorf("Expected ErrCalendarNameInvalid, got %v", err)
+	}
+
+	event, err := calendar.CreateEvent(1, date, "Standup", InCalendar(work.ID))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	calendar.CreateEvent(1, date, "Dentist")
+
+	if events := calendar.GetEventsForDay(1, date, work.ID); len(events) != 1 || events[0].ID != event.ID {
+		t.Errorf("Expected only work event, got %v", events)
+	}
+	if events := calendar.GetEventsForDay(1, date, 0); len(events) != 1 || events[0].Title != "Dentist" {
+		t.Errorf("Expected only default calendar event, got %v", events)
+	}
+
+	// без InCalendar изменение оставляет событие в прежнем календаре
+	updated, _ := calendar.UpdateEvent(event.ID, 1, date, "Daily")
+	if updated.CalendarID != work.ID {
+		t.Errorf("Expected event to stay in calendar %d, got %d", work.ID, updated.CalendarID)
+	}
+
+	if err := calendar.DeleteCalendar(work.ID, 1); !errors.Is(err, ErrCalendarNotEmpty) {
+		t.Errorf("Expected ErrCalendarNotEmpty, got %v", err)
+	}
+	if err := calendar.DeleteCalendar(work.ID, 2); !errors.Is(err, ErrCalendarNotFound) {
+		t.Errorf("Expected ErrCalendarNotFound for another user, got %v", err)
+	}
+	calendar.DeleteEvent(event.ID, 1)
+	if err := calendar.DeleteCalendar(work.ID, 1); err != nil {
+		t.Errorf("Expected empty calendar to be deleted, got %v", err)
+	}
+	if _, err := calendar.CreateEvent(1, date, "Lost", InCalendar(work.ID)); !errors.Is(err, ErrCalendarNotFound) {
+		t.Errorf("Expected ErrCalendarNotFound for deleted calendar, got %v", err)
+	}
+}
+
+func TestCalendar_SharedCalendars(t *testing.T) {
+	calendar := NewCalendar()
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	duty, _ := calendar.CreateCalendar(1, "Дежурства")
+	event, _ := calendar.CreateEvent(1, date, "On call", InCalendar(duty.ID))
+
+	if _, err := calendar.ShareCalendar(duty.ID, 2, 3, PermissionRead); !errors.Is(err, ErrCalendarNotFound) {
+		t.Errorf("Expected ErrCalendarNotFound for non-owner, got %v", err)
+	}
+	if _, err := calendar.ShareCalendar(duty.ID, 1, 1, PermissionRead); !errors.Is(err, ErrShareWithOwner) {
+		t.Errorf("Expected ErrShareWithOwner, got %v", err)
+	}
+	if _, err := calendar.ShareCalendar(duty.ID, 1, 2, PermissionRead); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if events := calendar.GetEventsForDay(2, date); len(events) != 1 || events[0].ID != event.ID {
+		t.Fatalf("Expected shared event to be visible, got %v", events)
+	}
+	if _, err := calendar.GetEvent(event.ID, 2); err != nil {
+		t.Errorf("Expected shared event to be readable, got %v", err)
+	}
+	if _, err := calendar.UpdateEvent(event.ID, 2, date, "Hijack"); !errors.Is(err, ErrCalendarReadOnly) {
+		t.Errorf("Expected ErrCalendarReadOnly, got %v", err)
+	}
+	if _, err := calendar.CreateEvent(2, date, "Swap", InCalendar(duty.ID)); !errors.Is(err, ErrCalendarReadOnly) {
+		t.Errorf("Expected ErrCalendarReadOnly on create, got %v", err)
+	}
+
+	calendar.ShareCalendar(duty.ID, 1, 2, PermissionWrite)
+	updated, err := calendar.UpdateEvent(event.ID, 2, date, "On call (swapped)")
+	if err != nil {
+		t.Fatalf("Expected writer to update, got %v", err)
+	}
+	if updated.UserID != 1 {
+		t.Errorf("Expected event to stay owned by calendar owner, got %d", updated.UserID)
+	}
+	history, _ := calendar.History(event.ID, 1)
+	if last := history[len(history)-1]; last.Actor != 2 {
+		t.Errorf("Expected writer to be recorded as actor, got %d", last.Actor)
+	}
+	created, err := calendar.CreateEvent(2, date.Add(time.Hour), "Handover", InCalendar(duty.ID))
+	if err != nil || created.UserID != 1 {
+		t.Errorf("Expected event created in owner's calendar, got %+v (%v)", created, err)
+	}
+
+	// чужие события не переносятся в собственный календарь писателя
+	own, _ := calendar.CreateCalendar(2, "Личное")
+	if _, err := calendar.UpdateEvent(event.ID, 2, date, "Moved", InCalendar(own.ID)); !errors.Is(err, ErrCalendarMismatch) {
+		t.Errorf("Expected ErrCalendarMismatch, got %v", err)
+	}
+
+	if cals := calendar.Calendars(2); len(cals) != 2 || cals[0].Access != PermissionWrite || cals[0].Shares != nil {
+		t.Errorf("Expected shared calendar without shares list, got %+v", cals)
+	}
+
+	calendar.UnshareCalendar(duty.ID, 1, 2)
+	if events := calendar.GetEventsForDay(2, date); len(events) != 0 {
+		t.Errorf("Expected no events after unshare, got %v", events)
+	}
+	if _, err := calendar.GetEvent(event.ID, 2); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound after unshare, got %v", err)
+	}
+}
+
+func TestFileStorage_CalendarsSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	storage, _ := NewFileStorage(dir, 100)
+	calendar, err := NewCalendarWithStorage(storage)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	work, _ := calendar.CreateCalendar(1, "Работа")
+	calendar.ShareCalendar(work.ID, 1, 2, PermissionRead)
+	calendar.CreateEvent(1, date, "Review", InCalendar(work.ID))
+	storage.journal.Close()
+
+	storage, _ = NewFileStorage(dir, 100)
+	calendar, err = NewCalendarWithStorage(storage)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer calendar.Close()
+
+	if cals := calendar.Calendars(1); len(cals) != 1 || len(cals[0].Shares) != 1 {
+		t.Fatalf("Expected calendar with share, got %+v", cals)
+	}
+	if events := calendar.GetEventsForDay(2, date); len(events) != 1 {
+		t.Errorf("Expected shared event after restart, got %v", events)
+	}
+	if event, _ := calendar.CreateEvent(1, date, "Next"); event.ID <= work.ID+1 {
+		t.Errorf("Expected ID after %d, got %d", work.ID+1, event.ID)
+	}
+}
+
+func TestHandlerV2_Calendars(t *testing.T) {
+	router := v2Router(NewCalendar())
+
+	rec := v2Do(router, "POST", "/api/v2/users/1/calendars", `{"name":"Работа"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var work UserCalendar
+	json.Unmarshal(rec.Body.Bytes(), &work)
+	if rec.Header().Get("Location") != "/api/v2/users/1/calendars/1" {
+		t.Errorf("Unexpected Location %q", rec.Header().Get("Location"))
+	}
+	if rec := v2Do(router, "POST", "/api/v2/users/1/calendars", `{"name":"Работа"}`, nil); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for duplicate name, got %d", rec.Code)
+	}
+
+	v2Do(router, "POST", "/api/v2/users/1/events", `{"date":"2024-01-01T10:00:00Z","title":"Review","calendar_id":1}`, nil)
+	v2Do(router, "POST", "/api/v2/users/1/events", `{"date":"2024-01-01T12:00:00Z","title":"Lunch"}`, nil)
+
+	if rec := v2Do(router, "GET", "/api/v2/users/2/events/2", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 before share, got %d", rec.Code)
+	}
+	if rec := v2Do(router, "PUT", "/api/v2/users/1/calendars/1/shares/2", `{"permission":"admin"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown permission, got %d", rec.Code)
+	}
+	if rec := v2Do(router, "PUT", "/api/v2/users/1/calendars/1/shares/2", `{"permission":"read"}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = v2Do(router, "GET", "/api/v2/users/2/events?from=2024-01-01&to=2024-01-02", "", nil)
+	var page eventPage
+	json.Unmarshal(rec.Body.Bytes(), &page)
+	if len(page.Events) != 1 || page.Events[0].Title != "Review" {
+		t.Errorf("Expected shared event in range, got %v", page.Events)
+	}
+	if rec := v2Do(router, "PATCH", "/api/v2/users/2/events/2", `{"title":"Mine"}`, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for read-only calendar, got %d", rec.Code)
+	}
+
+	rec = v2Do(router, "GET", "/api/v2/users/1/events?from=2024-01-01&to=2024-01-02&calendar_id=0", "", nil)
+	json.Unmarshal(rec.Body.Bytes(), &page)
+	if len(page.Events) != 1 || page.Events[0].Title != "Lunch" {
+		t.Errorf("Expected only default calendar, got %v", page.Events)
+	}
+
+	if rec := v2Do(router, "DELETE", "/api/v2/users/1/calendars/1", "", nil); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for non-empty calendar, got %d", rec.Code)
+	}
+	if rec := v2Do(router, "DELETE", "/api/v2/users/1/calendars/1/shares/2", "", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 on unshare, got %d", rec.Code)
+	}
+	if rec := v2Do(router, "GET", "/api/v2/users/2/calendars/1", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after unshare, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ChangeType string
+
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+
+	// changeBacklog - сколько последних изменений хранится для переподключений
+	changeBacklog = 1024
+	// subscriberBuffer - очередь подписчика; кто не успевает её разбирать,
+	// отключается и догоняет через backlog
+	subscriberBuffer = 64
+)
+
+// ErrChangesExpired означает, что запрошенные изменения уже вытеснены из
+// backlog (или получены от прошлого запуска сервера) и клиенту нужно заново
+// загрузить события.
+var ErrChangesExpired = errors.New("changes are no longer available, resync required")
+
+// Change - уведомление об изменении события. Seq растёт монотонно в пределах
+// запуска сервера, Epoch различает запуски; вместе они служат id для
+// возобновления потока.
+type Change struct {
+	Seq     uint64     `json:"seq"`
+	Epoch   string     `json:"epoch"`
+	Type    ChangeType `json:"type"`
+	UserID  int        `json:"user_id"`
+	EventID int        `json:"event_id"`
+	// Event - состояние события после изменения, для deleted - последнее
+	Event Event     `json:"event"`
+	At    time.Time `json:"at"`
+	// audience - кому видно изменение; собирается при публикации, чтобы
+	// backlog отвечал так же, как в момент изменения
+	audience []int
+}
+
+// ID возвращает id изменения в виде "<epoch>-<seq>".
+func (c Change) ID() string {
+	return c.Epoch + "-" + strconv.FormatUint(c.Seq, 10)
+}
+
+// parseChangeID разбирает id изменения. Голый seq без epoch принимается, но
+// ни с одним запуском не совпадёт и приведёт к ErrChangesExpired.
+func parseChangeID(id string) (string, uint64, error) {
+	epoch, seq, found := strings.Cut(id, "-")
+	if !found {
+		epoch, seq = "", id
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return epoch, n, err
+}
+
+// visibleTo сообщает, касается ли изменение пользователя.
+func (c Change) visibleTo(userID int) bool {
+	return containsInt(c.audience, userID)
+}
+
+func containsInt(list []int, n int) bool {
+	for _, item := range list {
+		if item == n {
+			return true
+		}
+	}
+	return false
+}
+
+// audience возвращает пользователей, которым событие видно в выборках:
+// организатора, приглашённых и тех, кому открыт календарь события.
+func (c *Calendar) audience(event Event) []int {
+	ids := []int{event.UserID}
+	for _, a := range event.Attendees {
+		ids = append(ids, a.UserID)
+	}
+	if cal, ok := c.calendars[event.CalendarID]; ok {
+		for _, share := range cal.Shares {
+			ids = append(ids, share.UserID)
+		}
+	}
+	return ids
+}
+
+// Subscription получает изменения событий одного пользователя. Канал C
+// закрывается при Close или если подписчик отстал.
+type Subscription struct {
+	C <-chan Change
+
+	ch     chan Change
+	userID int
+	hub    *changeHub
+}
+
+func (s *Subscription) Close() {
+	s.hub.unsubscribe(s)
+}
+
+// changeHub раздаёт изменения подписчикам и хранит кольцевой backlog.
+type changeHub struct {
+	mu sync.Mutex
+	// epoch - случайный id запуска: seq после рестарта начинается заново
+	epoch   string
+	seq     uint64
+	backlog []Change
+	subs    map[*Subscription]struct{}
+	now     func() time.Time
+}
+
+func newChangeHub() *changeHub {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return &changeHub{
+		epoch: hex.EncodeToString(b),
+		subs:  make(map[*Subscription]struct{}),
+		now:   time.Now,
+	}
+}
+
+func (h *changeHub) publish(typ ChangeType, event Event, audience []int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	change := Change{
+		Seq:      h.seq,
+		Epoch:    h.epoch,
+		Type:     typ,
+		UserID:   event.UserID,
+		EventID:  event.ID,
+		Event:    event,
+		At:       h.now(),
+		audience: audience,
+	}
+
+	if len(h.backlog) == changeBacklog {
+		copy(h.backlog, h.backlog[1:])
+		h.backlog = h.backlog[:changeBacklog-1]
+	}
+	h.backlog = append(h.backlog, change)
+
+	for sub := range h.subs {
+		if !change.visibleTo(sub.userID) {
+			continue
+		}
+		select {
+		case sub.ch <- change:
+		default:
+			delete(h.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// subscribe регистрирует подписчика и возвращает изменения пользователя с
+// Seq больше after. after == 0 означает "только новые изменения"; seq из
+// другого запуска (epoch не совпадает) уже ничего не значит.
+func (h *changeHub) subscribe(userID int, epoch string, after uint64) (*Subscription, []Change, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var missed []Change
+	if after > 0 {
+		if epoch != h.epoch || after > h.seq || (len(h.backlog) > 0 && after < h.backlog[0].Seq-1) {
+			return nil, nil, ErrChangesExpired
+		}
+		for _, change := range h.backlog {
+			if change.Seq > after && change.visibleTo(userID) {
+				missed = append(missed, change)
+			}
+		}
+	}
+
+	ch := make(chan Change, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, userID: userID, hub: h}
+	h.subs[sub] = struct{}{}
+	return sub, missed, nil
+}
+
+func (h *changeHub) unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Subscribe подписывает на изменения событий пользователя. Если after > 0,
+// вместе с подпиской возвращаются пропущенные изменения с Seq > after того же
+// запуска epoch.
+func (c *Calendar) Subscribe(userID int, epoch string, after uint64) (*Subscription, []Change, error) {
+	return c.changes.subscribe(userID, epoch, after)
+}
@@ -0,0 +1,412 @@
+// Package client - типизированный клиент HTTP API календаря L2_18.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client обращается к серверу календаря. Ресурсные операции идут через
+// /api/v2, остальные - через v1-маршруты.
+type Client struct {
+	baseURL string
+	http    *http.Client
+	token   string
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(c *http.Client) Option {
+	return func(client *Client) {
+		client.http = c
+	}
+}
+
+// WithToken задаёт bearer-токен (API-ключ или JWT) для серверов с -auth.
+func WithToken(token string) Option {
+	return func(client *Client) {
+		client.token = token
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError - ответ сервера с кодом 4xx/5xx.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Code       string
+	Details    []FieldError
+	// RetryAfter - из заголовка Retry-After ответа 429
+	RetryAfter time.Duration
+	// Results - результаты по операциям для отклонённого пакета
+	Results []BatchItemResult
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("calendar: %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("calendar: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// ListOptions - параметры списков. Без From отдаются сами события, с From -
+// вхождения в [From, To).
+type ListOptions struct {
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Cursor string
+	// Calendars ограничивает выборку календарями; 0 - календарь по умолчанию
+	Calendars []int
+}
+
+func (o ListOptions) values() url.Values {
+	q := url.Values{}
+	if !o.From.IsZero() {
+		q.Set("from", o.From.Format(time.RFC3339))
+	}
+	if !o.To.IsZero() {
+		q.Set("to", o.To.Format(time.RFC3339))
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Cursor != "" {
+		q.Set("cursor", o.Cursor)
+	}
+	for _, id := range o.Calendars {
+		q.Add("calendar_id", strconv.Itoa(id))
+	}
+	return q
+}
+
+func eventPath(userID, id int) string {
+	return fmt.Sprintf("/api/v2/users/%d/events/%d", userID, id)
+}
+
+// ifMatch делает запрос условным: version 0 снимает проверку.
+func ifMatch(id, version int) http.Header {
+	if version == 0 {
+		return nil
+	}
+	return http.Header{"If-Match": {fmt.Sprintf(`"%d-%d"`, id, version)}}
+}
+
+func (c *Client) ListEvents(ctx context.Context, userID int, opts ListOptions) (EventPage, error) {
+	var page EventPage
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v2/users/%d/events", userID), opts.values(), nil, nil, &page)
+	return page, err
+}
+
+func (c *Client) CreateEvent(ctx context.Context, userID int, input EventInput) (Event, error) {
+	var event Event
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v2/users/%d/events", userID), nil, nil, input, &event)
+	return event, err
+}
+
+func (c *Client) GetEvent(ctx context.Context, userID, id int) (Event, error) {
+	var event Event
+	err := c.do(ctx, http.MethodGet, eventPath(userID, id), nil, nil, nil, &event)
+	return event, err
+}
+
+// ReplaceEvent заменяет событие целиком; ненулевая version делает замену
+// условной (If-Match).
+func (c *Client) ReplaceEvent(ctx context.Context, userID, id, version int, input EventInput) (Event, error) {
+	var event Event
+	err := c.do(ctx, http.MethodPut, eventPath(userID, id), nil, ifMatch(id, version), input, &event)
+	return event, err
+}
+
+func (c *Client) PatchEvent(ctx context.Context, userID, id, version int, patch EventPatch) (Event, error) {
+	var event Event
+	err := c.do(ctx, http.MethodPatch, eventPath(userID, id), nil, ifMatch(id, version), patch, &event)
+	return event, err
+}
+
+func (c *Client) DeleteEvent(ctx context.Context, userID, id, version int) error {
+	return c.do(ctx, http.MethodDelete, eventPath(userID, id), nil, ifMatch(id, version), nil, nil)
+}
+
+func (c *Client) RespondToInvitation(ctx context.Context, userID, id int, status AttendeeStatus) (Event, error) {
+	var event Event
+	body := struct {
+		Status AttendeeStatus `json:"status"`
+	}{status}
+	err := c.do(ctx, http.MethodPost, eventPath(userID, id)+"/rsvp", nil, nil, body, &event)
+	return event, err
+}
+
+// Invitations возвращает приглашения пользователя; пустой status - с любым ответом.
+func (c *Client) Invitations(ctx context.Context, userID int, status AttendeeStatus, opts ListOptions) (EventPage, error) {
+	q := opts.values()
+	if status != "" {
+		q.Set("status", string(status))
+	}
+	var page EventPage
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v2/users/%d/invitations", userID), q, nil, nil, &page)
+	return page, err
+}
+
+func (c *Client) History(ctx context.Context, userID, id int) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	err := c.do(ctx, http.MethodGet, eventPath(userID, id)+"/history", nil, nil, nil, &entries)
+	return entries, err
+}
+
+func (c *Client) RestoreEvent(ctx context.Context, userID, id int) (Event, error) {
+	var event Event
+	err := c.do(ctx, http.MethodPost, eventPath(userID, id)+"/restore", nil, nil, nil, &event)
+	return event, err
+}
+
+// Trash возвращает удалённые события пользователя, недавно удалённые первыми.
+func (c *Client) Trash(ctx context.Context, userID int) ([]TrashedEvent, error) {
+	var trash []TrashedEvent
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v2/users/%d/trash", userID), nil, nil, nil, &trash)
+	return trash, err
+}
+
+func (c *Client) RevertEvent(ctx context.Context, userID, id, version int) (Event, error) {
+	var event Event
+	body := struct {
+		Version int `json:"version"`
+	}{version}
+	err := c.do(ctx, http.MethodPost, eventPath(userID, id)+"/revert", nil, nil, body, &event)
+	return event, err
+}
+
+func calendarPath(userID, id int) string {
+	return fmt.Sprintf("/api/v2/users/%d/calendars/%d", userID, id)
+}
+
+func (c *Client) ListCalendars(ctx context.Context, userID int) ([]Calendar, error) {
+	var calendars []Calendar
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v2/users/%d/calendars", userID), nil, nil, nil, &calendars)
+	return calendars, err
+}
+
+func (c *Client) CreateCalendar(ctx context.Context, userID int, name string) (Calendar, error) {
+	var calendar Calendar
+	body := struct {
+		Name string `json:"name"`
+	}{name}
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v2/users/%d/calendars", userID), nil, nil, body, &calendar)
+	return calendar, err
+}
+
+func (c *Client) RenameCalendar(ctx context.Context, userID, id int, name string) (Calendar, error) {
+	var calendar Calendar
+	body := struct {
+		Name string `json:"name"`
+	}{name}
+	err := c.do(ctx, http.MethodPatch, calendarPath(userID, id), nil, nil, body, &calendar)
+	return calendar, err
+}
+
+// DeleteCalendar удаляет календарь; непустой календарь не удаляется.
+func (c *Client) DeleteCalendar(ctx context.Context, userID, id int) error {
+	return c.do(ctx, http.MethodDelete, calendarPath(userID, id), nil, nil, nil, nil)
+}
+
+func (c *Client) ShareCalendar(ctx context.Context, userID, id, withUserID int, perm Permission) (Calendar, error) {
+	var calendar Calendar
+	body := struct {
+		Permission Permission `json:"permission"`
+	}{perm}
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("%s/shares/%d", calendarPath(userID, id), withUserID), nil, nil, body, &calendar)
+	return calendar, err
+}
+
+func (c *Client) UnshareCalendar(ctx context.Context, userID, id, withUserID int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("%s/shares/%d", calendarPath(userID, id), withUserID), nil, nil, nil, nil)
+}
+
+// Search ищет события по словам query и тегам tags.
+func (c *Client) Search(ctx context.Context, userID int, query string, tags []string, limit int) ([]SearchResult, error) {
+	q := url.Values{"user_id": {strconv.Itoa(userID)}}
+	if query != "" {
+		q.Set("q", query)
+	}
+	for _, tag := range tags {
+		q.Add("tag", tag)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var results []SearchResult
+	err := c.do(ctx, http.MethodGet, "/events/search", q, nil, nil, result(&results))
+	return results, err
+}
+
+func (c *Client) FreeBusy(ctx context.Context, userIDs []int, from, to time.Time) (FreeBusy, error) {
+	var busy FreeBusy
+	err := c.do(ctx, http.MethodGet, "/freebusy", rangeValues(userIDs, from, to), nil, nil, result(&busy))
+	return busy, err
+}
+
+// FreeSlots ищет до count общих свободных окон длительностью duration с
+// началом на сетке step; нулевые step и count - значения сервера по умолчанию.
+func (c *Client) FreeSlots(ctx context.Context, userIDs []int, from, to time.Time, duration, step time.Duration, count int) ([]Interval, error) {
+	q := rangeValues(userIDs, from, to)
+	q.Set("duration", duration.String())
+	if step > 0 {
+		q.Set("step", step.String())
+	}
+	if count > 0 {
+		q.Set("count", strconv.Itoa(count))
+	}
+	var slots []Interval
+	err := c.do(ctx, http.MethodGet, "/free_slots", q, nil, nil, result(&slots))
+	return slots, err
+}
+
+func rangeValues(userIDs []int, from, to time.Time) url.Values {
+	ids := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	return url.Values{
+		"user_ids": {strings.Join(ids, ",")},
+		"from":     {from.Format(time.RFC3339)},
+		"to":       {to.Format(time.RFC3339)},
+	}
+}
+
+// Batch применяет операции атомарно. Если пакет отклонён, *APIError.Results
+// показывает, какая операция его остановила.
+func (c *Client) Batch(ctx context.Context, ops []BatchOperation) ([]BatchItemResult, error) {
+	body := struct {
+		Operations []BatchOperation `json:"operations"`
+	}{ops}
+	var results []BatchItemResult
+	err := c.do(ctx, http.MethodPost, "/events/batch", nil, nil, body, result(&results))
+	return results, err
+}
+
+func (c *Client) ExportICS(ctx context.Context, userID int) ([]byte, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/export.ics", url.Values{"user_id": {strconv.Itoa(userID)}}, nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (c *Client) ImportICS(ctx context.Context, userID int, ics io.Reader) (ImportResult, error) {
+	resp, err := c.send(ctx, http.MethodPost, "/import", url.Values{"user_id": {strconv.Itoa(userID)}}, nil, ics, "text/calendar")
+	if err != nil {
+		return ImportResult{}, err
+	}
+	defer resp.Body.Close()
+
+	var imported ImportResult
+	if err := json.NewDecoder(resp.Body).Decode(result(&imported)); err != nil {
+		return ImportResult{}, fmt.Errorf("calendar: decode response: %w", err)
+	}
+	return imported, nil
+}
+
+// envelope - обёртка ответов v1: {"result": ...}.
+type envelope struct {
+	Result interface{} `json:"result"`
+}
+
+func result(v interface{}) *envelope {
+	return &envelope{Result: v}
+}
+
+// do отправляет JSON-тело body и разбирает JSON-ответ в out.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, body, out interface{}) error {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	resp, err := c.send(ctx, method, path, query, header, reader, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("calendar: decode response: %w", err)
+	}
+	return nil
+}
+
+// send выполняет запрос и превращает ответы 4xx/5xx в *APIError.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, header http.Header, body io.Reader, contentType string) (*http.Response, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 400 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: resp.Status}
+	var payload struct {
+		Error   string            `json:"error"`
+		Code    string            `json:"code"`
+		Details []FieldError      `json:"details"`
+		Result  []BatchItemResult `json:"result"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&payload) == nil && payload.Error != "" {
+		apiErr.Message = payload.Error
+		apiErr.Code = payload.Code
+		apiErr.Details = payload.Details
+		apiErr.Results = payload.Result
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return nil, apiErr
+}
@@ -0,0 +1,210 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Типы повторяют схемы /openapi.json; их соответствие серверу проверяют
+// контрактные тесты в L2_18.
+
+type AttendeeStatus string
+
+const (
+	StatusPending   AttendeeStatus = "pending"
+	StatusAccepted  AttendeeStatus = "accepted"
+	StatusDeclined  AttendeeStatus = "declined"
+	StatusTentative AttendeeStatus = "tentative"
+)
+
+type Event struct {
+	ID     int       `json:"id"`
+	UserID int       `json:"user_id"`
+	Date   time.Time `json:"date"`
+	Title  string    `json:"title"`
+	// CalendarID - 0 для календаря по умолчанию
+	CalendarID int       `json:"calendar_id,omitempty"`
+	End        time.Time `json:"end"`
+	Timezone   string    `json:"timezone"`
+	AllDay     bool      `json:"all_day,omitempty"`
+
+	Description string   `json:"description,omitempty"`
+	Location    string   `json:"location,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+
+	Reminders      []Reminder     `json:"reminders,omitempty"`
+	Attendees      []Attendee     `json:"attendees,omitempty"`
+	ResponseStatus AttendeeStatus `json:"response_status,omitempty"`
+	UID            string         `json:"uid"`
+	Version        int            `json:"version"`
+
+	Recurrence   *Recurrence `json:"recurrence,omitempty"`
+	SeriesID     int         `json:"series_id,omitempty"`
+	RecurrenceID *time.Time  `json:"recurrence_id,omitempty"`
+}
+
+type Recurrence struct {
+	Freq     string      `json:"freq"`
+	Interval int         `json:"interval,omitempty"`
+	ByDay    []string    `json:"by_day,omitempty"`
+	Count    int         `json:"count,omitempty"`
+	Until    *time.Time  `json:"until,omitempty"`
+	ExDates  []time.Time `json:"ex_dates,omitempty"`
+}
+
+type Reminder struct {
+	MinutesBefore int `json:"minutes_before"`
+}
+
+type Attendee struct {
+	UserID int            `json:"user_id"`
+	Status AttendeeStatus `json:"status"`
+}
+
+// EventInput - тело создания и замены события. Date и End принимают
+// YYYY-MM-DD (событие на весь день), RFC 3339 или локальное время в Timezone.
+type EventInput struct {
+	UserID  int    `json:"user_id,omitempty"`
+	ID      int    `json:"id,omitempty"`
+	Version int    `json:"version,omitempty"`
+	Date    string `json:"date"`
+	Title   string `json:"title"`
+	// CalendarID nil оставляет событие в прежнем календаре
+	CalendarID *int `json:"calendar_id,omitempty"`
+
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	RRule      string      `json:"rrule,omitempty"`
+	Occurrence string      `json:"occurrence,omitempty"`
+
+	End      string `json:"end,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+
+	Reminders []Reminder `json:"reminders,omitempty"`
+	UID       string     `json:"uid,omitempty"`
+
+	Description string   `json:"description,omitempty"`
+	Location    string   `json:"location,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+
+	Attendees []int          `json:"attendees,omitempty"`
+	Status    AttendeeStatus `json:"status,omitempty"`
+	Strict    bool           `json:"strict,omitempty"`
+}
+
+// EventPatch - частичное изменение: nil-поля не меняются. Recurrence задаётся
+// JSON-значением, json.RawMessage("null") снимает повторение.
+type EventPatch struct {
+	Title       *string         `json:"title,omitempty"`
+	Date        *string         `json:"date,omitempty"`
+	End         *string         `json:"end,omitempty"`
+	Duration    *string         `json:"duration,omitempty"`
+	Timezone    *string         `json:"timezone,omitempty"`
+	Recurrence  json.RawMessage `json:"recurrence,omitempty"`
+	RRule       *string         `json:"rrule,omitempty"`
+	Reminders   *[]Reminder     `json:"reminders,omitempty"`
+	UID         *string         `json:"uid,omitempty"`
+	Attendees   *[]int          `json:"attendees,omitempty"`
+	Description *string         `json:"description,omitempty"`
+	Location    *string         `json:"location,omitempty"`
+	Tags        *[]string       `json:"tags,omitempty"`
+	CalendarID  *int            `json:"calendar_id,omitempty"`
+}
+
+type Permission string
+
+const (
+	PermissionRead  Permission = "read"
+	PermissionWrite Permission = "write"
+	PermissionOwner Permission = "owner"
+)
+
+// Calendar - именованный календарь. Shares видны только владельцу, Access -
+// права запросившего пользователя.
+type Calendar struct {
+	ID      int        `json:"id"`
+	OwnerID int        `json:"owner_id"`
+	Name    string     `json:"name"`
+	Shares  []Share    `json:"shares,omitempty"`
+	Access  Permission `json:"access,omitempty"`
+}
+
+type Share struct {
+	UserID     int        `json:"user_id"`
+	Permission Permission `json:"permission"`
+}
+
+type EventPage struct {
+	Events     []Event `json:"events"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+type AuditEntry struct {
+	Seq     int64     `json:"seq"`
+	Action  string    `json:"action"`
+	EventID int       `json:"event_id"`
+	UserID  int       `json:"user_id"`
+	Actor   int       `json:"actor"`
+	At      time.Time `json:"at"`
+	Before  *Event    `json:"before,omitempty"`
+	After   *Event    `json:"after,omitempty"`
+}
+
+// TrashedEvent - удалённое событие, которое ещё можно восстановить
+// (RestoreEvent) до PurgeAt.
+type TrashedEvent struct {
+	Event     Event      `json:"event"`
+	DeletedAt time.Time  `json:"deleted_at"`
+	DeletedBy int        `json:"deleted_by"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"`
+}
+
+type SearchResult struct {
+	Event Event   `json:"event"`
+	Score float64 `json:"score"`
+}
+
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type FreeBusy struct {
+	UserIDs []int      `json:"user_ids"`
+	From    time.Time  `json:"from"`
+	To      time.Time  `json:"to"`
+	Busy    []Interval `json:"busy"`
+}
+
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+type BatchOperation struct {
+	Op string `json:"op"`
+	EventInput
+}
+
+type BatchItemResult struct {
+	Index   int          `json:"index"`
+	Op      string       `json:"op"`
+	Status  string       `json:"status"`
+	Event   *Event       `json:"event,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Code    string       `json:"code,omitempty"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+type ImportResult struct {
+	Created int      `json:"created"`
+	Updated int      `json:"updated"`
+	Skipped []string `json:"skipped,omitempty"`
+	Events  []Event  `json:"events"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/yokitheyo/level_2/L2_18/client"
+)
+
+// Контрактные тесты: клиентский пакет против настоящего Handler.
+
+func newContractServer(t *testing.T) *client.Client {
+	t.Helper()
+	r := mux.NewRouter()
+	NewHandler(NewCalendar()).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return client.New(server.URL, client.WithHTTPClient(server.Client()))
+}
+
+func TestClient_TypesMatchServer(t *testing.T) {
+	for _, pair := range [][2]interface{}{
+		{client.Event{}, Event{}},
+		{client.EventInput{}, eventRequest{}},
+		{client.EventPatch{}, eventPatch{}},
+		{client.Recurrence{}, Recurrence{}},
+		{client.Attendee{}, Attendee{}},
+		{client.EventPage{}, eventPage{}},
+		{client.AuditEntry{}, AuditEntry{}},
+		{client.TrashedEvent{}, TrashedEvent{}},
+		{client.SearchResult{}, SearchResult{}},
+		{client.FreeBusy{}, freeBusyResult{}},
+		{client.BatchOperation{}, batchOperation{}},
+		{client.BatchItemResult{}, batchItemResult{}},
+		{client.ImportResult{}, importResult{}},
+		{client.FieldError{}, FieldError{}},
+		{client.Calendar{}, UserCalendar{}},
+		{client.Share{}, Share{}},
+	} {
+		clientType, serverType := reflect.TypeOf(pair[0]), reflect.TypeOf(pair[1])
+		if got, want := jsonFields(clientType), jsonFields(serverType); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v has fields %v, server %v has %v", clientType, got, serverType, want)
+		}
+	}
+}
+
+func TestClient_EventLifecycle(t *testing.T) {
+	c := newContractServer(t)
+	ctx := context.Background()
+
+	event, err := c.CreateEvent(ctx, 1, client.EventInput{
+		Date:      "2024-01-01T09:00:00",
+		Title:     "Planning",
+		Duration:  "1h",
+		Timezone:  "Europe/Berlin",
+		Tags:      []string{"work"},
+		Attendees: []int{2},
+	})
+	if err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	if event.Version != 1 || event.Timezone != "Europe/Berlin" || event.End.Sub(event.Date) != time.Hour {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	title := "Quarterly planning"
+	event, err = c.PatchEvent(ctx, 1, event.ID, event.Version, client.EventPatch{Title: &title})
+	if err != nil || event.Title != title || event.Version != 2 {
+		t.Fatalf("PatchEvent: %+v, %v", event, err)
+	}
+
+	_, err = c.ReplaceEvent(ctx, 1, event.ID, 1, client.EventInput{Date: "2024-01-02", Title: "Stale"})
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusPreconditionFailed || apiErr.Code != string(CodeVersionMismatch) {
+		t.Errorf("Expected 412 version_mismatch, got %v", err)
+	}
+
+	if _, err := c.RespondToInvitation(ctx, 2, event.ID, client.StatusAccepted); err != nil {
+		t.Errorf("RespondToInvitation: %v", err)
+	}
+	if page, err := c.Invitations(ctx, 2, client.StatusAccepted, client.ListOptions{}); err != nil || len(page.Events) != 1 {
+		t.Errorf("Invitations: %+v, %v", page, err)
+	}
+
+	results, err := c.Search(ctx, 1, "quart", nil, 0)
+	if err != nil || len(results) != 1 {
+		t.Errorf("Search: %+v, %v", results, err)
+	}
+
+	page, err := c.ListEvents(ctx, 1, client.ListOptions{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil || len(page.Events) != 1 {
+		t.Errorf("ListEvents: %+v, %v", page, err)
+	}
+
+	if err := c.DeleteEvent(ctx, 1, event.ID, 0); err != nil {
+		t.Fatalf("DeleteEvent: %v", err)
+	}
+	if _, err := c.GetEvent(ctx, 1, event.ID); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %v", err)
+	}
+	if _, err := c.RestoreEvent(ctx, 1, event.ID); err != nil {
+		t.Fatalf("RestoreEvent: %v", err)
+	}
+	event, err = c.RevertEvent(ctx, 1, event.ID, 1)
+	if err != nil || event.Title != "Planning" {
+		t.Errorf("RevertEvent: %+v, %v", event, err)
+	}
+	history, err := c.History(ctx, 1, event.ID)
+	if err != nil || len(history) != 6 {
+		t.Errorf("History: %d entries, %v", len(history), err)
+	}
+}
+
+func TestClient_ValidationError(t *testing.T) {
+	c := newContractServer(t)
+
+	_, err := c.CreateEvent(context.Background(), 1, client.EventInput{Date: "yesterday", Title: "Bad"})
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != string(CodeValidation) || len(apiErr.Details) != 1 || apiErr.Details[0].Field != "date" {
+		t.Errorf("Unexpected error: %+v", apiErr)
+	}
+}
+
+func TestClient_BatchAndScheduling(t *testing.T) {
+	c := newContractServer(t)
+	ctx := context.Background()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	results, err := c.Batch(ctx, []client.BatchOperation{
+		{Op: client.OpCreate, EventInput: client.EventInput{UserID: 1, Date: "2024-01-01T09:00:00Z", Duration: "1h", Title: "A"}},
+		{Op: client.OpCreate, EventInput: client.EventInput{UserID: 1, Date: "2024-01-01T13:00:00Z", Duration: "1h", Title: "B"}},
+	})
+	if err != nil || len(results) != 2 || results[1].Event == nil {
+		t.Fatalf("Batch: %+v, %v", results, err)
+	}
+
+	_, err = c.Batch(ctx, []client.BatchOperation{
+		{Op: client.OpCreate, EventInput: client.EventInput{UserID: 1, Date: "2024-01-02", Title: "C"}},
+		{Op: client.OpDelete, EventInput: client.EventInput{UserID: 1, ID: 99}},
+	})
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || len(apiErr.Results) != 2 || apiErr.Results[0].Status != batchRolledBack {
+		t.Errorf("Expected per-item results on failed batch, got %v", err)
+	}
+
+	busy, err := c.FreeBusy(ctx, []int{1}, day, day.Add(24*time.Hour))
+	if err != nil || len(busy.Busy) != 2 {
+		t.Errorf("FreeBusy: %+v, %v", busy, err)
+	}
+	slots, err := c.FreeSlots(ctx, []int{1}, day.Add(9*time.Hour), day.Add(12*time.Hour), time.Hour, 0, 1)
+	if err != nil || len(slots) != 1 || !slots[0].Start.Equal(day.Add(10*time.Hour)) {
+		t.Errorf("FreeSlots: %+v, %v", slots, err)
+	}
+
+	ics, err := c.ExportICS(ctx, 1)
+	if err != nil || !strings.Contains(string(ics), "SUMMARY:B") {
+		t.Fatalf("ExportICS: %v", err)
+	}
+	imported, err := c.ImportICS(ctx, 2, strings.NewReader(string(ics)))
+	if err != nil || imported.Created != 2 {
+		t.Errorf("ImportICS: %+v, %v", imported, err)
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yokitheyo/level_2/L2_18/client"
+)
+
+// eventFlags - поля события для create и update. В update попадают только
+// явно заданные флаги.
+type eventFlags struct {
+	title       string
+	date        string
+	end         string
+	duration    string
+	timezone    string
+	rrule       string
+	calendar    int
+	description string
+	location    string
+	tags        []string
+	reminders   []int
+	attendees   []int
+	uid         string
+}
+
+var eventFlagNames = []string{"title", "date", "end", "duration", "timezone", "rrule", "calendar",
+	"description", "location", "tag", "reminder", "attendee", "uid"}
+
+func addEventFlags(cmd *cobra.Command, f *eventFlags) {
+	flags := cmd.Flags()
+	flags.StringVar(&f.title, "title", "", "event title")
+	flags.StringVar(&f.date, "date", "", "start: YYYY-MM-DD for an all-day event, RFC 3339 or local time in --timezone")
+	flags.StringVar(&f.end, "end", "", "end in the same formats as --date")
+	flags.StringVar(&f.duration, "duration", "", "duration instead of --end, e.g. 1h30m")
+	flags.StringVar(&f.timezone, "timezone", "", "IANA timezone of the event")
+	flags.StringVar(&f.rrule, "rrule", "", "recurrence rule, e.g. FREQ=WEEKLY;BYDAY=MO,WE")
+	flags.IntVar(&f.calendar, "calendar", 0, "calendar ID, 0 for the default calendar")
+	flags.StringVar(&f.description, "description", "", "event description")
+	flags.StringVar(&f.location, "location", "", "event location")
+	flags.StringSliceVar(&f.tags, "tag", nil, "tag, may be repeated or comma-separated")
+	flags.IntSliceVar(&f.reminders, "reminder", nil, "reminder in minutes before the start, may be repeated")
+	flags.IntSliceVar(&f.attendees, "attendee", nil, "invited user ID, may be repeated")
+	flags.StringVar(&f.uid, "uid", "", "iCalendar UID")
+}
+
+func reminders(minutes []int) []client.Reminder {
+	result := make([]client.Reminder, len(minutes))
+	for i, m := range minutes {
+		result[i] = client.Reminder{MinutesBefore: m}
+	}
+	return result
+}
+
+func (f *eventFlags) input(cmd *cobra.Command) client.EventInput {
+	input := client.EventInput{
+		Title:       f.title,
+		Date:        f.date,
+		End:         f.end,
+		Duration:    f.duration,
+		Timezone:    f.timezone,
+		RRule:       f.rrule,
+		Description: f.description,
+		Location:    f.location,
+		Tags:        f.tags,
+		Reminders:   reminders(f.reminders),
+		Attendees:   f.attendees,
+		UID:         f.uid,
+	}
+	if cmd.Flags().Changed("calendar") {
+		input.CalendarID = &f.calendar
+	}
+	return input
+}
+
+func (f *eventFlags) patch(cmd *cobra.Command) (client.EventPatch, error) {
+	var patch client.EventPatch
+	changed := cmd.Flags().Changed
+	set := false
+	for _, name := range eventFlagNames {
+		set = set || changed(name)
+	}
+	if !set {
+		return patch, errors.New("nothing to update: set at least one event flag")
+	}
+	str := func(name string, value *string, field **string) {
+		if changed(name) {
+			*field = value
+		}
+	}
+	str("title", &f.title, &patch.Title)
+	str("date", &f.date, &patch.Date)
+	str("end", &f.end, &patch.End)
+	str("duration", &f.duration, &patch.Duration)
+	str("timezone", &f.timezone, &patch.Timezone)
+	str("description", &f.description, &patch.Description)
+	str("location", &f.location, &patch.Location)
+	str("uid", &f.uid, &patch.UID)
+	if changed("rrule") {
+		// пустое правило снимает повторение
+		if f.rrule == "" {
+			patch.Recurrence = []byte("null")
+		} else {
+			patch.RRule = &f.rrule
+		}
+	}
+	if changed("calendar") {
+		patch.CalendarID = &f.calendar
+	}
+	if changed("tag") {
+		patch.Tags = &f.tags
+	}
+	if changed("reminder") {
+		r := reminders(f.reminders)
+		patch.Reminders = &r
+	}
+	if changed("attendee") {
+		patch.Attendees = &f.attendees
+	}
+	return patch, nil
+}
+
+func eventID(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid event ID %q", arg)
+	}
+	return id, nil
+}
+
+func (a *app) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), a.Timeout)
+}
+
+func newCreateCmd(a *app) *cobra.Command {
+	var f eventFlags
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an event",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := a.context()
+			defer cancel()
+			event, err := a.client().CreateEvent(ctx, a.User, f.input(cmd))
+			if err != nil {
+				return err
+			}
+			return writeEvents(a.out, a.Output, []client.Event{event})
+		},
+	}
+	addEventFlags(cmd, &f)
+	cmd.MarkFlagRequired("title")
+	cmd.MarkFlagRequired("date")
+	return cmd
+}
+
+func newUpdateCmd(a *app) *cobra.Command {
+	var f eventFlags
+	var version int
+	cmd := &cobra.Command{
+		Use:   "update ID",
+		Short: "Change the given fields of an event",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := eventID(args[0])
+			if err != nil {
+				return err
+			}
+			patch, err := f.patch(cmd)
+			if err != nil {
+				return err
+			}
+			ctx, cancel := a.context()
+			defer cancel()
+			event, err := a.client().PatchEvent(ctx, a.User, id, version, patch)
+			if err != nil {
+				return err
+			}
+			return writeEvents(a.out, a.Output, []client.Event{event})
+		},
+	}
+	addEventFlags(cmd, &f)
+	cmd.Flags().IntVar(&version, "version", 0, "update only if the event is still at this version")
+	return cmd
+}
+
+func newDeleteCmd(a *app) *cobra.Command {
+	var version int
+	cmd := &cobra.Command{
+		Use:   "delete ID",
+		Short: "Delete an event; it stays in the trash until purged",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := eventID(args[0])
+			if err != nil {
+				return err
+			}
+			ctx, cancel := a.context()
+			defer cancel()
+			if err := a.client().DeleteEvent(ctx, a.User, id, version); err != nil {
+				return err
+			}
+			if a.Output == outputTable {
+				fmt.Fprintf(a.out, "event %d deleted\n", id)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&version, "version", 0, "delete only if the event is still at this version")
+	return cmd
+}
+
+// period - выбранный в list интервал; без него list отдаёт сами события и
+// серии, а не вхождения.
+type period struct {
+	day, week, month, span string
+	timezone               string
+}
+
+// bounds возвращает [from, to) выбранного периода. Неделя начинается с
+// понедельника, как и на сервере.
+func (p period) bounds() (from, to time.Time, err error) {
+	loc := time.Local
+	if p.timezone != "" {
+		if loc, err = time.LoadLocation(p.timezone); err != nil {
+			return from, to, fmt.Errorf("--timezone: %w", err)
+		}
+	}
+
+	set := 0
+	for _, v := range []string{p.day, p.week, p.month, p.span} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return from, to, errors.New("use only one of --day, --week, --month and --range")
+	}
+
+	switch {
+	case p.day != "":
+		from, err = parseDay(p.day, loc)
+		return from, from.AddDate(0, 0, 1), err
+	case p.week != "":
+		from, err = parseDay(p.week, loc)
+		from = from.AddDate(0, 0, -((int(from.Weekday()) + 6) % 7))
+		return from, from.AddDate(0, 0, 7), err
+	case p.month != "":
+		from, err = time.ParseInLocation("2006-01", p.month, loc)
+		if err != nil {
+			var day time.Time
+			if day, err = parseDay(p.month, loc); err == nil {
+				from = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, loc)
+			}
+		}
+		return from, from.AddDate(0, 1, 0), err
+	case p.span != "":
+		parts := strings.Split(p.span, ",")
+		if len(parts) != 2 {
+			return from, to, errors.New("--range must be FROM,TO")
+		}
+		if from, err = parseTime(parts[0], loc); err != nil {
+			return from, to, err
+		}
+		if to, err = parseTime(parts[1], loc); err != nil {
+			return from, to, err
+		}
+		if !to.After(from) {
+			return from, to, errors.New("--range: TO must be after FROM")
+		}
+		return from, to, nil
+	}
+	return from, to, nil
+}
+
+func parseDay(value string, loc *time.Location) (time.Time, error) {
+	if value == "today" {
+		now := time.Now().In(loc)
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc), nil
+	}
+	day, err := time.ParseInLocation(time.DateOnly, value, loc)
+	if err != nil {
+		return day, fmt.Errorf("invalid date %q: want YYYY-MM-DD or today", value)
+	}
+	return day, nil
+}
+
+func parseTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return parseDay(value, loc)
+}
+
+func newListCmd(a *app) *cobra.Command {
+	var p period
+	var calendars []int
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List events of a day, week, month or range",
+		Long: "Without a period, list prints events and series themselves; with one it prints\n" +
+			"the occurrences that intersect the period.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, to, err := p.bounds()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := a.context()
+			defer cancel()
+
+			opts := client.ListOptions{From: from, To: to, Limit: 500, Calendars: calendars}
+			var events []client.Event
+			for {
+				page, err := a.client().ListEvents(ctx, a.User, opts)
+				if err != nil {
+					return err
+				}
+				events = append(events, page.Events...)
+				if page.NextCursor == "" {
+					break
+				}
+				opts.Cursor = page.NextCursor
+			}
+			return writeEvents(a.out, a.Output, events)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&p.day, "day", "", "day YYYY-MM-DD or today")
+	flags.StringVar(&p.week, "week", "", "week (Monday to Sunday) containing the day YYYY-MM-DD or today")
+	flags.StringVar(&p.month, "month", "", "month YYYY-MM, or the month containing YYYY-MM-DD or today")
+	flags.StringVar(&p.span, "range", "", "FROM,TO as YYYY-MM-DD or RFC 3339, TO excluded")
+	flags.StringVar(&p.timezone, "timezone", "", "timezone of the period bounds (default local)")
+	flags.IntSliceVar(&calendars, "calendar", nil, "only these calendar IDs, 0 for the default calendar")
+	return cmd
+}
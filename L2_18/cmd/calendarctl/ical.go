@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newImportCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "import FILE",
+		Short: "Import events from an iCalendar file, - for stdin",
+		Long: "Events are matched by UID: known ones are updated, new ones created. The table\n" +
+			"and CSV outputs print the imported events, JSON prints the whole import result.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var in io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				file, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				in = file
+			}
+
+			ctx, cancel := a.context()
+			defer cancel()
+			result, err := a.client().ImportICS(ctx, a.User, in)
+			if err != nil {
+				return err
+			}
+			if a.Output == outputJSON {
+				return writeJSON(a.out, result)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "created %d, updated %d, skipped %d\n", result.Created, result.Updated, len(result.Skipped))
+			for _, skipped := range result.Skipped {
+				fmt.Fprintf(cmd.ErrOrStderr(), "skipped: %s\n", skipped)
+			}
+			return writeEvents(a.out, a.Output, result.Events)
+		},
+	}
+}
+
+func newExportCmd(a *app) *cobra.Command {
+	var path string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the user's events as iCalendar",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := a.context()
+			defer cancel()
+			ics, err := a.client().ExportICS(ctx, a.User)
+			if err != nil {
+				return err
+			}
+			if path == "" || path == "-" {
+				_, err = a.out.Write(ics)
+				return err
+			}
+			return os.WriteFile(path, ics, 0o644)
+		},
+	}
+	cmd.Flags().StringVarP(&path, "file", "f", "", "write to this file instead of stdout")
+	return cmd
+}
@@ -0,0 +1,155 @@
+// Command calendarctl - консольный клиент сервера календаря L2_18 для
+// скриптов: создание, изменение и удаление событий, выборки за период,
+// импорт и экспорт iCalendar.
+//
+// Адрес сервера, токен, пользователь и формат вывода берутся из флагов,
+// переменных окружения CALENDARCTL_SERVER, CALENDARCTL_TOKEN,
+// CALENDARCTL_USER, CALENDARCTL_OUTPUT или JSON-файла настроек (--config или
+// $CALENDARCTL_CONFIG, по умолчанию calendarctl/config.json в
+// os.UserConfigDir); флаги перекрывают окружение, окружение - файл.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yokitheyo/level_2/L2_18/client"
+)
+
+const envPrefix = "CALENDARCTL_"
+
+// settings - настройки, общие для всех подкоманд; JSON-поля совпадают с
+// ключами файла настроек.
+type settings struct {
+	Server  string        `json:"server"`
+	Token   string        `json:"token"`
+	User    int           `json:"user"`
+	Output  string        `json:"output"`
+	Timeout time.Duration `json:"-"`
+	config  string
+}
+
+type app struct {
+	settings
+	out io.Writer
+}
+
+func (a *app) client() *client.Client {
+	opts := []client.Option{client.WithHTTPClient(&http.Client{Timeout: a.Timeout})}
+	if a.Token != "" {
+		opts = append(opts, client.WithToken(a.Token))
+	}
+	return client.New(a.Server, opts...)
+}
+
+func main() {
+	if err := newRootCmd(os.Stdout, os.LookupEnv).Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func newRootCmd(out io.Writer, lookupEnv func(string) (string, bool)) *cobra.Command {
+	a := &app{out: out}
+	root := &cobra.Command{
+		Use:           "calendarctl",
+		Short:         "Command line client for the calendar server",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := a.load(cmd, lookupEnv); err != nil {
+				return err
+			}
+			switch a.Output {
+			case outputTable, outputJSON, outputCSV:
+			default:
+				return fmt.Errorf("unknown output %q: want table, json or csv", a.Output)
+			}
+			if a.User <= 0 {
+				return errors.New("user is required: set --user, $CALENDARCTL_USER or \"user\" in the config file")
+			}
+			return nil
+		},
+	}
+	root.SetOut(out)
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&a.config, "config", "", "JSON settings file (default calendarctl/config.json in the user config dir)")
+	flags.StringVar(&a.Server, "server", "http://localhost:8080", "calendar server URL")
+	flags.StringVar(&a.Token, "token", "", "API key or JWT for servers started with -auth")
+	flags.IntVarP(&a.User, "user", "u", 0, "user ID the command acts for")
+	flags.StringVarP(&a.Output, "output", "o", outputTable, "output format: table, json or csv")
+	flags.DurationVar(&a.Timeout, "timeout", 30*time.Second, "request timeout")
+
+	root.AddCommand(newCreateCmd(a), newUpdateCmd(a), newDeleteCmd(a), newListCmd(a), newImportCmd(a), newExportCmd(a))
+	return root
+}
+
+// load дополняет незаданные флаги значениями из окружения и файла настроек.
+func (a *app) load(cmd *cobra.Command, lookupEnv func(string) (string, bool)) error {
+	path := a.config
+	if path == "" {
+		path, _ = lookupEnv(envPrefix + "CONFIG")
+	}
+	explicit := path != ""
+	if path == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "calendarctl", "config.json")
+		}
+	}
+
+	var file settings
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &file); err != nil {
+				return fmt.Errorf("config %s: %w", path, err)
+			}
+		case explicit || !errors.Is(err, os.ErrNotExist):
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+
+	flags := cmd.Flags()
+	pick := func(name, fromFile string) (string, bool) {
+		if flags.Changed(name) {
+			return "", false
+		}
+		if value, ok := lookupEnv(envPrefix + strings.ToUpper(name)); ok {
+			return value, true
+		}
+		return fromFile, fromFile != ""
+	}
+	if value, ok := pick("server", file.Server); ok {
+		a.Server = value
+	}
+	if value, ok := pick("token", file.Token); ok {
+		a.Token = value
+	}
+	if value, ok := pick("output", file.Output); ok {
+		a.Output = value
+	}
+	fileUser := ""
+	if file.User != 0 {
+		fileUser = strconv.Itoa(file.User)
+	}
+	if value, ok := pick("user", fileUser); ok {
+		user, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%sUSER: must be an integer", envPrefix)
+		}
+		a.User = user
+	}
+	return nil
+}
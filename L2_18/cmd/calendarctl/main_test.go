@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPeriod_Bounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		p        period
+		from, to string
+	}{
+		{"day", period{day: "2024-03-14"}, "2024-03-14", "2024-03-15"},
+		{"week from Monday", period{week: "2024-03-14"}, "2024-03-11", "2024-03-18"},
+		{"week on Sunday", period{week: "2024-03-17"}, "2024-03-11", "2024-03-18"},
+		{"month", period{month: "2024-02"}, "2024-02-01", "2024-03-01"},
+		{"month by day", period{month: "2024-12-31"}, "2024-12-01", "2025-01-01"},
+		{"range", period{span: "2024-03-01,2024-03-04"}, "2024-03-01", "2024-03-04"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.p.timezone = "Europe/Moscow"
+			from, to, err := tt.p.bounds()
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if from.Format(time.DateOnly) != tt.from || to.Format(time.DateOnly) != tt.to {
+				t.Errorf("Expected [%s, %s), got [%v, %v)", tt.from, tt.to, from, to)
+			}
+			if from.Location().String() != "Europe/Moscow" {
+				t.Errorf("Expected bounds in Europe/Moscow, got %v", from.Location())
+			}
+		})
+	}
+
+	for _, p := range []period{{day: "2024-03-14", week: "2024-03-14"}, {span: "2024-03-04,2024-03-01"}, {span: "2024-03-04"}, {day: "14.03.2024"}} {
+		if _, _, err := p.bounds(); err == nil {
+			t.Errorf("Expected error for %+v", p)
+		}
+	}
+}
+
+// run выполняет calendarctl с аргументами args и окружением env.
+func run(t *testing.T, env map[string]string, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	lookupEnv := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+	cmd := newRootCmd(&out, lookupEnv)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestSettings_Precedence(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.Path+" "+r.Header.Get("Authorization"))
+		w.Write([]byte(`{"events":[]}`))
+	}))
+	defer server.Close()
+
+	config := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(config, []byte(`{"server":"`+server.URL+`","token":"from-file","user":7}`), 0o600)
+
+	if _, err := run(t, map[string]string{"CALENDARCTL_CONFIG": config}, "list"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := run(t, map[string]string{"CALENDARCTL_CONFIG": config, "CALENDARCTL_TOKEN": "from-env"}, "list", "-u", "8"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := []string{"/api/v2/users/7/events Bearer from-file", "/api/v2/users/8/events Bearer from-env"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if _, err := run(t, map[string]string{"CALENDARCTL_CONFIG": filepath.Join(t.TempDir(), "missing.json")}, "list"); err == nil {
+		t.Error("Expected error for missing explicit config")
+	}
+	if _, err := run(t, map[string]string{"CALENDARCTL_CONFIG": config}, "list", "-o", "xml"); err == nil {
+		t.Error("Expected error for unknown output")
+	}
+}
+
+func TestList_PagesAndFormats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("from") != "2024-03-11T00:00:00Z" || q.Get("to") != "2024-03-18T00:00:00Z" || q.Get("calendar_id") != "3" {
+			http.Error(w, "unexpected query "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		page := map[string]interface{}{
+			"events":      []map[string]interface{}{{"id": 1, "title": "Standup, daily", "date": "2024-03-11T09:00:00Z", "calendar_id": 3, "version": 1}},
+			"next_cursor": "next",
+		}
+		if q.Get("cursor") == "next" {
+			page = map[string]interface{}{"events": []map[string]interface{}{{"id": 2, "title": "Retro", "date": "2024-03-15T16:00:00Z", "calendar_id": 3, "version": 2}}}
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	args := []string{"list", "--server", server.URL, "-u", "1", "--week", "2024-03-14", "--timezone", "UTC", "--calendar", "3"}
+	out, err := run(t, nil, append(args, "-o", "csv")...)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := "ID,START,END,TITLE,CALENDAR,TAGS,VERSION\n" +
+		"1,2024-03-11T09:00:00Z,,\"Standup, daily\",3,,1\n" +
+		"2,2024-03-15T16:00:00Z,,Retro,3,,2\n"
+	if out != want {
+		t.Errorf("Expected CSV\n%s\ngot\n%s", want, out)
+	}
+
+	out, err = run(t, nil, append(args, "-o", "json")...)
+	var events []map[string]interface{}
+	if err != nil || json.Unmarshal([]byte(out), &events) != nil || len(events) != 2 {
+		t.Errorf("Expected JSON array of 2 events, got %s (%v)", out, err)
+	}
+}
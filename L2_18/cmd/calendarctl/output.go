@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yokitheyo/level_2/L2_18/client"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+var eventColumns = []string{"ID", "START", "END", "TITLE", "CALENDAR", "TAGS", "VERSION"}
+
+// eventRow - событие одной строкой таблицы или CSV. Вхождения серии
+// отличаются от самой серии только временем, поэтому ID у них общий.
+func eventRow(event client.Event) []string {
+	layout := time.RFC3339
+	if event.AllDay {
+		layout = time.DateOnly
+	}
+	end := ""
+	if !event.End.IsZero() {
+		end = event.End.Format(layout)
+	}
+	return []string{
+		strconv.Itoa(event.ID),
+		event.Date.Format(layout),
+		end,
+		event.Title,
+		strconv.Itoa(event.CalendarID),
+		strings.Join(event.Tags, ","),
+		strconv.Itoa(event.Version),
+	}
+}
+
+// writeEvents печатает события в выбранном формате; JSON - массив событий
+// API как есть.
+func writeEvents(w io.Writer, format string, events []client.Event) error {
+	switch format {
+	case outputJSON:
+		if events == nil {
+			events = []client.Event{}
+		}
+		return writeJSON(w, events)
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(eventColumns)
+		for _, event := range events {
+			cw.Write(eventRow(event))
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(eventColumns, "\t"))
+		for _, event := range events {
+			fmt.Fprintln(tw, strings.Join(eventRow(event), "\t"))
+		}
+		return tw.Flush()
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
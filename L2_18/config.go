@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// envPrefix - префикс переменных окружения: флагу -data-dir соответствует
+// CALENDAR_DATA_DIR.
+const envPrefix = "CALENDAR_"
+
+// defaultRouteLimits снимает ограничение частоты с проб, метрик и описания API
+// и разрешает импорт iCalendar-файлов до 10 МБ.
+const defaultRouteLimits = "/healthz=rate:0; /readyz=rate:0; /metrics=rate:0; /openapi.json=rate:0; POST /import=body:10485760"
+
+type Config struct {
+	Port             string
+	GRPCPort         string
+	Storage          string
+	DataDir          string
+	SnapshotEvery    int
+	Notifier         string
+	WebhookURL       string
+	ReminderInterval time.Duration
+	TrashRetention   time.Duration
+	PurgeInterval    time.Duration
+	Auth             string
+	APIKeysFile      string
+	JWTSecret        string
+	LogFormat        string
+	LogLevel         string
+
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	ReadinessGrace    time.Duration
+
+	RateLimit   float64
+	RateBurst   int
+	MaxBody     int64
+	RouteLimits RouteLimits
+}
+
+func newFlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("calendar", flag.ContinueOnError)
+	fs.String("config", "", "JSON config file with flag names as keys (default $CALENDAR_CONFIG)")
+
+	fs.StringVar(&cfg.Port, "port", "8080", "HTTP server port")
+	fs.StringVar(&cfg.GRPCPort, "grpc-port", "9090", "gRPC server port, empty disables gRPC")
+	fs.StringVar(&cfg.Storage, "storage", "memory", "event storage backend: memory or file")
+	fs.StringVar(&cfg.DataDir, "data-dir", "data", "directory for the file storage journal and snapshots")
+	fs.IntVar(&cfg.SnapshotEvery, "snapshot-every", 1000, "journal records between file storage snapshots")
+	fs.StringVar(&cfg.Notifier, "notifier", "log", "reminder notifier: log, webhook or none")
+	fs.StringVar(&cfg.WebhookURL, "webhook-url", "", "URL the webhook notifier posts reminders to")
+	fs.DurationVar(&cfg.ReminderInterval, "reminder-interval", 30*time.Second, "how often due reminders are checked")
+	fs.DurationVar(&cfg.TrashRetention, "trash-retention", 30*24*time.Hour, "how long deleted events can be restored, 0 keeps them forever")
+	fs.DurationVar(&cfg.PurgeInterval, "purge-interval", time.Hour, "how often expired deleted events are purged")
+	fs.StringVar(&cfg.Auth, "auth", "none", "authentication: none, apikeys or jwt")
+	fs.StringVar(&cfg.APIKeysFile, "api-keys-file", "api_keys.json", "JSON file with API keys for -auth=apikeys")
+	fs.StringVar(&cfg.JWTSecret, "jwt-secret", "", "HS256 secret for -auth=jwt")
+	fs.StringVar(&cfg.LogFormat, "log-format", "json", "log output: json or text")
+	fs.StringVar(&cfg.LogLevel, "log-level", "info", "minimum log level: debug, info, warn or error")
+
+	fs.DurationVar(&cfg.ReadTimeout, "read-timeout", 15*time.Second, "maximum duration for reading a whole request")
+	fs.DurationVar(&cfg.ReadHeaderTimeout, "read-header-timeout", 5*time.Second, "maximum duration for reading request headers")
+	fs.DurationVar(&cfg.WriteTimeout, "write-timeout", 30*time.Second, "maximum duration before timing out response writes")
+	fs.DurationVar(&cfg.IdleTimeout, "idle-timeout", 2*time.Minute, "how long keep-alive connections stay idle")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", 20*time.Second, "how long in-flight requests may drain on SIGTERM")
+	fs.DurationVar(&cfg.ReadinessGrace, "readiness-grace", 5*time.Second, "how long /readyz reports 503 on SIGTERM before the server stops accepting connections")
+
+	fs.Float64Var(&cfg.RateLimit, "rate-limit", 20, "requests per second per client and route, 0 disables rate limiting")
+	fs.IntVar(&cfg.RateBurst, "rate-burst", 40, "requests a client may make in a burst above -rate-limit")
+	fs.Int64Var(&cfg.MaxBody, "max-body", 1<<20, "maximum request body size in bytes, 0 for no limit")
+	cfg.RouteLimits = RouteLimits{}
+	cfg.RouteLimits.Set(defaultRouteLimits)
+	fs.Var(cfg.RouteLimits, "route-limits", `per-route overrides, e.g. "POST /import=body:10485760; /events/search=rate:5,burst:10"`)
+	return fs
+}
+
+// LoadConfig собирает настройки из значений по умолчанию, JSON-файла
+// (-config или $CALENDAR_CONFIG), переменных окружения CALENDAR_* и флагов
+// командной строки; каждый следующий источник перекрывает предыдущий.
+func LoadConfig(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	// предварительный разбор нужен только чтобы узнать путь к файлу
+	probe := newFlagSet(&Config{})
+	probe.SetOutput(io.Discard)
+	// ошибки во флагах (и -h) сообщит основной разбор ниже
+	probe.Parse(args)
+	path := probe.Lookup("config").Value.String()
+	if path == "" {
+		path, _ = lookupEnv(envPrefix + "CONFIG")
+	}
+
+	var cfg Config
+	fs := newFlagSet(&cfg)
+	if path != "" {
+		if err := loadConfigFile(fs, path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	var envErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := lookupEnv(envName(f.Name))
+		if !ok || f.Name == "config" || envErr != nil {
+			return
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			envErr = fmt.Errorf("%s: %w", envName(f.Name), err)
+		}
+	})
+	if envErr != nil {
+		return Config{}, envErr
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func loadConfigFile(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var values map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	for name, value := range values {
+		if name == "config" || fs.Lookup(name) == nil {
+			return fmt.Errorf("%s: unknown setting %q", path, name)
+		}
+		if err := fs.Set(name, fmt.Sprint(value)); err != nil {
+			return fmt.Errorf("%s: %s: %w", path, name, err)
+		}
+	}
+	return nil
+}
+
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.json")
+	os.WriteFile(path, []byte(`{"port": "9000", "storage": "file", "snapshot-every": 50, "write-timeout": "1m"}`), 0o644)
+
+	env := map[string]string{
+		"CALENDAR_CONFIG":  path,
+		"CALENDAR_STORAGE": "memory",
+		"CALENDAR_PORT":    "9100",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	cfg, err := LoadConfig([]string{"-port", "9200"}, lookup)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// флаг важнее окружения, окружение важнее файла, файл важнее умолчаний
+	if cfg.Port != "9200" {
+		t.Errorf("Expected port from flag, got %s", cfg.Port)
+	}
+	if cfg.Storage != "memory" {
+		t.Errorf("Expected storage from env, got %s", cfg.Storage)
+	}
+	if cfg.SnapshotEvery != 50 || cfg.WriteTimeout != time.Minute {
+		t.Errorf("Expected values from file, got %d and %v", cfg.SnapshotEvery, cfg.WriteTimeout)
+	}
+	if cfg.ReadHeaderTimeout != 5*time.Second || cfg.ReadinessGrace != 5*time.Second {
+		t.Errorf("Expected default read header timeout and readiness grace, got %v and %v", cfg.ReadHeaderTimeout, cfg.ReadinessGrace)
+	}
+}
+
+func TestLoadConfig_UnknownSetting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.json")
+	os.WriteFile(path, []byte(`{"prot": "9000"}`), 0o644)
+
+	noEnv := func(string) (string, bool) { return "", false }
+	if _, err := LoadConfig([]string{"-config", path}, noEnv); err == nil {
+		t.Error("Expected error for unknown setting")
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// ErrorCode - машиночитаемый код ошибки в ответе API. В отличие от текста
+// ошибки коды стабильны, и клиенты могут на них опираться.
+type ErrorCode string
+
+const (
+	CodeBadRequest      ErrorCode = "bad_request"
+	CodeValidation      ErrorCode = "validation_failed"
+	CodeNotFound        ErrorCode = "not_found"
+	CodeConflict        ErrorCode = "conflict"
+	CodeVersionMismatch ErrorCode = "version_mismatch"
+	CodeUnauthenticated ErrorCode = "unauthenticated"
+	CodeForbidden       ErrorCode = "forbidden"
+	CodeUnavailable     ErrorCode = "unavailable"
+	CodeTooLarge        ErrorCode = "payload_too_large"
+	CodeRateLimited     ErrorCode = "rate_limited"
+	CodeChangesExpired  ErrorCode = "changes_expired"
+	CodeInternal        ErrorCode = "internal"
+)
+
+// FieldError описывает ошибку в одном поле запроса.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError собирает ошибки по полям запроса. Исходные ошибки
+// сохраняются, так что errors.Is(err, ErrRecurrenceInvalid) продолжает работать.
+type ValidationError struct {
+	Fields []FieldError
+	errs   []error
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.errs
+}
+
+func invalidField(field string, err error) *ValidationError {
+	return &ValidationError{
+		Fields: []FieldError{{Field: field, Message: err.Error()}},
+		errs:   []error{err},
+	}
+}
+
+// validationFields сопоставляет ошибки календаря полям запроса, в которых
+// они обычно возникают.
+var validationFields = []struct {
+	err   error
+	field string
+}{
+	{ErrDateInvalid, "date"},
+	{ErrEndBeforeStart, "end"},
+	{ErrTimezoneInvalid, "timezone"},
+	{ErrRecurrenceInvalid, "recurrence"},
+	{ErrReminderInvalid, "reminders"},
+	{ErrNotRecurring, "occurrence"},
+	{ErrCursorInvalid, "cursor"},
+	{ErrAttendeeStatusInvalid, "status"},
+	{ErrBatchInvalid, "operations"},
+	{ErrTagInvalid, "tags"},
+	{ErrCalendarNameInvalid, "name"},
+	{ErrPermissionInvalid, "permission"},
+	{ErrShareWithOwner, "share_with"},
+	{ErrCalendarMismatch, "calendar_id"},
+}
+
+// errorStatus определяет HTTP-статус и код ошибки. 503 отдаётся только при
+// недоступном хранилище: на остальные ошибки повтор запроса не поможет.
+func errorStatus(err error) (int, ErrorCode) {
+	var validation *ValidationError
+	switch {
+	case errors.As(err, &validation):
+		return http.StatusBadRequest, CodeValidation
+	case errors.Is(err, ErrEventNotFound), errors.Is(err, ErrOccurrenceNotFound), errors.Is(err, ErrVersionNotFound),
+		errors.Is(err, ErrCalendarNotFound):
+		return http.StatusNotFound, CodeNotFound
+	case errors.Is(err, ErrVersionConflict):
+		return http.StatusPreconditionFailed, CodeVersionMismatch
+	case errors.Is(err, ErrUIDConflict), errors.Is(err, ErrEventConflict), errors.Is(err, ErrEventNotDeleted),
+		errors.Is(err, ErrCalendarNameTaken), errors.Is(err, ErrCalendarNotEmpty):
+		return http.StatusConflict, CodeConflict
+	case errors.Is(err, ErrUnauthenticated):
+		return http.StatusUnauthorized, CodeUnauthenticated
+	case errors.Is(err, ErrForbidden), errors.Is(err, ErrNotOrganizer), errors.Is(err, ErrCalendarReadOnly),
+		errors.Is(err, ErrNotCalendarOwner):
+		return http.StatusForbidden, CodeForbidden
+	case errors.Is(err, ErrChangesExpired):
+		return http.StatusGone, CodeChangesExpired
+	case errors.Is(err, ErrStorageUnavailable), errors.Is(err, ErrStorageClosed):
+		return http.StatusServiceUnavailable, CodeUnavailable
+	}
+	for _, v := range validationFields {
+		if errors.Is(err, v.err) {
+			return http.StatusBadRequest, CodeValidation
+		}
+	}
+	return http.StatusInternalServerError, CodeInternal
+}
+
+// writeCalendarError пишет ошибку с кодом и, для ошибок валидации, списком полей.
+func writeCalendarError(w http.ResponseWriter, r *http.Request, err error) {
+	status, resp := errorResponse(r.Context(), err)
+	writeJSON(w, resp, status)
+}
+
+// errorResponse собирает ответ с ошибкой. ctx нужен только для лога
+// внутренних ошибок: по request_id их можно сопоставить с запросом.
+func errorResponse(ctx context.Context, err error) (int, response) {
+	status, code := errorStatus(err)
+
+	resp := response{Error: err.Error(), Code: code}
+	var validation *ValidationError
+	if errors.As(err, &validation) {
+		resp.Details = validation.Fields
+	} else if code == CodeValidation {
+		for _, v := range validationFields {
+			if errors.Is(err, v.err) {
+				resp.Details = []FieldError{{Field: v.field, Message: err.Error()}}
+				break
+			}
+		}
+	}
+	if status == http.StatusInternalServerError {
+		// подробности внутренних ошибок клиенту не нужны
+		slog.ErrorContext(ctx, "internal error", "request_id", RequestIDFromContext(ctx), "error", err)
+		resp.Error = "internal error"
+	}
+	return status, resp
+}
+
+// codeForStatus подбирает код для ошибок, которые обработчики формируют сами.
+func codeForStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusPreconditionFailed:
+		return CodeVersionMismatch
+	case http.StatusRequestEntityTooLarge:
+		return CodeTooLarge
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	default:
+		return CodeInternal
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   ErrorCode
+	}{
+		{ErrEventNotFound, http.StatusNotFound, CodeNotFound},
+		{fmt.Errorf("%w: FREQ", ErrRecurrenceInvalid), http.StatusBadRequest, CodeValidation},
+		{invalidField("date", errors.New("bad")), http.StatusBadRequest, CodeValidation},
+		{ErrUIDConflict, http.StatusConflict, CodeConflict},
+		{ErrVersionConflict, http.StatusPreconditionFailed, CodeVersionMismatch},
+		{fmt.Errorf("%w: disk full", ErrStorageUnavailable), http.StatusServiceUnavailable, CodeUnavailable},
+		{errors.New("boom"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		status, code := errorStatus(tt.err)
+		if status != tt.status || code != tt.code {
+			t.Errorf("%v: expected %d %s, got %d %s", tt.err, tt.status, tt.code, status, code)
+		}
+	}
+}
+
+func TestHandler_ErrorResponses(t *testing.T) {
+	r := mux.NewRouter()
+	NewHandler(NewCalendar()).RegisterRoutes(r)
+
+	post := func(path string, form url.Values) (int, response) {
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		var resp response
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec.Code, resp
+	}
+
+	// отсутствующее событие - 404, а не 503, чтобы клиенты не повторяли запрос
+	status, resp := post("/update_event", url.Values{"id": {"42"}, "user_id": {"1"}, "date": {"2024-01-01"}, "title": {"x"}})
+	if status != http.StatusNotFound || resp.Code != CodeNotFound {
+		t.Errorf("Expected 404 not_found, got %d %s", status, resp.Code)
+	}
+	if status, resp := post("/delete_event", url.Values{"id": {"42"}, "user_id": {"1"}}); status != http.StatusNotFound || resp.Code != CodeNotFound {
+		t.Errorf("Expected 404 not_found on delete, got %d %s", status, resp.Code)
+	}
+	occurrence := url.Values{"id": {"42"}, "user_id": {"1"}, "date": {"2024-01-01"}, "title": {"x"}, "occurrence": {"2024-01-01"}}
+	if status, resp := post("/update_event", occurrence); status != http.StatusNotFound || resp.Code != CodeNotFound {
+		t.Errorf("Expected 404 not_found for occurrence update, got %d %s", status, resp.Code)
+	}
+
+	status, resp = post("/create_event", url.Values{"user_id": {"1"}, "date": {"2024-01-01"}, "end": {"2024-01-01T10:00"}, "title": {"x"}})
+	if status != http.StatusBadRequest || resp.Code != CodeValidation {
+		t.Fatalf("Expected 400 validation_failed, got %d %s", status, resp.Code)
+	}
+	if len(resp.Details) != 1 || resp.Details[0].Field != "end" {
+		t.Errorf("Expected details for end, got %+v", resp.Details)
+	}
+
+	form := url.Values{"user_id": {"1"}, "date": {"2024-01-01"}, "title": {"x"}, "uid": {"a@example.com"}}
+	post("/create_event", form)
+	if status, resp := post("/create_event", form); status != http.StatusConflict || resp.Code != CodeConflict {
+		t.Errorf("Expected 409 conflict, got %d %s", status, resp.Code)
+	}
+}
+
+func TestWriteCalendarError_InternalLogged(t *testing.T) {
+	var logs bytes.Buffer
+	// SetDefault перенаправляет и пакет log, поэтому возвращаем оба
+	previous, output, flags := slog.Default(), log.Writer(), log.Flags()
+	defer func() {
+		slog.SetDefault(previous)
+		log.SetOutput(output)
+		log.SetFlags(flags)
+	}()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	req := httptest.NewRequest("GET", "/events", nil)
+	req = req.WithContext(WithRequestID(req.Context(), "trace-7"))
+	rec := httptest.NewRecorder()
+	writeCalendarError(rec, req, errors.New("disk on fire"))
+
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "disk on fire") {
+		t.Errorf("Expected 500 without details, got %d %s", rec.Code, rec.Body)
+	}
+	if line := logs.String(); !strings.Contains(line, `"request_id":"trace-7"`) || !strings.Contains(line, `"error":"disk on fire"`) {
+		t.Errorf("Expected internal error logged with request ID, got %s", line)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// conflictHorizon - насколько вперёд проверяются вхождения новой серии на
+// пересечения в строгом режиме.
+const conflictHorizon = maxRangeQuery
+
+var ErrEventConflict = errors.New("event overlaps an existing event")
+
+// Interval - полуинтервал [Start, End).
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// RejectConflicts включает строгий режим: CreateEvent и UpdateEvent вернут
+// ErrEventConflict, если событие пересекается с занятым временем организатора.
+func RejectConflicts() EventOption {
+	return func(e *Event) {
+		e.strict = true
+	}
+}
+
+// busy сообщает, занимает ли событие время пользователя. События на весь
+// день и без длительности время не занимают, отклонённые приглашения тоже.
+func (e Event) busy() bool {
+	return !e.AllDay && e.End.After(e.Date) && e.ResponseStatus != StatusDeclined
+}
+
+// FreeBusy возвращает объединённые интервалы, когда хотя бы один из
+// пользователей занят в [from, to). Интервалы обрезаются по границам запроса.
+func (c *Calendar) FreeBusy(userIDs []int, from, to time.Time) []Interval {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.busyBetween(userIDs, from, to)
+}
+
+func (c *Calendar) busyBetween(userIDs []int, from, to time.Time) []Interval {
+	var intervals []Interval
+	for _, userID := range userIDs {
+		for _, event := range c.visibleBetween(userID, from, to) {
+			if !event.busy() {
+				continue
+			}
+			start, end := event.Date, event.End
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			intervals = append(intervals, Interval{Start: start.In(from.Location()), End: end.In(from.Location())})
+		}
+	}
+	return mergeIntervals(intervals)
+}
+
+// mergeIntervals сортирует интервалы и склеивает пересекающиеся и смежные.
+func mergeIntervals(intervals []Interval) []Interval {
+	if len(intervals) == 0 {
+		return nil
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].Start.Before(intervals[j].Start)
+	})
+
+	merged := []Interval{intervals[0]}
+	for _, iv := range intervals[1:] {
+		last := &merged[len(merged)-1]
+		if iv.Start.After(last.End) {
+			merged = append(merged, iv)
+			continue
+		}
+		if iv.End.After(last.End) {
+			last.End = iv.End
+		}
+	}
+	return merged
+}
+
+// FreeSlots предлагает до n свободных у всех пользователей слотов длительностью
+// duration в [from, to). Начала слотов выравниваются по сетке step от from.
+func (c *Calendar) FreeSlots(userIDs []int, from, to time.Time, duration, step time.Duration, n int) []Interval {
+	if duration <= 0 || n <= 0 {
+		return nil
+	}
+	if step <= 0 {
+		step = duration
+	}
+
+	busy := c.FreeBusy(userIDs, from, to)
+	// фиктивный занятый интервал в конце закрывает последний промежуток
+	busy = append(busy, Interval{Start: to, End: to})
+
+	var slots []Interval
+	gapStart := from
+	for _, b := range busy {
+		for start := alignUp(gapStart, from, step); !start.Add(duration).After(b.Start); start = start.Add(step) {
+			slots = append(slots, Interval{Start: start, End: start.Add(duration)})
+			if len(slots) == n {
+				return slots
+			}
+		}
+		if b.End.After(gapStart) {
+			gapStart = b.End
+		}
+	}
+	return slots
+}
+
+// alignUp округляет t вверх до ближайшей точки сетки origin + k*step.
+func alignUp(t, origin time.Time, step time.Duration) time.Time {
+	offset := t.Sub(origin)
+	if rem := offset % step; rem != 0 {
+		offset += step - rem
+	}
+	return origin.Add(offset)
+}
+
+// checkConflicts ищет пересечения события (или вхождений серии в пределах
+// conflictHorizon) с занятым временем организатора, не считая само событие.
+func (c *Calendar) checkConflicts(event Event) error {
+	if !event.busy() {
+		return nil
+	}
+
+	for _, occurrence := range expand(event, event.Date, event.Date.Add(conflictHorizon)) {
+		for _, other := range c.visibleBetween(event.UserID, occurrence.Date, occurrence.End) {
+			if other.ID == event.ID || (other.SeriesID != 0 && other.SeriesID == event.ID) || !other.busy() {
+				continue
+			}
+			return fmt.Errorf("%w: %q (id %d) at %s", ErrEventConflict, other.Title, other.ID, other.Date.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+type freeBusyResult struct {
+	UserIDs []int      `json:"user_ids"`
+	From    time.Time  `json:"from"`
+	To      time.Time  `json:"to"`
+	Busy    []Interval `json:"busy"`
+}
+
+// freeBusy отдаёт занятость пользователей user_ids=1,2 в диапазоне from/to
+// (форматы и ограничения те же, что у /events). Подробности событий не
+// раскрываются, поэтому запрашивать можно и чужую занятость.
+func (h *Handler) freeBusy(w http.ResponseWriter, r *http.Request) {
+	userIDs, q, err := parseFreeBusyQuery(r.URL.Query())
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+
+	busy := h.calendar.FreeBusy(userIDs, q.from, q.to)
+	if busy == nil {
+		busy = []Interval{}
+	}
+	writeJSON(w, response{Result: freeBusyResult{UserIDs: userIDs, From: q.from, To: q.to, Busy: busy}}, http.StatusOK)
+}
+
+// freeSlots предлагает count (по умолчанию 3) свободных слотов длительностью
+// duration с шагом step (по умолчанию 15m).
+func (h *Handler) freeSlots(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userIDs, q, err := parseFreeBusyQuery(query)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+
+	duration, err := time.ParseDuration(query.Get("duration"))
+	if err != nil || duration <= 0 {
+		writeCalendarError(w, r, invalidField("duration", errors.New("must be a positive duration like 30m")))
+		return
+	}
+	step := 15 * time.Minute
+	if stepStr := query.Get("step"); stepStr != "" {
+		if step, err = time.ParseDuration(stepStr); err != nil || step <= 0 {
+			writeCalendarError(w, r, invalidField("step", errors.New("must be a positive duration like 15m")))
+			return
+		}
+	}
+	count := 3
+	if countStr := query.Get("count"); countStr != "" {
+		if count, err = strconv.Atoi(countStr); err != nil || count < 1 || count > maxPageLimit {
+			writeCalendarError(w, r, invalidField("count", fmt.Errorf("must be between 1 and %d", maxPageLimit)))
+			return
+		}
+	}
+
+	slots := h.calendar.FreeSlots(userIDs, q.from, q.to, duration, step, count)
+	if slots == nil {
+		slots = []Interval{}
+	}
+	writeJSON(w, response{Result: slots}, http.StatusOK)
+}
+
+func parseFreeBusyQuery(query url.Values) ([]int, rangeQuery, error) {
+	var userIDs []int
+	for _, userIDStr := range strings.Split(query.Get("user_ids"), ",") {
+		if userIDStr = strings.TrimSpace(userIDStr); userIDStr == "" {
+			continue
+		}
+		userID, err := strconv.Atoi(userIDStr)
+		if err != nil {
+			return nil, rangeQuery{}, invalidField("user_ids", errors.New("must be a comma-separated list of integers"))
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if len(userIDs) == 0 {
+		return nil, rangeQuery{}, invalidField("user_ids", errors.New("at least one user is required"))
+	}
+
+	q, err := parseRangeQuery(query, 0)
+	if err != nil {
+		return nil, rangeQuery{}, err
+	}
+	return userIDs, q, nil
+}
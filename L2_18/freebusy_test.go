@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCalendar_FreeBusy(t *testing.T) {
+	calendar := NewCalendar()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hour, min int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute)
+	}
+
+	calendar.CreateEvent(1, at(9, 0), "A", WithDuration(time.Hour))
+	calendar.CreateEvent(2, at(9, 30), "B", WithDuration(time.Hour))
+	calendar.CreateEvent(2, at(10, 30), "Adjacent", WithDuration(30*time.Minute))
+	calendar.CreateEvent(1, at(14, 0), "C", WithDuration(time.Hour))
+	calendar.CreateEvent(1, day, "Holiday", WithAllDay())
+	declined, _ := calendar.CreateEvent(3, at(16, 0), "Declined", WithDuration(time.Hour), WithAttendees(1))
+	calendar.RespondToInvitation(declined.ID, 1, StatusDeclined)
+
+	busy := calendar.FreeBusy([]int{1, 2}, at(8, 0), at(18, 0))
+	want := []Interval{{at(9, 0), at(11, 0)}, {at(14, 0), at(15, 0)}}
+	if len(busy) != len(want) {
+		t.Fatalf("Expected %d intervals, got %+v", len(want), busy)
+	}
+	for i := range want {
+		if !busy[i].Start.Equal(want[i].Start) || !busy[i].End.Equal(want[i].End) {
+			t.Errorf("Interval %d: expected %v-%v, got %v-%v", i, want[i].Start, want[i].End, busy[i].Start, busy[i].End)
+		}
+	}
+
+	slots := calendar.FreeSlots([]int{1, 2}, at(8, 30), at(18, 0), time.Hour, 30*time.Minute, 3)
+	if len(slots) != 3 {
+		t.Fatalf("Expected 3 slots, got %+v", slots)
+	}
+	for i, start := range []time.Time{at(11, 0), at(11, 30), at(12, 0)} {
+		if !slots[i].Start.Equal(start) {
+			t.Errorf("Slot %d: expected %v, got %v", i, start, slots[i].Start)
+		}
+	}
+}
+
+func TestCalendar_StrictCreate(t *testing.T) {
+	calendar := NewCalendar()
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(1, start, "Standup", WithDuration(15*time.Minute), WithRecurrence(&Recurrence{Freq: FreqDaily}))
+
+	_, err := calendar.CreateEvent(1, start.AddDate(0, 0, 3).Add(10*time.Minute), "Clash", WithDuration(time.Hour), RejectConflicts())
+	if !errors.Is(err, ErrEventConflict) {
+		t.Errorf("Expected ErrEventConflict, got %v", err)
+	}
+	if _, err := calendar.CreateEvent(1, start.Add(15*time.Minute), "Right after", WithDuration(time.Hour), RejectConflicts()); err != nil {
+		t.Errorf("Expected adjacent event to be accepted, got %v", err)
+	}
+	if _, err := calendar.CreateEvent(1, start.AddDate(0, 0, 3), "Overbooked", WithDuration(time.Hour)); err != nil {
+		t.Errorf("Expected overlap to be allowed without strict, got %v", err)
+	}
+}
+
+func TestHandler_FreeSlots(t *testing.T) {
+	calendar := NewCalendar()
+	calendar.CreateEvent(1, time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), "Busy", WithDuration(time.Hour))
+
+	r := mux.NewRouter()
+	NewHandler(calendar).RegisterRoutes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/free_slots?user_ids=1,2&from=2024-01-01T09:00:00Z&to=2024-01-01T12:00:00Z&duration=30m&count=2", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var resp struct {
+		Result []Interval `json:"result"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Result) != 2 || resp.Result[0].Start.Hour() != 10 {
+		t.Errorf("Expected 2 slots from 10:00, got %+v", resp.Result)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/freebusy?user_ids=x&from=2024-01-01", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for bad user_ids, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/yokitheyo/level_2/L2_18/calendarpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcService отдаёт по gRPC тот же Calendar, что и HTTP API; запросы
+// разбираются теми же функциями, что и JSON, поэтому правила проверки общие.
+type grpcService struct {
+	calendarpb.UnimplementedCalendarServiceServer
+
+	calendar *Calendar
+	shutdown <-chan struct{}
+}
+
+// NewGRPCServer создаёт gRPC-сервер с CalendarService. При заданном auth
+// каждый вызов требует metadata authorization: Bearer <token>. Закрытие
+// shutdown завершает открытые потоки Watch, чтобы GracefulStop не ждал их.
+func NewGRPCServer(calendar *Calendar, auth Authenticator, shutdown <-chan struct{}) *grpc.Server {
+	// паника в одном вызове не должна ронять процесс вместе с HTTP-сервером
+	unary := []grpc.UnaryServerInterceptor{grpcRecoverUnary}
+	stream := []grpc.StreamServerInterceptor{grpcRecoverStream}
+	if auth != nil {
+		unary = append(unary, func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := grpcAuthenticate(ctx, auth)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		})
+		stream = append(stream, func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := grpcAuthenticate(ss.Context(), auth)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &principalStream{ServerStream: ss, ctx: ctx})
+		})
+	}
+
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	calendarpb.RegisterCalendarServiceServer(server, &grpcService{calendar: calendar, shutdown: shutdown})
+	return server
+}
+
+func grpcRecoverUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = grpcPanic(ctx, info.FullMethod, p)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func grpcRecoverStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = grpcPanic(ss.Context(), info.FullMethod, p)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+func grpcPanic(ctx context.Context, method string, p interface{}) error {
+	slog.ErrorContext(ctx, "grpc handler panic", "method", method, "panic", p, "stack", string(debug.Stack()))
+	return status.Error(codes.Internal, "internal error")
+}
+
+func grpcAuthenticate(ctx context.Context, auth Authenticator) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, ErrUnauthenticated.Error())
+	}
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return nil, status.Error(codes.Unauthenticated, ErrUnauthenticated.Error())
+	}
+	principal, err := auth.Authenticate(strings.TrimSpace(token))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, ErrUnauthenticated.Error())
+	}
+	return WithPrincipal(ctx, principal), nil
+}
+
+// principalStream подменяет контекст потока на контекст с Principal.
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcUser проверяет, что запрос сделан от имени аутентифицированного
+// пользователя; без аутентификации принимается любой user_id.
+func grpcUser(ctx context.Context, userID int64) (int, error) {
+	if principal, ok := PrincipalFromContext(ctx); ok && int64(principal.UserID) != userID {
+		return 0, grpcError(ctx, ErrForbidden)
+	}
+	return int(userID), nil
+}
+
+func (s *grpcService) CreateEvent(ctx context.Context, req *calendarpb.CreateEventRequest) (*calendarpb.Event, error) {
+	userID, err := grpcUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GetEvent() == nil {
+		return nil, grpcError(ctx, invalidField("event", errors.New("is required")))
+	}
+	input := requestFromProto(req.GetEvent())
+	date, opts, err := parseEventRequest(input)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	event, err := s.calendar.CreateEvent(userID, date, input.Title, opts...)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return eventToProto(event), nil
+}
+
+func (s *grpcService) UpdateEvent(ctx context.Context, req *calendarpb.UpdateEventRequest) (*calendarpb.Event, error) {
+	userID, err := grpcUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GetEvent() == nil {
+		return nil, grpcError(ctx, invalidField("event", errors.New("is required")))
+	}
+	input := requestFromProto(req.GetEvent())
+	date, opts, err := parseEventRequest(input)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	id, version := int(req.GetId()), int(req.GetExpectedVersion())
+	var event Event
+	if req.GetOccurrence() != "" {
+		var occurrence time.Time
+		if occurrence, err = parseOccurrence(&eventRequest{Occurrence: req.GetOccurrence(), Timezone: input.Timezone}); err != nil {
+			return nil, grpcError(ctx, err)
+		}
+		event, err = s.calendar.UpdateOccurrenceIfVersion(id, userID, version, occurrence, date, input.Title, opts...)
+	} else {
+		if version != 0 {
+			opts = append(opts, IfVersion(version))
+		}
+		event, err = s.calendar.UpdateEvent(id, userID, date, input.Title, opts...)
+	}
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return eventToProto(event), nil
+}
+
+func (s *grpcService) DeleteEvent(ctx context.Context, req *calendarpb.DeleteEventRequest) (*calendarpb.DeleteEventResponse, error) {
+	userID, err := grpcUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	id, version := int(req.GetId()), int(req.GetExpectedVersion())
+	if req.GetOccurrence() != "" {
+		var occurrence time.Time
+		if occurrence, err = parseOccurrence(&eventRequest{Occurrence: req.GetOccurrence(), Timezone: req.GetTimezone()}); err != nil {
+			return nil, grpcError(ctx, err)
+		}
+		err = s.calendar.DeleteOccurrenceIfVersion(id, userID, version, occurrence)
+	} else {
+		err = s.calendar.DeleteEventIfVersion(id, userID, version)
+	}
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return &calendarpb.DeleteEventResponse{}, nil
+}
+
+func (s *grpcService) ListEvents(ctx context.Context, req *calendarpb.ListEventsRequest) (*calendarpb.ListEventsResponse, error) {
+	userID, err := grpcUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int(req.GetPageSize())
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+	if limit < 1 || limit > maxPageLimit {
+		return nil, grpcError(ctx, invalidField("page_size", fmt.Errorf("must be between 1 and %d", maxPageLimit)))
+	}
+	var cursor *eventCursor
+	if token := req.GetPageToken(); token != "" {
+		if cursor, err = parseCursor(token); err != nil {
+			return nil, grpcError(ctx, invalidField("page_token", err))
+		}
+	}
+
+	calendarIDs := make([]int, len(req.GetCalendarIds()))
+	for i, id := range req.GetCalendarIds() {
+		calendarIDs[i] = int(id)
+	}
+	var events []Event
+	if req.GetFrom() == nil {
+		if req.GetTo() != nil {
+			return nil, grpcError(ctx, invalidField("to", errors.New("requires from")))
+		}
+		events = s.calendar.GetEvents(userID, calendarIDs...)
+	} else {
+		from, to, err := rangeFromProto(req.GetFrom(), req.GetTo())
+		if err != nil {
+			return nil, grpcError(ctx, err)
+		}
+		events = s.calendar.GetEventsInRange(userID, from, to, calendarIDs...)
+	}
+
+	page := paginate(events, cursor, limit)
+	resp := &calendarpb.ListEventsResponse{
+		Events:        make([]*calendarpb.Event, len(page.Events)),
+		NextPageToken: page.NextCursor,
+	}
+	for i, event := range page.Events {
+		resp.Events[i] = eventToProto(event)
+	}
+	return resp, nil
+}
+
+func (s *grpcService) Watch(req *calendarpb.WatchRequest, stream grpc.ServerStreamingServer[calendarpb.Change]) error {
+	userID, err := grpcUser(stream.Context(), req.GetUserId())
+	if err != nil {
+		return err
+	}
+
+	sub, missed, err := s.calendar.Subscribe(userID, req.GetEpoch(), req.GetAfterSeq())
+	if err != nil {
+		return grpcError(stream.Context(), err)
+	}
+	defer sub.Close()
+
+	last := req.GetAfterSeq()
+	for _, change := range missed {
+		if err := stream.Send(changeToProto(change)); err != nil {
+			return err
+		}
+		last = change.Seq
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-s.shutdown:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case change, ok := <-sub.C:
+			if !ok {
+				// подписчик отстал: клиент продолжит с последнего полученного seq
+				return status.Errorf(codes.Unavailable, "watcher fell behind, resume with after_seq=%d", last)
+			}
+			if err := stream.Send(changeToProto(change)); err != nil {
+				return err
+			}
+			last = change.Seq
+		}
+	}
+}
+
+func rangeFromProto(fromTS, toTS *timestamppb.Timestamp) (time.Time, time.Time, error) {
+	if err := fromTS.CheckValid(); err != nil {
+		return time.Time{}, time.Time{}, invalidField("from", err)
+	}
+	from := fromTS.AsTime()
+	to := from.Add(maxRangeQuery)
+	if toTS != nil {
+		if err := toTS.CheckValid(); err != nil {
+			return time.Time{}, time.Time{}, invalidField("to", err)
+		}
+		to = toTS.AsTime()
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, invalidField("to", errors.New("must be after from"))
+	}
+	if to.Sub(from) > maxRangeQuery {
+		return time.Time{}, time.Time{}, invalidField("to", fmt.Errorf("range must not exceed %d days", int(maxRangeQuery.Hours()/24)))
+	}
+	return from, to, nil
+}
+
+// requestFromProto переводит EventInput в eventRequest, чтобы разобрать его
+// как JSON-запрос.
+func requestFromProto(input *calendarpb.EventInput) *eventRequest {
+	req := &eventRequest{
+		Title:       input.GetTitle(),
+		Date:        input.GetStart(),
+		End:         input.GetEnd(),
+		Duration:    input.GetDuration(),
+		Timezone:    input.GetTimezone(),
+		RRule:       input.GetRrule(),
+		UID:         input.GetUid(),
+		Description: input.GetDescription(),
+		Location:    input.GetLocation(),
+		Tags:        input.GetTags(),
+		Strict:      input.GetStrict(),
+	}
+	if input != nil && input.CalendarId != nil {
+		id := int(input.GetCalendarId())
+		req.CalendarID = &id
+	}
+	for _, minutes := range input.GetReminderMinutes() {
+		req.Reminders = append(req.Reminders, Reminder{MinutesBefore: int(minutes)})
+	}
+	if attendees := input.GetAttendees(); attendees != nil {
+		req.Attendees = make([]int, len(attendees.GetUserIds()))
+		for i, id := range attendees.GetUserIds() {
+			req.Attendees[i] = int(id)
+		}
+	}
+	return req
+}
+
+func eventToProto(event Event) *calendarpb.Event {
+	pb := &calendarpb.Event{
+		Id:          int64(event.ID),
+		UserId:      int64(event.UserID),
+		Title:       event.Title,
+		Start:       timestamppb.New(event.Date),
+		Timezone:    event.Timezone,
+		AllDay:      event.AllDay,
+		Description: event.Description,
+		Location:    event.Location,
+		Tags:        event.Tags,
+		Uid:         event.UID,
+		Version:     int64(event.Version),
+		SeriesId:    int64(event.SeriesID),
+		CalendarId:  int64(event.CalendarID),
+	}
+	if !event.End.IsZero() {
+		pb.End = timestamppb.New(event.End)
+	}
+	for _, reminder := range event.Reminders {
+		pb.ReminderMinutes = append(pb.ReminderMinutes, int32(reminder.MinutesBefore))
+	}
+	for _, attendee := range event.Attendees {
+		pb.Attendees = append(pb.Attendees, &calendarpb.Attendee{UserId: int64(attendee.UserID), Status: string(attendee.Status)})
+	}
+	if event.Recurrence != nil {
+		pb.Rrule = event.Recurrence.String()
+		for _, exDate := range event.Recurrence.ExDates {
+			pb.ExDates = append(pb.ExDates, timestamppb.New(exDate))
+		}
+	}
+	if event.RecurrenceID != nil {
+		pb.RecurrenceId = timestamppb.New(*event.RecurrenceID)
+	}
+	return pb
+}
+
+var changeTypes = map[ChangeType]calendarpb.Change_Type{
+	ChangeCreated: calendarpb.Change_CREATED,
+	ChangeUpdated: calendarpb.Change_UPDATED,
+	ChangeDeleted: calendarpb.Change_DELETED,
+}
+
+func changeToProto(change Change) *calendarpb.Change {
+	return &calendarpb.Change{
+		Seq:     change.Seq,
+		Epoch:   change.Epoch,
+		Type:    changeTypes[change.Type],
+		UserId:  int64(change.UserID),
+		EventId: int64(change.EventID),
+		Event:   eventToProto(change.Event),
+		At:      timestamppb.New(change.At),
+	}
+}
+
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusConflict:              codes.AlreadyExists,
+	http.StatusPreconditionFailed:    codes.FailedPrecondition,
+	http.StatusGone:                  codes.OutOfRange,
+	http.StatusRequestEntityTooLarge: codes.ResourceExhausted,
+	http.StatusServiceUnavailable:    codes.Unavailable,
+}
+
+// grpcError переводит ошибку календаря в статус gRPC с тем же текстом, что и в
+// HTTP API; код ошибки уходит в ErrorInfo.Reason, ошибки полей - в BadRequest.
+func grpcError(ctx context.Context, err error) error {
+	httpStatus, resp := errorResponse(ctx, err)
+	code, ok := grpcCodes[httpStatus]
+	if !ok {
+		code = codes.Internal
+	}
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: string(resp.Code), Domain: "calendar"}}
+	if len(resp.Details) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, field := range resp.Details {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field.Field,
+				Description: field.Message,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	st := status.New(code, resp.Error)
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yokitheyo/level_2/L2_18/calendarpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newGRPCTestClient поднимает CalendarService на bufconn и возвращает клиент;
+// закрытие shutdown имитирует остановку сервера.
+func newGRPCTestClient(t *testing.T, calendar *Calendar, auth Authenticator, shutdown <-chan struct{}) calendarpb.CalendarServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := NewGRPCServer(calendar, auth, shutdown)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Could not dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return calendarpb.NewCalendarServiceClient(conn)
+}
+
+func TestGRPC_EventLifecycle(t *testing.T) {
+	client := newGRPCTestClient(t, NewCalendar(), nil, nil)
+	ctx := context.Background()
+
+	created, err := client.CreateEvent(ctx, &calendarpb.CreateEventRequest{
+		UserId: 1,
+		Event: &calendarpb.EventInput{
+			Title:           "Standup",
+			Start:           "2024-01-01T09:00",
+			Duration:        "15m",
+			Timezone:        "Europe/Moscow",
+			Rrule:           "FREQ=DAILY;COUNT=3",
+			ReminderMinutes: []int32{10},
+			Tags:            []string{"#Work"},
+			Attendees:       &calendarpb.AttendeeList{UserIds: []int64{2}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	if created.GetId() == 0 || created.GetVersion() != 1 || created.GetRrule() != "FREQ=DAILY;COUNT=3" {
+		t.Errorf("Unexpected event %v", created)
+	}
+	if want := time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC); !created.GetStart().AsTime().Equal(want) {
+		t.Errorf("Expected start %v, got %v", want, created.GetStart().AsTime())
+	}
+	if len(created.GetTags()) != 1 || created.GetTags()[0] != "work" || len(created.GetAttendees()) != 1 {
+		t.Errorf("Unexpected tags or attendees %v %v", created.GetTags(), created.GetAttendees())
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	list, err := client.ListEvents(ctx, &calendarpb.ListEventsRequest{
+		UserId:   1,
+		From:     timestamppb.New(from),
+		To:       timestamppb.New(from.AddDate(0, 0, 7)),
+		PageSize: 2,
+	})
+	if err != nil {
+		t.Fatalf("ListEvents failed: %v", err)
+	}
+	if len(list.GetEvents()) != 2 || list.GetNextPageToken() == "" {
+		t.Fatalf("Expected first page of 2 occurrences, got %d (%q)", len(list.GetEvents()), list.GetNextPageToken())
+	}
+	rest, err := client.ListEvents(ctx, &calendarpb.ListEventsRequest{
+		UserId:    1,
+		From:      timestamppb.New(from),
+		To:        timestamppb.New(from.AddDate(0, 0, 7)),
+		PageSize:  2,
+		PageToken: list.GetNextPageToken(),
+	})
+	if err != nil || len(rest.GetEvents()) != 1 || rest.GetNextPageToken() != "" {
+		t.Fatalf("Expected last occurrence, got %v (%v)", rest.GetEvents(), err)
+	}
+	if rest.GetEvents()[0].GetRecurrenceId() == nil {
+		t.Error("Expected occurrence to carry recurrence_id")
+	}
+
+	// attendees не передан - приглашённые сохраняются
+	updated, err := client.UpdateEvent(ctx, &calendarpb.UpdateEventRequest{
+		UserId:          1,
+		Id:              created.GetId(),
+		ExpectedVersion: created.GetVersion(),
+		Event:           &calendarpb.EventInput{Title: "Daily", Start: "2024-01-01T10:00:00Z"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	if updated.GetTitle() != "Daily" || updated.GetVersion() != 2 || len(updated.GetAttendees()) != 1 {
+		t.Errorf("Unexpected updated event %v", updated)
+	}
+
+	_, err = client.DeleteEvent(ctx, &calendarpb.DeleteEventRequest{UserId: 1, Id: created.GetId(), ExpectedVersion: 1})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for stale version, got %v", err)
+	}
+	if _, err := client.DeleteEvent(ctx, &calendarpb.DeleteEventRequest{UserId: 1, Id: created.GetId(), ExpectedVersion: 2}); err != nil {
+		t.Fatalf("DeleteEvent failed: %v", err)
+	}
+
+	all, err := client.ListEvents(ctx, &calendarpb.ListEventsRequest{UserId: 1})
+	if err != nil || len(all.GetEvents()) != 0 {
+		t.Errorf("Expected no events after delete, got %v (%v)", all.GetEvents(), err)
+	}
+}
+
+func TestGRPC_OccurrenceExpectedVersion(t *testing.T) {
+	calendar := NewCalendar()
+	client := newGRPCTestClient(t, calendar, nil, nil)
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	series, _ := calendar.CreateEvent(1, start, "Standup", WithRecurrence(&Recurrence{Freq: FreqDaily, Count: 3}))
+
+	// оба клиента ждут версию 1: второе изменение вхождения должно отклониться
+	update := &calendarpb.UpdateEventRequest{UserId: 1, Id: int64(series.ID), Occurrence: "2024-01-02T09:00:00Z", ExpectedVersion: 1,
+		Event: &calendarpb.EventInput{Title: "Moved", Start: "2024-01-02T11:00:00Z"}}
+	if _, err := client.UpdateEvent(ctx, update); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	remove := &calendarpb.DeleteEventRequest{UserId: 1, Id: int64(series.ID), Occurrence: "2024-01-03T09:00:00Z", ExpectedVersion: 1}
+	if _, err := client.DeleteEvent(ctx, remove); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for stale series version, got %v", err)
+	}
+	remove.ExpectedVersion = 2
+	if _, err := client.DeleteEvent(ctx, remove); err != nil {
+		t.Errorf("DeleteEvent failed: %v", err)
+	}
+}
+
+func TestGRPC_DeleteOccurrenceTimezone(t *testing.T) {
+	calendar := NewCalendar()
+	client := newGRPCTestClient(t, calendar, nil, nil)
+	ctx := context.Background()
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, tokyo)
+	series, _ := calendar.CreateEvent(1, start, "Night shift", WithRecurrence(&Recurrence{Freq: FreqDaily, Count: 3}))
+
+	// полночь по UTC не совпадает с полночью по Токио
+	remove := &calendarpb.DeleteEventRequest{UserId: 1, Id: int64(series.ID), Occurrence: "2024-01-02"}
+	if _, err := client.DeleteEvent(ctx, remove); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for UTC date, got %v", err)
+	}
+	remove.Timezone = "Asia/Tokyo"
+	if _, err := client.DeleteEvent(ctx, remove); err != nil {
+		t.Fatalf("DeleteEvent failed: %v", err)
+	}
+	if got := calendar.GetEventsInRange(1, start, start.AddDate(0, 0, 3)); len(got) != 2 {
+		t.Errorf("Expected 2 occurrences left, got %d", len(got))
+	}
+}
+
+func TestGRPC_Errors(t *testing.T) {
+	client := newGRPCTestClient(t, NewCalendar(), nil, nil)
+	ctx := context.Background()
+
+	_, err := client.CreateEvent(ctx, &calendarpb.CreateEventRequest{
+		UserId: 1,
+		Event:  &calendarpb.EventInput{Title: "Bad", Start: "2024-01-01", Duration: "1h"},
+	})
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got %v", err)
+	}
+	var field string
+	var reason string
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.BadRequest:
+			field = d.GetFieldViolations()[0].GetField()
+		case *errdetails.ErrorInfo:
+			reason = d.GetReason()
+		}
+	}
+	if field != "duration" || reason != string(CodeValidation) {
+		t.Errorf("Expected duration violation with reason %s, got %q %q", CodeValidation, field, reason)
+	}
+
+	_, err = client.UpdateEvent(ctx, &calendarpb.UpdateEventRequest{
+		UserId: 1,
+		Id:     42,
+		Event:  &calendarpb.EventInput{Title: "Missing", Start: "2024-01-01"},
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+
+	if _, err := client.CreateEvent(ctx, &calendarpb.CreateEventRequest{UserId: 1}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for missing event, got %v", err)
+	}
+	if _, err := client.UpdateEvent(ctx, &calendarpb.UpdateEventRequest{UserId: 1, Id: 42}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for missing event on update, got %v", err)
+	}
+
+	input := &calendarpb.EventInput{Title: "Once", Start: "2024-01-01", Uid: "same"}
+	client.CreateEvent(ctx, &calendarpb.CreateEventRequest{UserId: 1, Event: input})
+	if _, err := client.CreateEvent(ctx, &calendarpb.CreateEventRequest{UserId: 1, Event: input}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Expected AlreadyExists for duplicate UID, got %v", err)
+	}
+
+	if _, err := client.ListEvents(ctx, &calendarpb.ListEventsRequest{UserId: 1, PageSize: maxPageLimit + 1}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for page_size, got %v", err)
+	}
+	from := timestamppb.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if _, err := client.ListEvents(ctx, &calendarpb.ListEventsRequest{UserId: 1, From: from, To: from}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for empty range, got %v", err)
+	}
+}
+
+func TestGRPC_RecoverPanic(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/calendar.CalendarService/CreateEvent"}
+	_, err := grpcRecoverUnary(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		var input *calendarpb.EventInput
+		return input.CalendarId, nil
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Expected Internal after panic, got %v", err)
+	}
+}
+
+func TestGRPC_Watch(t *testing.T) {
+	calendar := NewCalendar()
+	shutdown := make(chan struct{})
+	client := newGRPCTestClient(t, calendar, nil, shutdown)
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	calendar.CreateEvent(1, date, "Seen")
+	missed, _ := calendar.CreateEvent(1, date, "Missed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	epoch := calendar.changes.epoch
+	stream, err := client.Watch(ctx, &calendarpb.WatchRequest{UserId: 1, AfterSeq: 1, Epoch: epoch})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	// пропущенное после after_seq приходит первым
+	change, err := stream.Recv()
+	if err != nil || change.GetType() != calendarpb.Change_CREATED || change.GetEventId() != int64(missed.ID) || change.GetEpoch() != epoch {
+		t.Fatalf("Expected backlog change, got %v (%v)", change, err)
+	}
+
+	calendar.CreateEvent(2, date, "Other user")
+	calendar.UpdateEvent(missed.ID, 1, date, "Renamed")
+	change, err = stream.Recv()
+	if err != nil || change.GetType() != calendarpb.Change_UPDATED || change.GetEvent().GetTitle() != "Renamed" {
+		t.Fatalf("Expected update, got %v (%v)", change, err)
+	}
+
+	close(shutdown)
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable on shutdown, got %v", err)
+	}
+
+	for _, req := range []*calendarpb.WatchRequest{{UserId: 1, AfterSeq: 100, Epoch: epoch}, {UserId: 1, AfterSeq: 1, Epoch: "other-run"}} {
+		expired, err := client.Watch(ctx, req)
+		if err == nil {
+			_, err = expired.Recv()
+		}
+		if status.Code(err) != codes.OutOfRange {
+			t.Errorf("Expected OutOfRange for %v, got %v", req, err)
+		}
+	}
+}
+
+func TestGRPC_Auth(t *testing.T) {
+	secret := []byte("grpc-secret")
+	client := newGRPCTestClient(t, NewCalendar(), NewJWTAuthenticator(secret), nil)
+	input := &calendarpb.EventInput{Title: "Private", Start: "2024-01-01"}
+
+	_, err := client.CreateEvent(context.Background(), &calendarpb.CreateEventRequest{UserId: 1, Event: input})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without token, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+SignJWT(secret, 1, time.Hour))
+	if _, err := client.CreateEvent(ctx, &calendarpb.CreateEventRequest{UserId: 1, Event: input}); err != nil {
+		t.Errorf("Expected own event to be created, got %v", err)
+	}
+	if _, err := client.CreateEvent(ctx, &calendarpb.CreateEventRequest{UserId: 2, Event: input}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for other user, got %v", err)
+	}
+
+	stream, err := client.Watch(ctx, &calendarpb.WatchRequest{UserId: 2})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for other user's stream, got %v", err)
+	}
+}
@@ -2,9 +2,15 @@ package main
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -12,10 +18,13 @@ import (
 
 type Handler struct {
 	calendar *Calendar
+
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
 }
 
 func NewHandler(calendar *Calendar) *Handler {
-	return &Handler{calendar: calendar}
+	return &Handler{calendar: calendar, shutdown: make(chan struct{})}
 }
 
 func (h *Handler) RegisterRoutes(r *mux.Router) {
@@ -25,6 +34,31 @@ func (h *Handler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/events_for_day", h.eventsForDay).Methods("GET")
 	r.HandleFunc("/events_for_week", h.eventsForWeek).Methods("GET")
 	r.HandleFunc("/events_for_month", h.eventsForMonth).Methods("GET")
+	r.HandleFunc("/events", h.eventsInRange).Methods("GET")
+	r.HandleFunc("/events/batch", h.eventsBatch).Methods("POST")
+	r.HandleFunc("/events/stream", h.eventsStream).Methods("GET")
+	r.HandleFunc("/events/ws", h.eventsWebSocket).Methods("GET")
+	r.HandleFunc("/events/search", h.searchEvents).Methods("GET")
+	r.HandleFunc("/events/history", h.eventHistory).Methods("GET")
+	r.HandleFunc("/events/restore", h.restoreEvent).Methods("POST")
+	r.HandleFunc("/events/revert", h.revertEvent).Methods("POST")
+	r.HandleFunc("/events/trash", h.trash).Methods("GET")
+	r.HandleFunc("/export.ics", h.exportICS).Methods("GET")
+	r.HandleFunc("/import", h.importICS).Methods("POST")
+	r.HandleFunc("/rsvp", h.rsvp).Methods("POST")
+	r.HandleFunc("/invitations", h.invitations).Methods("GET")
+	r.HandleFunc("/freebusy", h.freeBusy).Methods("GET")
+	r.HandleFunc("/free_slots", h.freeSlots).Methods("GET")
+	r.HandleFunc("/calendars", h.listCalendars).Methods("GET")
+	r.HandleFunc("/calendars/create", h.createCalendar).Methods("POST")
+	r.HandleFunc("/calendars/rename", h.renameCalendar).Methods("POST")
+	r.HandleFunc("/calendars/delete", h.deleteCalendar).Methods("POST")
+	r.HandleFunc("/calendars/share", h.shareCalendar).Methods("POST")
+	r.HandleFunc("/healthz", h.healthz).Methods("GET")
+	r.HandleFunc("/readyz", h.readyz).Methods("GET")
+	r.HandleFunc("/openapi.json", h.openAPI).Methods("GET")
+
+	h.registerV2Routes(r.PathPrefix("/api/v2").Subrouter())
 }
 
 type eventRequest struct {
@@ -32,29 +66,65 @@ type eventRequest struct {
 	Date   string `json:"date"`
 	Title  string `json:"title"`
 	ID     int    `json:"id"`
+	// CalendarID - календарь события; если поле не передано, при изменении
+	// событие остаётся в прежнем календаре.
+	CalendarID *int `json:"calendar_id,omitempty"`
+	// Version - версия события для /events/revert
+	Version int `json:"version,omitempty"`
+
+	// Recurrence задаёт правило структурой, RRule - строкой RRULE; указывается что-то одно.
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	RRule      string      `json:"rrule,omitempty"`
+	// Occurrence (recurrence_id вхождения или YYYY-MM-DD для серий на весь день)
+	// ограничивает изменение или удаление одним вхождением серии.
+	Occurrence string `json:"occurrence,omitempty"`
+
+	// Date и End принимают YYYY-MM-DD (событие на весь день, End включительно),
+	// RFC 3339 или локальное время YYYY-MM-DDTHH:MM[:SS] в поясе Timezone.
+	// Вместо End можно передать Duration ("45m", "1h30m").
+	End      string `json:"end,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+
+	Reminders []Reminder `json:"reminders,omitempty"`
+	UID       string     `json:"uid,omitempty"`
+
+	Description string   `json:"description,omitempty"`
+	Location    string   `json:"location,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+
+	// Attendees - ID приглашённых; если поле не передано, при изменении
+	// приглашённые остаются прежними. Status - ответ на приглашение для /rsvp.
+	Attendees []int  `json:"attendees,omitempty"`
+	Status    string `json:"status,omitempty"`
+
+	// Strict отклоняет событие, пересекающееся с уже занятым временем.
+	Strict bool `json:"strict,omitempty"`
 }
 
 type response struct {
-	Result interface{} `json:"result,omitempty"`
-	Error  string      `json:"error,omitempty"`
+	Result  interface{}  `json:"result,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Code    ErrorCode    `json:"code,omitempty"`
+	Details []FieldError `json:"details,omitempty"`
 }
 
 func (h *Handler) createEvent(w http.ResponseWriter, r *http.Request) {
 	var req eventRequest
 	if err := parseRequest(r, &req); err != nil {
-		writeError(w, "invalid request body", http.StatusBadRequest)
+		writeBodyError(w, err)
 		return
 	}
 
-	date, err := time.Parse("2006-01-02", req.Date)
+	date, opts, err := parseEventRequest(&req)
 	if err != nil {
-		writeError(w, "invalid date format, expected YYYY-MM-DD", http.StatusBadRequest)
+		writeCalendarError(w, r, err)
 		return
 	}
 
-	event, err := h.calendar.CreateEvent(req.UserID, date, req.Title)
+	event, err := h.calendar.CreateEvent(req.UserID, date, req.Title, opts...)
 	if err != nil {
-		writeError(w, err.Error(), http.StatusServiceUnavailable)
+		writeCalendarError(w, r, err)
 		return
 	}
 
@@ -64,19 +134,29 @@ func (h *Handler) createEvent(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) updateEvent(w http.ResponseWriter, r *http.Request) {
 	var req eventRequest
 	if err := parseRequest(r, &req); err != nil {
-		writeError(w, "invalid request body", http.StatusBadRequest)
+		writeBodyError(w, err)
 		return
 	}
 
-	date, err := time.Parse("2006-01-02", req.Date)
+	date, opts, err := parseEventRequest(&req)
 	if err != nil {
-		writeError(w, "invalid date format, expected YYYY-MM-DD", http.StatusBadRequest)
+		writeCalendarError(w, r, err)
 		return
 	}
 
-	event, err := h.calendar.UpdateEvent(req.ID, req.UserID, date, req.Title)
+	var event Event
+	if req.Occurrence != "" {
+		var occurrence time.Time
+		if occurrence, err = parseOccurrence(&req); err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+		event, err = h.calendar.UpdateOccurrence(req.ID, req.UserID, occurrence, date, req.Title, opts...)
+	} else {
+		event, err = h.calendar.UpdateEvent(req.ID, req.UserID, date, req.Title, opts...)
+	}
 	if err != nil {
-		writeError(w, err.Error(), http.StatusServiceUnavailable)
+		writeCalendarError(w, r, err)
 		return
 	}
 
@@ -86,12 +166,26 @@ func (h *Handler) updateEvent(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) deleteEvent(w http.ResponseWriter, r *http.Request) {
 	var req eventRequest
 	if err := parseRequest(r, &req); err != nil {
-		writeError(w, "invalid request body", http.StatusBadRequest)
+		writeBodyError(w, err)
+		return
+	}
+
+	if req.Occurrence != "" {
+		occurrence, err := parseOccurrence(&req)
+		if err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+		if err := h.calendar.DeleteOccurrence(req.ID, req.UserID, occurrence); err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+		writeJSON(w, response{Result: "occurrence deleted"}, http.StatusOK)
 		return
 	}
 
 	if err := h.calendar.DeleteEvent(req.ID, req.UserID); err != nil {
-		writeError(w, err.Error(), http.StatusServiceUnavailable)
+		writeCalendarError(w, r, err)
 		return
 	}
 
@@ -101,39 +195,150 @@ func (h *Handler) deleteEvent(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) eventsForDay(w http.ResponseWriter, r *http.Request) {
 	userID, date, err := parseQueryParams(r)
 	if err != nil {
-		writeError(w, err.Error(), http.StatusBadRequest)
+		writeCalendarError(w, r, err)
+		return
+	}
+	calendarIDs, err := parseCalendarIDs(r.URL.Query())
+	if err != nil {
+		writeCalendarError(w, r, err)
 		return
 	}
 
-	events := h.calendar.GetEventsForDay(userID, date)
+	events := h.calendar.GetEventsForDay(userID, date, calendarIDs...)
 	writeJSON(w, response{Result: events}, http.StatusOK)
 }
 
 func (h *Handler) eventsForWeek(w http.ResponseWriter, r *http.Request) {
 	userID, date, err := parseQueryParams(r)
 	if err != nil {
-		writeError(w, err.Error(), http.StatusBadRequest)
+		writeCalendarError(w, r, err)
+		return
+	}
+	calendarIDs, err := parseCalendarIDs(r.URL.Query())
+	if err != nil {
+		writeCalendarError(w, r, err)
 		return
 	}
 
-	events := h.calendar.GetEventsForWeek(userID, date)
+	events := h.calendar.GetEventsForWeek(userID, date, calendarIDs...)
 	writeJSON(w, response{Result: events}, http.StatusOK)
 }
 
 func (h *Handler) eventsForMonth(w http.ResponseWriter, r *http.Request) {
 	userID, date, err := parseQueryParams(r)
 	if err != nil {
-		writeError(w, err.Error(), http.StatusBadRequest)
+		writeCalendarError(w, r, err)
+		return
+	}
+	calendarIDs, err := parseCalendarIDs(r.URL.Query())
+	if err != nil {
+		writeCalendarError(w, r, err)
 		return
 	}
 
-	events := h.calendar.GetEventsForMonth(userID, date)
+	events := h.calendar.GetEventsForMonth(userID, date, calendarIDs...)
 	writeJSON(w, response{Result: events}, http.StatusOK)
 }
 
+func (h *Handler) eventsInRange(w http.ResponseWriter, r *http.Request) {
+	q, err := parseRangeParams(r)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+
+	events := h.calendar.GetEventsInRange(q.userID, q.from, q.to, q.calendars...)
+	writeJSON(w, response{Result: paginate(events, q.cursor, q.limit)}, http.StatusOK)
+}
+
+func (h *Handler) exportICS(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		writeCalendarError(w, r, invalidField("user_id", errors.New("must be an integer")))
+		return
+	}
+	calendarIDs, err := parseCalendarIDs(r.URL.Query())
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="calendar.ics"`)
+	if err := WriteICS(w, h.calendar.GetEvents(userID, calendarIDs...)); err != nil {
+		// заголовки уже отправлены, остаётся только записать ошибку в лог
+		slog.ErrorContext(r.Context(), "export failed", "request_id", RequestIDFromContext(r.Context()), "user_id", userID, "error", err)
+	}
+}
+
+func (h *Handler) importICS(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		writeCalendarError(w, r, invalidField("user_id", errors.New("must be an integer")))
+		return
+	}
+
+	timezone := r.URL.Query().Get("tz")
+	if _, err := loadLocation(timezone); err != nil {
+		writeCalendarError(w, r, invalidField("tz", err))
+		return
+	}
+
+	events, err := ParseICS(r.Body, timezone)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeBodyError(w, err)
+			return
+		}
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := importICSEvents(h.calendar, userID, events)
+	writeJSON(w, response{Result: result}, http.StatusOK)
+}
+
+func (h *Handler) rsvp(w http.ResponseWriter, r *http.Request) {
+	var req eventRequest
+	if err := parseRequest(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	event, err := h.calendar.RespondToInvitation(req.ID, req.UserID, AttendeeStatus(req.Status))
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+
+	writeJSON(w, response{Result: event}, http.StatusOK)
+}
+
+func (h *Handler) invitations(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		writeCalendarError(w, r, invalidField("user_id", errors.New("must be an integer")))
+		return
+	}
+
+	status := AttendeeStatus(r.URL.Query().Get("status"))
+	if status != "" && !status.valid() {
+		writeCalendarError(w, r, invalidField("status", ErrAttendeeStatusInvalid))
+		return
+	}
+
+	writeJSON(w, response{Result: h.calendar.Invitations(userID, status)}, http.StatusOK)
+}
+
 func parseRequest(r *http.Request, v interface{}) error {
 	if r.Header.Get("Content-Type") == "application/json" {
-		return json.NewDecoder(r.Body).Decode(v)
+		if err := decodeJSON(r, v); err != nil {
+			return err
+		}
+		req := v.(*eventRequest)
+		req.UserID = principalUserID(r, req.UserID)
+		return nil
 	}
 
 	if err := r.ParseForm(); err != nil {
@@ -145,34 +350,308 @@ func parseRequest(r *http.Request, v interface{}) error {
 			v.(*eventRequest).ID = id
 		}
 	}
+	if versionStr := r.FormValue("version"); versionStr != "" {
+		if version, err := strconv.Atoi(versionStr); err == nil {
+			v.(*eventRequest).Version = version
+		}
+	}
 	if userIDStr := r.FormValue("user_id"); userIDStr != "" {
 		if userID, err := strconv.Atoi(userIDStr); err == nil {
 			v.(*eventRequest).UserID = userID
 		}
 	}
+	if calendarIDStr := r.FormValue("calendar_id"); calendarIDStr != "" {
+		calendarID, err := strconv.Atoi(calendarIDStr)
+		if err != nil {
+			return err
+		}
+		v.(*eventRequest).CalendarID = &calendarID
+	}
 	v.(*eventRequest).Date = r.FormValue("date")
 	v.(*eventRequest).Title = r.FormValue("title")
+	v.(*eventRequest).RRule = r.FormValue("rrule")
+	v.(*eventRequest).Occurrence = r.FormValue("occurrence")
+	v.(*eventRequest).End = r.FormValue("end")
+	v.(*eventRequest).Duration = r.FormValue("duration")
+	v.(*eventRequest).Timezone = r.FormValue("timezone")
+	v.(*eventRequest).UID = r.FormValue("uid")
+	v.(*eventRequest).Status = r.FormValue("status")
+	v.(*eventRequest).Description = r.FormValue("description")
+	v.(*eventRequest).Location = r.FormValue("location")
+	// tags=work,urgent
+	if tagsStr := r.FormValue("tags"); tagsStr != "" {
+		v.(*eventRequest).Tags = strings.Split(tagsStr, ",")
+	}
+	if strictStr := r.FormValue("strict"); strictStr != "" {
+		strict, err := strconv.ParseBool(strictStr)
+		if err != nil {
+			return err
+		}
+		v.(*eventRequest).Strict = strict
+	}
+	// в форме напоминания передаются списком минут: reminders=15,60
+	if remindersStr := r.FormValue("reminders"); remindersStr != "" {
+		for _, minutesStr := range strings.Split(remindersStr, ",") {
+			minutes, err := strconv.Atoi(strings.TrimSpace(minutesStr))
+			if err != nil {
+				return err
+			}
+			v.(*eventRequest).Reminders = append(v.(*eventRequest).Reminders, Reminder{MinutesBefore: minutes})
+		}
+	}
+	// attendees=2,3; пустое значение снимает всех приглашённых
+	if attendees, ok := r.Form["attendees"]; ok {
+		v.(*eventRequest).Attendees = []int{}
+		for _, userIDStr := range strings.Split(attendees[0], ",") {
+			if userIDStr = strings.TrimSpace(userIDStr); userIDStr == "" {
+				continue
+			}
+			userID, err := strconv.Atoi(userIDStr)
+			if err != nil {
+				return err
+			}
+			v.(*eventRequest).Attendees = append(v.(*eventRequest).Attendees, userID)
+		}
+	}
 
 	return nil
 }
 
+// decodeJSON читает JSON-тело, отвергая неизвестные поля: опечатка в имени
+// поля иначе молча теряла бы значение.
+func decodeJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decodeSingle(decoder, v)
+}
+
+// decodeSingle читает ровно одно JSON-значение. Данные после него - ошибка:
+// AuthMiddleware и обработчик должны разбирать тело одинаково.
+func decodeSingle(decoder *json.Decoder, v interface{}) error {
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// parseEventRequest разбирает дату начала и собирает опции события из запроса.
+func parseEventRequest(req *eventRequest) (time.Time, []EventOption, error) {
+	loc, err := loadLocation(req.Timezone)
+	if err != nil {
+		return time.Time{}, nil, invalidField("timezone", err)
+	}
+
+	date, allDay, err := parseEventTime(req.Date, loc)
+	if err != nil {
+		return time.Time{}, nil, invalidField("date", errors.New("invalid date format, expected YYYY-MM-DD or RFC 3339"))
+	}
+
+	opts := []EventOption{WithTimezone(loc.String())}
+	if allDay {
+		opts = append(opts, WithAllDay())
+	}
+
+	switch {
+	case req.End != "" && req.Duration != "":
+		return time.Time{}, nil, invalidField("duration", errors.New("end and duration are mutually exclusive"))
+	case req.End != "":
+		end, endAllDay, err := parseEventTime(req.End, loc)
+		if err != nil {
+			return time.Time{}, nil, invalidField("end", errors.New("invalid end format, expected YYYY-MM-DD or RFC 3339"))
+		}
+		if endAllDay != allDay {
+			return time.Time{}, nil, invalidField("end", errors.New("date and end must both be dates or both be datetimes"))
+		}
+		if allDay {
+			end = end.AddDate(0, 0, 1)
+		}
+		opts = append(opts, WithEnd(end))
+	case req.Duration != "":
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d < 0 {
+			return time.Time{}, nil, invalidField("duration", errors.New("invalid duration"))
+		}
+		if allDay {
+			return time.Time{}, nil, invalidField("duration", errors.New("duration is not allowed for all-day events, use end"))
+		}
+		opts = append(opts, WithDuration(d))
+	}
+
+	if req.UID != "" {
+		opts = append(opts, WithUID(req.UID))
+	}
+	if req.CalendarID != nil {
+		opts = append(opts, InCalendar(*req.CalendarID))
+	}
+	if req.Attendees != nil {
+		opts = append(opts, WithAttendees(req.Attendees...))
+	}
+	if req.Description != "" {
+		opts = append(opts, WithDescription(req.Description))
+	}
+	if req.Location != "" {
+		opts = append(opts, WithLocation(req.Location))
+	}
+	if len(req.Tags) > 0 {
+		tags := make([]string, len(req.Tags))
+		for i, tag := range req.Tags {
+			tags[i] = normalizeTag(tag)
+		}
+		if err := validateTags(tags); err != nil {
+			return time.Time{}, nil, invalidField("tags", err)
+		}
+		opts = append(opts, WithTags(tags...))
+	}
+	if req.Strict {
+		opts = append(opts, RejectConflicts())
+	}
+	if len(req.Reminders) > 0 {
+		if err := validateReminders(req.Reminders); err != nil {
+			return time.Time{}, nil, invalidField("reminders", err)
+		}
+		opts = append(opts, WithReminders(req.Reminders...))
+	}
+
+	switch {
+	case req.Recurrence != nil && req.RRule != "":
+		return time.Time{}, nil, invalidField("rrule", errors.New("recurrence and rrule are mutually exclusive"))
+	case req.Recurrence != nil:
+		if err := req.Recurrence.validate(); err != nil {
+			return time.Time{}, nil, invalidField("recurrence", err)
+		}
+		opts = append(opts, WithRecurrence(req.Recurrence))
+	case req.RRule != "":
+		recurrence, err := ParseRRule(req.RRule)
+		if err != nil {
+			return time.Time{}, nil, invalidField("rrule", err)
+		}
+		opts = append(opts, WithRecurrence(recurrence))
+	}
+
+	return date, opts, nil
+}
+
+func parseOccurrence(req *eventRequest) (time.Time, error) {
+	loc, err := loadLocation(req.Timezone)
+	if err != nil {
+		return time.Time{}, invalidField("timezone", err)
+	}
+	occurrence, _, err := parseEventTime(req.Occurrence, loc)
+	if err != nil {
+		return time.Time{}, invalidField("occurrence", errors.New("invalid occurrence format, expected YYYY-MM-DD or RFC 3339"))
+	}
+	return occurrence, nil
+}
+
+// parseEventTime разбирает дату или дату со временем; allDay сообщает, что
+// передана только дата.
+func parseEventTime(value string, loc *time.Location) (t time.Time, allDay bool, err error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), false, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, false, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("unrecognized time %q", value)
+}
+
+// parseQueryParams разбирает user_id и date; необязательный tz (IANA) задаёт
+// пояс, в котором считаются границы дня, недели и месяца.
 func parseQueryParams(r *http.Request) (int, time.Time, error) {
 	userIDStr := r.URL.Query().Get("user_id")
 	dateStr := r.URL.Query().Get("date")
 
 	userID, err := strconv.Atoi(userIDStr)
 	if err != nil {
-		return 0, time.Time{}, err
+		return 0, time.Time{}, invalidField("user_id", errors.New("must be an integer"))
+	}
+
+	loc, err := loadLocation(r.URL.Query().Get("tz"))
+	if err != nil {
+		return 0, time.Time{}, invalidField("tz", err)
 	}
 
-	date, err := time.Parse("2006-01-02", dateStr)
+	date, err := time.ParseInLocation("2006-01-02", dateStr, loc)
 	if err != nil {
-		return 0, time.Time{}, err
+		return 0, time.Time{}, invalidField("date", errors.New("invalid date format, expected YYYY-MM-DD"))
 	}
 
 	return userID, date, nil
 }
 
+// parseRangeParams разбирает параметры /events: user_id, from, необязательные
+// to (по умолчанию from плюс maxRangeQuery), limit, cursor и tz. from и to
+// принимают те же форматы, что и date в eventRequest.
+func parseRangeParams(r *http.Request) (rangeQuery, error) {
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		return rangeQuery{}, invalidField("user_id", errors.New("must be an integer"))
+	}
+	return parseRangeQuery(r.URL.Query(), userID)
+}
+
+func parseRangeQuery(query url.Values, userID int) (rangeQuery, error) {
+	loc, err := loadLocation(query.Get("tz"))
+	if err != nil {
+		return rangeQuery{}, invalidField("tz", err)
+	}
+
+	from, _, err := parseEventTime(query.Get("from"), loc)
+	if err != nil {
+		return rangeQuery{}, invalidField("from", err)
+	}
+
+	to := from.Add(maxRangeQuery)
+	if toStr := query.Get("to"); toStr != "" {
+		if to, _, err = parseEventTime(toStr, loc); err != nil {
+			return rangeQuery{}, invalidField("to", err)
+		}
+	}
+	if !to.After(from) {
+		return rangeQuery{}, invalidField("to", errors.New("must be after from"))
+	}
+	if to.Sub(from) > maxRangeQuery {
+		return rangeQuery{}, invalidField("to", fmt.Errorf("range must not exceed %d days", int(maxRangeQuery.Hours()/24)))
+	}
+
+	limit, cursor, err := parsePageQuery(query)
+	if err != nil {
+		return rangeQuery{}, err
+	}
+	calendars, err := parseCalendarIDs(query)
+	if err != nil {
+		return rangeQuery{}, err
+	}
+
+	return rangeQuery{userID: userID, from: from, to: to, limit: limit, cursor: cursor, calendars: calendars}, nil
+}
+
+func parsePageQuery(query url.Values) (int, *eventCursor, error) {
+	var err error
+	limit := defaultPageLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, nil, invalidField("limit", fmt.Errorf("must be between 1 and %d", maxPageLimit))
+		}
+	}
+
+	var cursor *eventCursor
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		if cursor, err = parseCursor(cursorStr); err != nil {
+			return 0, nil, invalidField("cursor", err)
+		}
+	}
+	return limit, cursor, nil
+}
+
 func writeJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -180,14 +659,28 @@ func writeJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 }
 
 func writeError(w http.ResponseWriter, errorMsg string, statusCode int) {
-	writeJSON(w, response{Error: errorMsg}, statusCode)
+	writeJSON(w, response{Error: errorMsg, Code: codeForStatus(statusCode)}, statusCode)
 }
 
+// LoggingMiddleware пишет в slog строку на каждый запрос с ID запроса,
+// шаблоном маршрута, кодом ответа и длительностью.
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		log.Printf("Started %s %s", r.Method, r.URL.Path)
-		next.ServeHTTP(w, r)
-		log.Printf("Completed %s in %v", r.URL.Path, time.Since(start))
+		rec := newStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(r.Context(), level, "request",
+			slog.String("request_id", RequestIDFromContext(r.Context())),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", routeTemplate(r)),
+			slog.Int("status", rec.status),
+			slog.Duration("duration", time.Since(start)),
+		)
 	})
 }
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// registerV2Routes регистрирует ресурсный API: события пользователя доступны
+// по /users/{uid}/events/{id}, версии отдаются в ETag, а If-Match делает
+// изменение условным.
+func (h *Handler) registerV2Routes(r *mux.Router) {
+	r.HandleFunc("/users/{uid:[0-9]+}/events", h.v2ListEvents).Methods("GET")
+	r.HandleFunc("/users/{uid:[0-9]+}/events", h.v2CreateEvent).Methods("POST")
+	r.HandleFunc("/users/{uid:[0-9]+}/events/{id:[0-9]+}", h.v2GetEvent).Methods("GET")
+	r.HandleFunc("/users/{uid:[0-9]+}/events/{id:[0-9]+}", h.v2ReplaceEvent).Methods("PUT")
+	r.HandleFunc("/users/{uid:[0-9]+}/events/{id:[0-9]+}", h.v2PatchEvent).Methods("PATCH")
+	r.HandleFunc("/users/{uid:[0-9]+}/events/{id:[0-9]+}", h.v2DeleteEvent).Methods("DELETE")
+	r.HandleFunc("/users/{uid:[0-9]+}/events/{id:[0-9]+}/rsvp", h.v2RSVP).Methods("POST")
+	r.HandleFunc("/users/{uid:[0-9]+}/events/{id:[0-9]+}/history", h.v2EventHistory).Methods("GET")
+	r.HandleFunc("/users/{uid:[0-9]+}/events/{id:[0-9]+}/restore", h.v2RestoreEvent).Methods("POST")
+	r.HandleFunc("/users/{uid:[0-9]+}/events/{id:[0-9]+}/revert", h.v2RevertEvent).Methods("POST")
+	r.HandleFunc("/users/{uid:[0-9]+}/invitations", h.v2Invitations).Methods("GET")
+	r.HandleFunc("/users/{uid:[0-9]+}/trash", h.v2Trash).Methods("GET")
+	r.HandleFunc("/users/{uid:[0-9]+}/calendars", h.v2ListCalendars).Methods("GET")
+	r.HandleFunc("/users/{uid:[0-9]+}/calendars", h.v2CreateCalendar).Methods("POST")
+	r.HandleFunc("/users/{uid:[0-9]+}/calendars/{cid:[0-9]+}", h.v2GetCalendar).Methods("GET")
+	r.HandleFunc("/users/{uid:[0-9]+}/calendars/{cid:[0-9]+}", h.v2RenameCalendar).Methods("PATCH")
+	r.HandleFunc("/users/{uid:[0-9]+}/calendars/{cid:[0-9]+}", h.v2DeleteCalendar).Methods("DELETE")
+	r.HandleFunc("/users/{uid:[0-9]+}/calendars/{cid:[0-9]+}/shares/{sid:[0-9]+}", h.v2ShareCalendar).Methods("PUT")
+	r.HandleFunc("/users/{uid:[0-9]+}/calendars/{cid:[0-9]+}/shares/{sid:[0-9]+}", h.v2UnshareCalendar).Methods("DELETE")
+}
+
+// eventPatch - частичное изменение события в духе JSON Merge Patch: заданные
+// поля заменяются, recurrence: null снимает повторение.
+type eventPatch struct {
+	Title      *string         `json:"title"`
+	Date       *string         `json:"date"`
+	End        *string         `json:"end"`
+	Duration   *string         `json:"duration"`
+	Timezone   *string         `json:"timezone"`
+	Recurrence json.RawMessage `json:"recurrence"`
+	RRule      *string         `json:"rrule"`
+	Reminders  *[]Reminder     `json:"reminders"`
+	UID        *string         `json:"uid"`
+	Attendees  *[]int          `json:"attendees"`
+	CalendarID *int            `json:"calendar_id"`
+
+	Description *string   `json:"description"`
+	Location    *string   `json:"location"`
+	Tags        *[]string `json:"tags"`
+}
+
+func (h *Handler) v2ListEvents(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["uid"])
+	query := r.URL.Query()
+
+	// без from отдаются сами события и серии, с from - вхождения в диапазоне
+	if query.Get("from") == "" {
+		limit, cursor, err := parsePageQuery(query)
+		if err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+		calendarIDs, err := parseCalendarIDs(query)
+		if err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+		writeJSON(w, paginate(h.calendar.GetEvents(userID, calendarIDs...), cursor, limit), http.StatusOK)
+		return
+	}
+
+	q, err := parseRangeQuery(query, userID)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeJSON(w, paginate(h.calendar.GetEventsInRange(userID, q.from, q.to, q.calendars...), q.cursor, q.limit), http.StatusOK)
+}
+
+func (h *Handler) v2CreateEvent(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["uid"])
+
+	var req eventRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	date, opts, err := parseEventRequest(&req)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+
+	event, err := h.calendar.CreateEvent(userID, date, req.Title, opts...)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeEventV2(w, event, http.StatusCreated)
+}
+
+func (h *Handler) v2GetEvent(w http.ResponseWriter, r *http.Request) {
+	event, err := h.v2Event(r)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, event) {
+		w.Header().Set("ETag", eventETag(event))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeEventV2(w, event, http.StatusOK)
+}
+
+func (h *Handler) v2ReplaceEvent(w http.ResponseWriter, r *http.Request) {
+	// изменяет пользователь из пути: событие может лежать в чужом календаре
+	userID, _ := strconv.Atoi(mux.Vars(r)["uid"])
+	current, err := h.v2Event(r)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	version, ok := ifMatchVersion(r, current)
+	if !ok {
+		writeCalendarError(w, r, ErrVersionConflict)
+		return
+	}
+
+	var req eventRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	date, opts, err := parseEventRequest(&req)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+
+	if occurrenceStr := r.URL.Query().Get("occurrence"); occurrenceStr != "" {
+		occurrence, err := parseOccurrence(&eventRequest{Occurrence: occurrenceStr, Timezone: req.Timezone})
+		if err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+		event, err := h.calendar.UpdateOccurrenceIfVersion(current.ID, userID, version, occurrence, date, req.Title, opts...)
+		if err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+		writeEventV2(w, event, http.StatusCreated)
+		return
+	}
+
+	event, err := h.calendar.UpdateEvent(current.ID, userID, date, req.Title, append(opts, IfVersion(version))...)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeEventV2(w, event, http.StatusOK)
+}
+
+func (h *Handler) v2PatchEvent(w http.ResponseWriter, r *http.Request) {
+	// изменяет пользователь из пути: событие может лежать в чужом календаре
+	userID, _ := strconv.Atoi(mux.Vars(r)["uid"])
+	current, err := h.v2Event(r)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	if _, ok := ifMatchVersion(r, current); !ok {
+		writeCalendarError(w, r, ErrVersionConflict)
+		return
+	}
+
+	var patch eventPatch
+	if err := decodeJSON(r, &patch); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	req, err := patch.apply(requestFromEvent(current))
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	date, opts, err := parseEventRequest(req)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+
+	// патч собран из текущей версии, поэтому применяем его только к ней
+	event, err := h.calendar.UpdateEvent(current.ID, userID, date, req.Title, append(opts, IfVersion(current.Version))...)
+	if errors.Is(err, ErrVersionConflict) && r.Header.Get("If-Match") == "" {
+		writeError(w, "event was modified concurrently, retry", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeEventV2(w, event, http.StatusOK)
+}
+
+func (h *Handler) v2DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	// изменяет пользователь из пути: событие может лежать в чужом календаре
+	userID, _ := strconv.Atoi(mux.Vars(r)["uid"])
+	current, err := h.v2Event(r)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	version, ok := ifMatchVersion(r, current)
+	if !ok {
+		writeCalendarError(w, r, ErrVersionConflict)
+		return
+	}
+
+	if occurrenceStr := r.URL.Query().Get("occurrence"); occurrenceStr != "" {
+		var occurrence time.Time
+		if occurrence, err = parseOccurrence(&eventRequest{Occurrence: occurrenceStr, Timezone: r.URL.Query().Get("tz")}); err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+		err = h.calendar.DeleteOccurrenceIfVersion(current.ID, userID, version, occurrence)
+	} else {
+		err = h.calendar.DeleteEventIfVersion(current.ID, userID, version)
+	}
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) v2RSVP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, _ := strconv.Atoi(vars["uid"])
+	id, _ := strconv.Atoi(vars["id"])
+
+	var req struct {
+		Status AttendeeStatus `json:"status"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	event, err := h.calendar.RespondToInvitation(id, userID, req.Status)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeEventV2(w, event, http.StatusOK)
+}
+
+func (h *Handler) v2Invitations(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["uid"])
+
+	status := AttendeeStatus(r.URL.Query().Get("status"))
+	if status != "" && !status.valid() {
+		writeCalendarError(w, r, invalidField("status", ErrAttendeeStatusInvalid))
+		return
+	}
+	limit, cursor, err := parsePageQuery(r.URL.Query())
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	writeJSON(w, paginate(h.calendar.Invitations(userID, status), cursor, limit), http.StatusOK)
+}
+
+func (h *Handler) v2Event(r *http.Request) (Event, error) {
+	vars := mux.Vars(r)
+	userID, _ := strconv.Atoi(vars["uid"])
+	id, _ := strconv.Atoi(vars["id"])
+	return h.calendar.GetEvent(id, userID)
+}
+
+func writeEventV2(w http.ResponseWriter, event Event, statusCode int) {
+	w.Header().Set("ETag", eventETag(event))
+	if statusCode == http.StatusCreated {
+		w.Header().Set("Location", fmt.Sprintf("/api/v2/users/%d/events/%d", event.UserID, event.ID))
+	}
+	writeJSON(w, event, statusCode)
+}
+
+func eventETag(event Event) string {
+	return fmt.Sprintf(`"%d-%d"`, event.ID, event.Version)
+}
+
+// etagMatches сверяет список ETag из If-Match/If-None-Match с событием;
+// слабые ETag (W/) сравниваются по значению.
+func etagMatches(header string, event Event) bool {
+	current := eventETag(event)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion возвращает версию, которую должно иметь событие при
+// изменении: текущую, если If-Match с ней совпал, и 0 без If-Match.
+func ifMatchVersion(r *http.Request, current Event) (int, bool) {
+	match := r.Header.Get("If-Match")
+	if match == "" {
+		return 0, true
+	}
+	if !etagMatches(match, current) {
+		return 0, false
+	}
+	return current.Version, true
+}
+
+// requestFromEvent восстанавливает eventRequest, описывающий событие целиком.
+func requestFromEvent(event Event) *eventRequest {
+	req := &eventRequest{
+		Title:      event.Title,
+		Timezone:   event.Timezone,
+		Recurrence: event.Recurrence.clone(),
+		Reminders:  append([]Reminder(nil), event.Reminders...),
+		UID:        event.UID,
+		CalendarID: &event.CalendarID,
+
+		Description: event.Description,
+		Location:    event.Location,
+		Tags:        append([]string(nil), event.Tags...),
+	}
+	if event.Attendees != nil {
+		req.Attendees = make([]int, 0, len(event.Attendees))
+		for _, a := range event.Attendees {
+			req.Attendees = append(req.Attendees, a.UserID)
+		}
+	}
+
+	if event.AllDay {
+		req.Date = event.Date.Format("2006-01-02")
+		req.End = event.End.AddDate(0, 0, -1).Format("2006-01-02")
+	} else {
+		req.Date = event.Date.Format(time.RFC3339Nano)
+		req.End = event.End.Format(time.RFC3339Nano)
+	}
+	return req
+}
+
+func (p eventPatch) apply(req *eventRequest) (*eventRequest, error) {
+	if p.Title != nil {
+		req.Title = *p.Title
+	}
+	if p.Timezone != nil {
+		req.Timezone = *p.Timezone
+	}
+	if p.UID != nil {
+		req.UID = *p.UID
+	}
+	if p.Reminders != nil {
+		req.Reminders = *p.Reminders
+	}
+	if p.Attendees != nil {
+		req.Attendees = *p.Attendees
+	}
+	if p.CalendarID != nil {
+		req.CalendarID = p.CalendarID
+	}
+	if p.Description != nil {
+		req.Description = *p.Description
+	}
+	if p.Location != nil {
+		req.Location = *p.Location
+	}
+	if p.Tags != nil {
+		req.Tags = *p.Tags
+	}
+
+	if p.Date != nil {
+		// перенос начала без нового конца сохраняет длительность события
+		if p.End == nil && p.Duration == nil {
+			if err := keepDuration(req, *p.Date); err != nil {
+				return nil, err
+			}
+		}
+		req.Date = *p.Date
+	}
+	if p.End != nil {
+		req.End = *p.End
+		req.Duration = ""
+	}
+	if p.Duration != nil {
+		req.Duration = *p.Duration
+		req.End = ""
+	}
+
+	if len(p.Recurrence) > 0 {
+		req.RRule = ""
+		req.Recurrence = nil
+		if string(p.Recurrence) != "null" {
+			if err := json.Unmarshal(p.Recurrence, &req.Recurrence); err != nil {
+				return nil, invalidField("recurrence", errors.New("must be an object or null"))
+			}
+		}
+	}
+	if p.RRule != nil {
+		req.Recurrence = nil
+		req.RRule = *p.RRule
+	}
+	return req, nil
+}
+
+func keepDuration(req *eventRequest, newDate string) error {
+	loc, err := loadLocation(req.Timezone)
+	if err != nil {
+		return invalidField("timezone", err)
+	}
+	oldStart, oldAllDay, err := parseEventTime(req.Date, loc)
+	if err != nil {
+		return err
+	}
+	oldEnd, _, err := parseEventTime(req.End, loc)
+	if err != nil {
+		return err
+	}
+	newStart, newAllDay, err := parseEventTime(newDate, loc)
+	if err != nil {
+		return invalidField("date", errors.New("invalid date format, expected YYYY-MM-DD or RFC 3339"))
+	}
+
+	switch {
+	case oldAllDay && newAllDay:
+		req.End = newStart.AddDate(0, 0, daysBetween(oldStart, oldEnd)).Format("2006-01-02")
+	case !oldAllDay && !newAllDay:
+		req.End = ""
+		req.Duration = oldEnd.Sub(oldStart).String()
+	default:
+		req.End = ""
+	}
+	return nil
+}
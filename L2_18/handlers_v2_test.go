@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func v2Router(calendar *Calendar) *mux.Router {
+	r := mux.NewRouter()
+	NewHandler(calendar).RegisterRoutes(r)
+	return r
+}
+
+func v2Do(r http.Handler, method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandlerV2_Lifecycle(t *testing.T) {
+	router := v2Router(NewCalendar())
+
+	rec := v2Do(router, "POST", "/api/v2/users/1/events", `{"date":"2024-01-01T10:00:00Z","duration":"1h","title":"Meeting"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	location := rec.Header().Get("Location")
+	etag := rec.Header().Get("ETag")
+	if location != "/api/v2/users/1/events/1" || etag != `"1-1"` {
+		t.Fatalf("Unexpected Location %q or ETag %q", location, etag)
+	}
+
+	if rec := v2Do(router, "GET", location, "", map[string]string{"If-None-Match": etag}); rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304, got %d", rec.Code)
+	}
+	if rec := v2Do(router, "GET", "/api/v2/users/2/events/1", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another user, got %d", rec.Code)
+	}
+
+	rec = v2Do(router, "PUT", location, `{"date":"2024-01-02T10:00:00Z","title":"Moved"}`, map[string]string{"If-Match": etag})
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"1-2"` {
+		t.Fatalf("Expected 200 with new ETag, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	// устаревший ETag больше не подходит
+	if rec := v2Do(router, "PUT", location, `{"date":"2024-01-03T10:00:00Z","title":"Stale"}`, map[string]string{"If-Match": etag}); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412, got %d", rec.Code)
+	}
+	if rec := v2Do(router, "DELETE", location, "", map[string]string{"If-Match": etag}); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 on delete, got %d", rec.Code)
+	}
+
+	if rec := v2Do(router, "DELETE", location, "", map[string]string{"If-Match": `"1-2"`}); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", rec.Code)
+	}
+	if rec := v2Do(router, "GET", location, "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", rec.Code)
+	}
+}
+
+func TestHandlerV2_Patch(t *testing.T) {
+	calendar := NewCalendar()
+	router := v2Router(calendar)
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(1, start, "Standup", WithDuration(30*time.Minute), WithRecurrence(&Recurrence{Freq: FreqDaily}))
+
+	rec := v2Do(router, "PATCH", "/api/v2/users/1/events/1", `{"date":"2024-01-01T11:00:00Z","recurrence":null}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var event Event
+	if err := json.NewDecoder(rec.Body).Decode(&event); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if event.Title != "Standup" || event.Recurrence != nil {
+		t.Errorf("Expected title kept and recurrence cleared, got %+v", event)
+	}
+	if got := event.End.Sub(event.Date); got != 30*time.Minute {
+		t.Errorf("Expected duration to be kept, got %v", got)
+	}
+
+	if rec := v2Do(router, "PATCH", "/api/v2/users/1/events/1", `{"title":"x"}`, map[string]string{"If-Match": `"1-1"`}); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412, got %d", rec.Code)
+	}
+}
+
+func TestHandlerV2_UIDConflict(t *testing.T) {
+	router := v2Router(NewCalendar())
+
+	body := `{"date":"2024-01-01","title":"Holiday","uid":"holiday@example.com"}`
+	if rec := v2Do(router, "POST", "/api/v2/users/1/events", body, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", rec.Code)
+	}
+	if rec := v2Do(router, "POST", "/api/v2/users/1/events", body, nil); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409, got %d", rec.Code)
+	}
+}
+
+func TestHandlerV2_OccurrenceIfMatch(t *testing.T) {
+	calendar := NewCalendar()
+	router := v2Router(calendar)
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	series, _ := calendar.CreateEvent(1, start, "Standup", WithRecurrence(&Recurrence{Freq: FreqDaily, Count: 5}))
+
+	// оба клиента прочитали серию в версии 1; второй не должен затереть первого
+	if _, err := calendar.UpdateOccurrenceIfVersion(series.ID, 1, 1, start.AddDate(0, 0, 1), start.AddDate(0, 0, 1).Add(time.Hour), "Moved"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := calendar.DeleteOccurrenceIfVersion(series.ID, 1, 1, start.AddDate(0, 0, 2)); err != ErrVersionConflict {
+		t.Errorf("Expected ErrVersionConflict for stale series version, got %v", err)
+	}
+
+	path := "/api/v2/users/1/events/1?occurrence=2024-01-03T10:00:00Z"
+	body := `{"date":"2024-01-03T12:00:00Z","title":"Late"}`
+	if rec := v2Do(router, "PUT", path, body, map[string]string{"If-Match": `"1-1"`}); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for stale ETag, got %d", rec.Code)
+	}
+	if rec := v2Do(router, "PUT", path, body, map[string]string{"If-Match": `"1-2"`}); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := v2Do(router, "DELETE", "/api/v2/users/1/events/1?occurrence=2024-01-04T10:00:00Z", "", map[string]string{"If-Match": `"1-2"`}); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 on delete with outdated ETag, got %d", rec.Code)
+	}
+	if rec := v2Do(router, "DELETE", "/api/v2/users/1/events/1?occurrence=2024-01-03T10:00:00Z", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for already detached occurrence, got %d", rec.Code)
+	}
+}
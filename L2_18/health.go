@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+)
+
+type healthStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// healthz - проверка живости: процесс запущен и обслуживает запросы.
+func (h *Handler) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, healthStatus{Status: "ok"}, http.StatusOK)
+}
+
+// readyz - проверка готовности: хранилище доступно и сервер не завершается.
+// Во время остановки балансировщик перестаёт слать сюда новые запросы.
+func (h *Handler) readyz(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-h.shutdown:
+		writeJSON(w, healthStatus{Status: "shutting down"}, http.StatusServiceUnavailable)
+		return
+	default:
+	}
+
+	if err := h.calendar.Ping(); err != nil {
+		writeJSON(w, healthStatus{Status: "unavailable", Error: err.Error()}, http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, healthStatus{Status: "ok"}, http.StatusOK)
+}
+
+// Shutdown переводит обработчик в режим остановки: /readyz отвечает 503, а
+// открытые потоки изменений закрываются, чтобы не задерживать
+// http.Server.Shutdown. Вызывается до него, пока сервер ещё отвечает на пробы.
+func (h *Handler) Shutdown() {
+	h.shutdownOnce.Do(func() {
+		close(h.shutdown)
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestHandler_Readyz(t *testing.T) {
+	storage, err := NewFileStorage(t.TempDir(), 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	calendar, _ := NewCalendarWithStorage(storage)
+
+	handler := NewHandler(calendar)
+	r := mux.NewRouter()
+	handler.RegisterRoutes(r)
+
+	status := func(path string) int {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code
+	}
+
+	if code := status("/readyz"); code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", code)
+	}
+
+	calendar.Close()
+	if code := status("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 with closed storage, got %d", code)
+	}
+	if code := status("/healthz"); code != http.StatusOK {
+		t.Errorf("Expected liveness to stay 200, got %d", code)
+	}
+
+}
+
+func TestHandler_ReadyzDuringShutdown(t *testing.T) {
+	handler := NewHandler(NewCalendar())
+	r := mux.NewRouter()
+	handler.RegisterRoutes(r)
+
+	handler.Shutdown()
+	handler.Shutdown()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while shutting down, got %d", rec.Code)
+	}
+}
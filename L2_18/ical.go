@@ -0,0 +1,699 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	icsProdID     = "-//yokitheyo//L2_18 calendar//EN"
+	icsDateLayout = "20060102"
+	icsTimeLayout = "20060102T150405"
+	icsLineLimit  = 75
+	// icsZoneYears - на сколько лет после последнего события VTIMEZONE
+	// описывает переходы; дальше клиент продолжит последнее смещение
+	icsZoneYears = 10
+)
+
+var ErrICSInvalid = errors.New("invalid iCalendar data")
+
+type icsEvent struct {
+	UID          string
+	Start        time.Time
+	End          time.Time
+	Duration     time.Duration
+	Timezone     string
+	AllDay       bool
+	Summary      string
+	Description  string
+	Location     string
+	Categories   []string
+	Recurrence   *Recurrence
+	RecurrenceID *time.Time
+	Reminders    []Reminder
+}
+
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+	line   int
+}
+
+type importResult struct {
+	Created int      `json:"created"`
+	Updated int      `json:"updated"`
+	Skipped []string `json:"skipped,omitempty"`
+	Events  []Event  `json:"events"`
+}
+
+func WriteICS(w io.Writer, events []Event) error {
+	bw := bufio.NewWriter(w)
+	now := time.Now()
+	stamp := now.UTC().Format(icsTimeLayout + "Z")
+
+	writeICSLine(bw, "BEGIN:VCALENDAR")
+	writeICSLine(bw, "VERSION:2.0")
+	writeICSLine(bw, "PRODID:"+icsProdID)
+	writeICSLine(bw, "CALSCALE:GREGORIAN")
+	writeVTimezones(bw, events, now)
+
+	// даты отделённых вхождений исключены из серии только у нас; в iCalendar
+	// их замещает VEVENT с RECURRENCE-ID, поэтому в EXDATE они не попадают
+	overridden := make(map[string]bool)
+	for _, event := range events {
+		if event.SeriesID != 0 && event.RecurrenceID != nil {
+			overridden[event.UID+"/"+event.RecurrenceID.UTC().String()] = true
+		}
+	}
+
+	for _, event := range events {
+		writeICSLine(bw, "BEGIN:VEVENT")
+		writeICSLine(bw, "UID:"+escapeICSText(event.UID))
+		writeICSLine(bw, "DTSTAMP:"+stamp)
+		writeICSLine(bw, "DTSTART"+formatICSTime(event, event.Date))
+		if !event.End.Equal(event.Date) {
+			writeICSLine(bw, "DTEND"+formatICSTime(event, event.End))
+		}
+		writeICSLine(bw, "SUMMARY:"+escapeICSText(event.Title))
+		if event.Description != "" {
+			writeICSLine(bw, "DESCRIPTION:"+escapeICSText(event.Description))
+		}
+		if event.Location != "" {
+			writeICSLine(bw, "LOCATION:"+escapeICSText(event.Location))
+		}
+		if len(event.Tags) > 0 {
+			categories := make([]string, len(event.Tags))
+			for i, tag := range event.Tags {
+				categories[i] = escapeICSText(tag)
+			}
+			writeICSLine(bw, "CATEGORIES:"+strings.Join(categories, ","))
+		}
+		if event.RecurrenceID != nil {
+			writeICSLine(bw, "RECURRENCE-ID"+formatICSTime(event, *event.RecurrenceID))
+		}
+		if event.Recurrence != nil {
+			writeICSLine(bw, "RRULE:"+event.Recurrence.String())
+			for _, ex := range event.Recurrence.ExDates {
+				if overridden[event.UID+"/"+ex.UTC().String()] {
+					continue
+				}
+				writeICSLine(bw, "EXDATE"+formatICSTime(event, ex))
+			}
+		}
+		for _, r := range event.Reminders {
+			writeICSLine(bw, "BEGIN:VALARM")
+			writeICSLine(bw, "ACTION:DISPLAY")
+			writeICSLine(bw, "DESCRIPTION:"+escapeICSText(event.Title))
+			writeICSLine(bw, fmt.Sprintf("TRIGGER:-PT%dM", r.MinutesBefore))
+			writeICSLine(bw, "END:VALARM")
+		}
+		writeICSLine(bw, "END:VEVENT")
+	}
+
+	writeICSLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// writeVTimezones описывает каждый TZID, на который ссылаются события:
+// RFC 5545 требует VTIMEZONE для всех TZID в файле. Переходы берутся из базы
+// часовых поясов Go с года до первого события по icsZoneYears лет после
+// последнего (или текущего) года.
+func writeVTimezones(w *bufio.Writer, events []Event, now time.Time) {
+	type zoneRange struct {
+		loc        *time.Location
+		first, end int
+	}
+	zones := make(map[string]*zoneRange)
+	for _, event := range events {
+		if event.AllDay || event.Timezone == "" || event.Timezone == "UTC" {
+			continue
+		}
+		year := event.Date.Year()
+		z, ok := zones[event.Timezone]
+		if !ok {
+			z = &zoneRange{loc: event.Date.Location(), first: year, end: now.Year()}
+			zones[event.Timezone] = z
+		}
+		z.first = min(z.first, year)
+		z.end = max(z.end, year)
+	}
+
+	names := make([]string, 0, len(zones))
+	for name := range zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		z := zones[name]
+		// год назад, чтобы первое описание пояса началось раньше любого события
+		from := time.Date(z.first-1, time.January, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(z.end+icsZoneYears, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		writeICSLine(w, "BEGIN:VTIMEZONE")
+		writeICSLine(w, "TZID:"+name)
+		_, offset := from.In(z.loc).Zone()
+		writeICSObservance(w, from.In(z.loc), offset)
+		for _, at := range zoneTransitions(z.loc, from, to) {
+			writeICSObservance(w, at.In(z.loc), offset)
+			_, offset = at.In(z.loc).Zone()
+		}
+		writeICSLine(w, "END:VTIMEZONE")
+	}
+}
+
+// writeICSObservance пишет STANDARD или DAYLIGHT, начинающийся в at; DTSTART
+// указывается по местному времени до перехода.
+func writeICSObservance(w *bufio.Writer, at time.Time, offsetFrom int) {
+	kind := "STANDARD"
+	if at.IsDST() {
+		kind = "DAYLIGHT"
+	}
+	name, offsetTo := at.Zone()
+
+	writeICSLine(w, "BEGIN:"+kind)
+	writeICSLine(w, "DTSTART:"+at.UTC().Add(time.Duration(offsetFrom)*time.Second).Format(icsTimeLayout))
+	writeICSLine(w, "TZOFFSETFROM:"+formatICSOffset(offsetFrom))
+	writeICSLine(w, "TZOFFSETTO:"+formatICSOffset(offsetTo))
+	writeICSLine(w, "TZNAME:"+escapeICSText(name))
+	writeICSLine(w, "END:"+kind)
+}
+
+// formatICSOffset форматирует смещение в секундах как +HHMM или +HHMMSS.
+func formatICSOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	result := fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset/60%60)
+	if offset%60 != 0 {
+		result += fmt.Sprintf("%02d", offset%60)
+	}
+	return result
+}
+
+// zoneTransitions возвращает моменты смены смещения loc в [from, to): обход
+// по неделям и двоичный поиск до секунды внутри недели со сменой.
+func zoneTransitions(loc *time.Location, from, to time.Time) []time.Time {
+	const week = 7 * 24 * 60 * 60
+	offsetAt := func(unix int64) int {
+		_, offset := time.Unix(unix, 0).In(loc).Zone()
+		return offset
+	}
+
+	var result []time.Time
+	end := to.Unix()
+	for lo := from.Unix(); lo < end; {
+		hi := min(lo+week, end)
+		if offsetAt(hi) == offsetAt(lo) {
+			lo = hi
+			continue
+		}
+		offset := offsetAt(lo)
+		for hi-lo > 1 {
+			mid := lo + (hi-lo)/2
+			if offsetAt(mid) == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		result = append(result, time.Unix(hi, 0))
+		lo = hi
+	}
+	return result
+}
+
+// writeICSLine пишет строку, складывая её по 75 октетов, как требует RFC 5545.
+func writeICSLine(w *bufio.Writer, line string) {
+	for len(line) > icsLineLimit {
+		cut := icsLineLimit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// formatICSTime форматирует t в стиле события: дата для событий на весь день,
+// UTC для событий в UTC и локальное время с TZID для остальных.
+func formatICSTime(event Event, t time.Time) string {
+	switch {
+	case event.AllDay:
+		return ";VALUE=DATE:" + t.In(event.Date.Location()).Format(icsDateLayout)
+	case event.Timezone == "" || event.Timezone == "UTC":
+		return ":" + t.UTC().Format(icsTimeLayout) + "Z"
+	default:
+		return ";TZID=" + event.Timezone + ":" + t.In(event.Date.Location()).Format(icsTimeLayout)
+	}
+}
+
+func escapeICSText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+func unescapeICSText(s string) string {
+	return strings.NewReplacer(
+		`\\`, `\`,
+		`\;`, ";",
+		`\,`, ",",
+		`\n`, "\n",
+		`\N`, "\n",
+	).Replace(s)
+}
+
+// splitICSList делит значение-список по запятым, не экранированным обратной
+// косой чертой.
+func splitICSList(value string) []string {
+	var (
+		items []string
+		start int
+	)
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			items = append(items, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, value[start:])
+}
+
+// ParseICS разбирает VEVENT из r. Плавающее время (без TZID и Z) относится к
+// поясу DTSTART события, затем к X-WR-TIMEZONE календаря и затем к timezone;
+// пустой timezone означает UTC.
+func ParseICS(r io.Reader, timezone string) ([]icsEvent, error) {
+	lines, err := unfoldICSLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events  []icsEvent
+		pending []icsComponent
+		current *icsEvent
+		props   []icsProperty
+		nested  []string
+	)
+
+	for n, line := range lines {
+		prop, err := parseICSProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrICSInvalid, n+1, err)
+		}
+		prop.line = n + 1
+
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT"):
+			current = &icsEvent{}
+			props = nil
+			nested = nested[:0]
+		case current == nil:
+			if prop.name == "X-WR-TIMEZONE" {
+				timezone = prop.value
+			}
+		case prop.name == "BEGIN":
+			nested = append(nested, strings.ToUpper(prop.value))
+		case prop.name == "END" && len(nested) > 0:
+			nested = nested[:len(nested)-1]
+		case len(nested) == 1 && nested[0] == "VALARM" && prop.name == "TRIGGER":
+			current.addTrigger(prop)
+		case len(nested) > 0:
+			// прочие вложенные компоненты пропускаем
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			events = append(events, *current)
+			pending = append(pending, icsComponent{props: props, end: n + 1})
+			current = nil
+		default:
+			props = append(props, prop)
+		}
+	}
+
+	if current != nil {
+		return nil, fmt.Errorf("%w: unterminated VEVENT", ErrICSInvalid)
+	}
+	floating, err := loadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrICSInvalid, timezone)
+	}
+
+	// свойства применяются после разбора всего файла: X-WR-TIMEZONE может
+	// стоять после событий, а DTSTART - после DTEND и EXDATE
+	for i := range events {
+		if err := events[i].build(pending[i], floating); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// icsComponent - свойства одного VEVENT и строка его END.
+type icsComponent struct {
+	props []icsProperty
+	end   int
+}
+
+// build заполняет событие свойствами VEVENT, начиная с DTSTART, чтобы
+// остальные значения времени знали пояс и время начала события.
+func (e *icsEvent) build(component icsComponent, floating *time.Location) error {
+	props := component.props
+	sort.SliceStable(props, func(i, j int) bool {
+		return props[i].name == "DTSTART" && props[j].name != "DTSTART"
+	})
+	for _, prop := range props {
+		if err := e.set(prop, floating); err != nil {
+			return fmt.Errorf("%w: line %d: %v", ErrICSInvalid, prop.line, err)
+		}
+		if prop.name == "DTSTART" && e.Timezone == "" && !strings.HasSuffix(prop.value, "Z") {
+			// плавающее начало: событие живёт в поясе, которым его прочитали
+			e.Timezone = floating.String()
+			if e.Timezone == "UTC" {
+				e.Timezone = ""
+			}
+		}
+		if e.Timezone != "" {
+			floating = e.Start.Location()
+		}
+	}
+
+	if e.UID == "" || e.Start.IsZero() {
+		return fmt.Errorf("%w: VEVENT ending at line %d lacks UID or DTSTART", ErrICSInvalid, component.end)
+	}
+	return nil
+}
+
+func (e *icsEvent) set(prop icsProperty, floating *time.Location) error {
+	switch prop.name {
+	case "UID":
+		e.UID = unescapeICSText(prop.value)
+	case "SUMMARY":
+		e.Summary = unescapeICSText(prop.value)
+	case "DESCRIPTION":
+		e.Description = unescapeICSText(prop.value)
+	case "LOCATION":
+		e.Location = unescapeICSText(prop.value)
+	case "CATEGORIES":
+		// свойство может повторяться; пробелы внутри категории в тегах недопустимы
+		for _, category := range splitICSList(prop.value) {
+			if category = strings.Join(strings.Fields(unescapeICSText(category)), "-"); category != "" {
+				e.Categories = append(e.Categories, category)
+			}
+		}
+	case "DTSTART":
+		t, err := parseICSTime(prop.value, prop.params, floating)
+		if err != nil {
+			return err
+		}
+		e.Start = t
+		e.AllDay = isICSDate(prop)
+		e.Timezone = prop.params["TZID"]
+	case "DTEND":
+		t, err := parseICSTime(prop.value, prop.params, floating)
+		if err != nil {
+			return err
+		}
+		e.End = t
+	case "DURATION":
+		d, err := parseICSDuration(prop.value)
+		if err != nil {
+			return err
+		}
+		e.Duration = d
+	case "RECURRENCE-ID":
+		t, err := parseICSTime(prop.value, prop.params, floating)
+		if err != nil {
+			return err
+		}
+		e.RecurrenceID = &t
+	case "RRULE":
+		r, err := ParseRRule(prop.value)
+		if err != nil {
+			return err
+		}
+		if e.Recurrence != nil {
+			r.ExDates = e.Recurrence.ExDates
+		}
+		e.Recurrence = r
+	case "EXDATE":
+		if e.Recurrence == nil {
+			// EXDATE может идти раньше RRULE, правило подставится позже
+			e.Recurrence = &Recurrence{}
+		}
+		for _, value := range strings.Split(prop.value, ",") {
+			t, err := parseICSTime(value, prop.params, floating)
+			if err != nil {
+				return err
+			}
+			if isICSDate(icsProperty{params: prop.params, value: value}) && !e.AllDay && !e.Start.IsZero() {
+				// дата без времени у серии со временем исключает вхождение этого дня
+				hh, mm, ss := e.Start.Clock()
+				t = time.Date(t.Year(), t.Month(), t.Day(), hh, mm, ss, e.Start.Nanosecond(), e.Start.Location())
+			}
+			e.Recurrence.ExDates = append(e.Recurrence.ExDates, t)
+		}
+	}
+	return nil
+}
+
+// addTrigger превращает относительный TRIGGER до начала события в напоминание;
+// абсолютные и привязанные к концу события триггеры не поддерживаются.
+func (e *icsEvent) addTrigger(prop icsProperty) {
+	if prop.params["VALUE"] == "DATE-TIME" || prop.params["RELATED"] == "END" {
+		return
+	}
+	d, err := parseICSDuration(prop.value)
+	if err != nil || d > 0 || -d > maxReminderLead {
+		return
+	}
+	e.Reminders = append(e.Reminders, Reminder{MinutesBefore: int(-d / time.Minute)})
+}
+
+func unfoldICSLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func parseICSProperty(line string) (icsProperty, error) {
+	quoted := false
+	colon := -1
+	for i, ch := range line {
+		if ch == '"' {
+			quoted = !quoted
+		}
+		if ch == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return icsProperty{}, fmt.Errorf("missing ':' in %q", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := icsProperty{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string),
+		value:  line[colon+1:],
+	}
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return prop, nil
+}
+
+func isICSDate(prop icsProperty) bool {
+	return prop.params["VALUE"] == "DATE" || len(prop.value) == len(icsDateLayout)
+}
+
+// parseICSTime разбирает DATE или DATE-TIME; время без TZID и Z - плавающее
+// и читается в поясе floating.
+func parseICSTime(value string, params map[string]string, floating *time.Location) (time.Time, error) {
+	loc := floating
+	if tzid := params["TZID"]; tzid != "" {
+		var err error
+		if loc, err = loadLocation(tzid); err != nil {
+			return time.Time{}, fmt.Errorf("unknown TZID %q", tzid)
+		}
+	}
+
+	if params["VALUE"] == "DATE" || len(value) == len(icsDateLayout) {
+		return time.ParseInLocation(icsDateLayout, value, loc)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(icsTimeLayout+"Z", value)
+	}
+	return time.ParseInLocation(icsTimeLayout, value, loc)
+}
+
+// parseICSDuration разбирает DURATION из RFC 5545 (P1W, P1DT2H, PT45M).
+func parseICSDuration(value string) (time.Duration, error) {
+	s := strings.TrimPrefix(value, "+")
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	if !strings.HasPrefix(s, "P") {
+		return 0, fmt.Errorf("bad DURATION %q", value)
+	}
+	s = s[1:]
+
+	units := map[byte]time.Duration{
+		'W': 7 * 24 * time.Hour,
+		'D': 24 * time.Hour,
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+	}
+
+	var total time.Duration
+	inTime := false
+	n := -1
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == 'T':
+			inTime = true
+		case ch >= '0' && ch <= '9':
+			if n < 0 {
+				n = 0
+			}
+			n = n*10 + int(ch-'0')
+		default:
+			unit, ok := units[ch]
+			if !ok || n < 0 || (ch == 'M' && !inTime) {
+				return 0, fmt.Errorf("bad DURATION %q", value)
+			}
+			total += time.Duration(n) * unit
+			n = -1
+		}
+	}
+	if n >= 0 {
+		return 0, fmt.Errorf("bad DURATION %q", value)
+	}
+
+	if negative {
+		total = -total
+	}
+	return total, nil
+}
+
+func (e icsEvent) options() []EventOption {
+	timezone := e.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	opts := []EventOption{WithTimezone(timezone)}
+
+	if e.AllDay {
+		opts = append(opts, WithAllDay())
+	}
+	switch {
+	case !e.End.IsZero():
+		opts = append(opts, WithEnd(e.End))
+	case e.Duration > 0:
+		opts = append(opts, WithDuration(e.Duration))
+	}
+	if e.Recurrence != nil {
+		opts = append(opts, WithRecurrence(e.Recurrence))
+	}
+	if len(e.Reminders) > 0 {
+		opts = append(opts, WithReminders(e.Reminders...))
+	}
+	if e.Description != "" {
+		opts = append(opts, WithDescription(e.Description))
+	}
+	if e.Location != "" {
+		opts = append(opts, WithLocation(e.Location))
+	}
+	if len(e.Categories) > 0 {
+		opts = append(opts, WithTags(e.Categories...))
+	}
+	return opts
+}
+
+// importICSEvents сопоставляет VEVENT с событиями пользователя по UID: найденные
+// обновляются, остальные создаются. Отдельно изменённые вхождения (с
+// RECURRENCE-ID) применяются после серий, к которым относятся.
+func importICSEvents(c *Calendar, userID int, events []icsEvent) importResult {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].RecurrenceID == nil && events[j].RecurrenceID != nil
+	})
+
+	result := importResult{Events: make([]Event, 0, len(events))}
+	for _, ie := range events {
+		if ie.Recurrence != nil && ie.Recurrence.Freq == "" {
+			result.Skipped = append(result.Skipped, ie.UID+": EXDATE without RRULE")
+			continue
+		}
+
+		opts := ie.options()
+
+		var (
+			event   Event
+			err     error
+			updated bool
+		)
+		existing, found := c.EventByUID(userID, ie.UID, ie.RecurrenceID)
+		switch {
+		case found:
+			event, err = c.UpdateEvent(existing.ID, userID, ie.Start, ie.Summary, append(opts, WithUID(ie.UID))...)
+			updated = true
+		case ie.RecurrenceID != nil:
+			series, ok := c.EventByUID(userID, ie.UID, nil)
+			if !ok {
+				err = fmt.Errorf("series %s not found", ie.UID)
+				break
+			}
+			event, err = c.UpdateOccurrence(series.ID, userID, *ie.RecurrenceID, ie.Start, ie.Summary, opts...)
+		default:
+			event, err = c.CreateEvent(userID, ie.Start, ie.Summary, append(opts, WithUID(ie.UID))...)
+		}
+
+		if err != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %v", ie.UID, err))
+			continue
+		}
+		if updated {
+			result.Updated++
+		} else {
+			result.Created++
+		}
+		result.Events = append(result.Events, event)
+	}
+	return result
+}
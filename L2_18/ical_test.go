@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//EN\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup@example.com\r\n" +
+	"DTSTART;TZID=Europe/Moscow:20231204T100000\r\n" +
+	"SUMMARY:Standup\\, daily\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE\r\n" +
+	"EXDATE:20231206T070000Z\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup@example.com\r\n" +
+	"RECURRENCE-ID:20231211T070000Z\r\n" +
+	"DTSTART:20231211T090000Z\r\n" +
+	"SUMMARY:Late standup\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:party@example.com\r\n" +
+	"DTSTART;VALUE=DATE:20231231\r\n" +
+	"SUMMARY:New Year Party with a very long summary that has to be folded acr\r\n" +
+	" oss several lines\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	events, err := ParseICS(strings.NewReader(sampleICS), "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+
+	standup := events[0]
+	if standup.Summary != "Standup, daily" {
+		t.Errorf("Expected unescaped summary, got %q", standup.Summary)
+	}
+	if !standup.Start.Equal(time.Date(2023, 12, 4, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected TZID start converted to UTC, got %v", standup.Start)
+	}
+	if standup.Recurrence == nil || len(standup.Recurrence.ExDates) != 1 {
+		t.Errorf("Expected recurrence with one EXDATE, got %+v", standup.Recurrence)
+	}
+	if !strings.HasSuffix(events[2].Summary, "across several lines") {
+		t.Errorf("Expected unfolded summary, got %q", events[2].Summary)
+	}
+
+	if _, err := ParseICS(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:x\r\nEND:VEVENT\r\n"), ""); err == nil {
+		t.Error("Expected error for VEVENT without UID")
+	}
+}
+
+func TestImportICS_ReimportUpdates(t *testing.T) {
+	calendar := NewCalendar()
+
+	events, _ := ParseICS(strings.NewReader(sampleICS), "")
+	result := importICSEvents(calendar, 1, events)
+	if result.Created != 3 || result.Updated != 0 || len(result.Skipped) != 0 {
+		t.Fatalf("Unexpected first import result %+v", result)
+	}
+
+	december := calendar.GetEventsForMonth(1, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC))
+	// 8 вхождений серии минус EXDATE, плюс вечеринка
+	if len(december) != 8 {
+		t.Errorf("Expected 8 events, got %d", len(december))
+	}
+
+	events, _ = ParseICS(strings.NewReader(strings.Replace(sampleICS, "Late standup", "Later standup", 1)), "")
+	result = importICSEvents(calendar, 1, events)
+	if result.Created != 0 || result.Updated != 3 {
+		t.Fatalf("Unexpected re-import result %+v", result)
+	}
+	if got := len(calendar.GetEvents(1)); got != 3 {
+		t.Errorf("Expected 3 stored events after re-import, got %d", got)
+	}
+	recurrenceID := time.Date(2023, 12, 11, 7, 0, 0, 0, time.UTC)
+	override, ok := calendar.EventByUID(1, "standup@example.com", &recurrenceID)
+	if !ok || override.Title != "Later standup" {
+		t.Errorf("Expected updated override, got %+v", override)
+	}
+}
+
+func TestExportICS_RoundTrip(t *testing.T) {
+	calendar := NewCalendar()
+
+	date := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(1, date, "Party; bring snacks", WithAllDay())
+	series, _ := calendar.CreateEvent(1, date, "Daily", WithRecurrence(&Recurrence{Freq: FreqDaily, Count: 3}))
+	calendar.UpdateOccurrence(series.ID, 1, date.AddDate(0, 0, 1), date.AddDate(0, 0, 5), "Moved")
+
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, calendar.GetEvents(1)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "DTSTART;VALUE=DATE:20231231") {
+		t.Errorf("Expected date-only DTSTART, got:\n%s", buf.String())
+	}
+
+	events, err := ParseICS(&buf, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	other := NewCalendar()
+	result := importICSEvents(other, 2, events)
+	if result.Created != 3 {
+		t.Fatalf("Unexpected import result %+v", result)
+	}
+
+	january := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	got := other.GetEventsForMonth(2, january)
+	if len(got) != 2 || got[0].Date.Day() != 2 || got[1].Title != "Moved" {
+		t.Errorf("Unexpected January events %+v", got)
+	}
+
+	// повторный импорт экспортированного файла в исходный календарь ничего не дублирует
+	result = importICSEvents(calendar, 1, events)
+	if result.Created != 0 || result.Updated != 3 {
+		t.Errorf("Unexpected re-import result %+v", result)
+	}
+}
+
+func TestExportICS_VTimezone(t *testing.T) {
+	calendar := NewCalendar()
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	date := time.Date(2024, 3, 25, 9, 0, 0, 0, berlin)
+	calendar.CreateEvent(1, date, "Weekly", WithTimezone("Europe/Berlin"), WithRecurrence(&Recurrence{Freq: FreqWeekly, Count: 4}))
+	calendar.CreateEvent(1, date, "UTC call", WithTimezone("UTC"))
+
+	var buf bytes.Buffer
+	WriteICS(&buf, calendar.GetEvents(1))
+	out := buf.String()
+	for _, want := range []string{
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/Berlin\r\n",
+		// переход на летнее время 31 марта 2024 в 02:00 по местному времени
+		"BEGIN:DAYLIGHT\r\nDTSTART:20240331T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\nTZNAME:CEST\r\nEND:DAYLIGHT\r\n",
+		"DTSTART;TZID=Europe/Berlin:20240325T090000",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in export:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "BEGIN:VTIMEZONE") != 1 {
+		t.Errorf("Expected a single VTIMEZONE, got:\n%s", out)
+	}
+
+	events, err := ParseICS(&buf, "")
+	if err != nil || len(events) != 2 {
+		t.Fatalf("Expected export with VTIMEZONE to parse, got %d events (%v)", len(events), err)
+	}
+}
+
+func TestParseICS_FloatingTimes(t *testing.T) {
+	const floating = "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:floating@example.com\r\n" +
+		"EXDATE;VALUE=DATE:20240103\r\n" +
+		"DTSTART:20240101T100000\r\n" +
+		"RRULE:FREQ=DAILY;COUNT=5\r\n" +
+		"SUMMARY:Floating standup\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	events, err := ParseICS(strings.NewReader(floating), "Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := events[0]; got.Timezone != "Asia/Tokyo" || !got.Start.Equal(time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected floating start in request timezone, got %v (%s)", got.Start, got.Timezone)
+	}
+	// EXDATE-дата у серии со временем исключает вхождение в то же время дня
+	if ex := events[0].Recurrence.ExDates; len(ex) != 1 || !ex[0].Equal(time.Date(2024, 1, 3, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected EXDATE at the series time of day, got %v", ex)
+	}
+
+	calendar := NewCalendar()
+	importICSEvents(calendar, 1, events)
+	january := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := calendar.GetEventsInRange(1, january, january.AddDate(0, 0, 7)); len(got) != 4 {
+		t.Errorf("Expected 4 occurrences after EXDATE, got %d", len(got))
+	}
+
+	// X-WR-TIMEZONE календаря важнее часового пояса запроса
+	withCalendarZone := strings.Replace(floating, "BEGIN:VCALENDAR\r\n", "BEGIN:VCALENDAR\r\nX-WR-TIMEZONE:Europe/Moscow\r\n", 1)
+	events, _ = ParseICS(strings.NewReader(withCalendarZone), "Asia/Tokyo")
+	if got := events[0]; got.Timezone != "Europe/Moscow" || !got.Start.Equal(time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected floating start in X-WR-TIMEZONE, got %v (%s)", got.Start, got.Timezone)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// floatingSlack - максимальный сдвиг между хранимым началом события на весь
+// день и его "плавающим" началом в поясе запроса (разница UTC-11 и UTC+14 плюс час).
+const floatingSlack = 27 * time.Hour
+
+// shortSpan - предел длительности событий в byStart (сутки плюс час на переход
+// на летнее время). Более длинные события лежат в long и проверяются перебором,
+// зато окно поиска по byStart не зависит от самого длинного события.
+const shortSpan = 25 * time.Hour
+
+// startKey - компактный ключ byStart: сдвигать при вставке приходится его,
+// а не всё событие целиком.
+type startKey struct {
+	date time.Time
+	id   int
+}
+
+// userEvents - индекс событий одного пользователя. Ключи обычных событий лежат
+// в byStart, отсортированные по (Date, ID), поэтому выборка по диапазону стоит
+// O(log n + k). Многодневные события и серии хранятся отдельно; серии
+// разворачиваются при запросе, если их границы пересекают диапазон.
+type userEvents struct {
+	byID    map[int]Event
+	byUID   map[string][]int
+	byStart []startKey
+	// long - события длиннее shortSpan
+	long   map[int]struct{}
+	series map[int]struct{}
+	// seriesEnd - конец последнего вхождения серии с COUNT или UNTIL;
+	// бесконечных серий здесь нет
+	seriesEnd map[int]time.Time
+	// invited - события других пользователей, куда приглашён этот:
+	// ID события -> организатор
+	invited map[int]int
+	// shared - чужие календари, открытые пользователю: ID календаря -> права
+	shared map[int]Permission
+	// search - полнотекстовый индекс по названию, месту, описанию и тегам
+	search *searchIndex
+}
+
+func newUserEvents() *userEvents {
+	return &userEvents{
+		byID:      make(map[int]Event),
+		byUID:     make(map[string][]int),
+		long:      make(map[int]struct{}),
+		series:    make(map[int]struct{}),
+		seriesEnd: make(map[int]time.Time),
+		invited:   make(map[int]int),
+		shared:    make(map[int]Permission),
+		search:    newSearchIndex(),
+	}
+}
+
+func (u *userEvents) add(event Event) {
+	u.byID[event.ID] = event
+	u.byUID[event.UID] = append(u.byUID[event.UID], event.ID)
+	u.search.add(event)
+
+	if event.Recurrence != nil {
+		u.series[event.ID] = struct{}{}
+		if end, ok := seriesEnd(event); ok {
+			u.seriesEnd[event.ID] = end
+		}
+		return
+	}
+	if event.End.Sub(event.Date) > shortSpan {
+		u.long[event.ID] = struct{}{}
+		return
+	}
+
+	i := u.position(event)
+	u.byStart = append(u.byStart, startKey{})
+	copy(u.byStart[i+1:], u.byStart[i:])
+	u.byStart[i] = startKey{date: event.Date, id: event.ID}
+}
+
+func (u *userEvents) remove(event Event) {
+	delete(u.byID, event.ID)
+	u.search.remove(event)
+
+	ids := u.byUID[event.UID]
+	for i, id := range ids {
+		if id == event.ID {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(u.byUID, event.UID)
+	} else {
+		u.byUID[event.UID] = ids
+	}
+
+	if event.Recurrence != nil {
+		delete(u.series, event.ID)
+		delete(u.seriesEnd, event.ID)
+		return
+	}
+	if _, ok := u.long[event.ID]; ok {
+		delete(u.long, event.ID)
+		return
+	}
+
+	i := u.position(event)
+	if i < len(u.byStart) && u.byStart[i].id == event.ID {
+		u.byStart = append(u.byStart[:i], u.byStart[i+1:]...)
+	}
+}
+
+// position возвращает место события в byStart по ключу (Date, ID).
+func (u *userEvents) position(event Event) int {
+	return sort.Search(len(u.byStart), func(i int) bool {
+		key := u.byStart[i]
+		if key.date.Equal(event.Date) {
+			return key.id >= event.ID
+		}
+		return key.date.After(event.Date)
+	})
+}
+
+func (u *userEvents) searchStart(t time.Time) int {
+	return sort.Search(len(u.byStart), func(i int) bool {
+		return !u.byStart[i].date.Before(t)
+	})
+}
+
+// between возвращает события, пересекающиеся с [from, to), разворачивая серии.
+func (u *userEvents) between(from, to time.Time) []Event {
+	var result []Event
+
+	lo := u.searchStart(from.Add(-shortSpan - floatingSlack))
+	hi := u.searchStart(to.Add(floatingSlack))
+	for _, key := range u.byStart[lo:hi] {
+		if event := u.byID[key.id]; event.overlaps(from, to) {
+			result = append(result, event)
+		}
+	}
+
+	for id := range u.long {
+		if event := u.byID[id]; event.overlaps(from, to) {
+			result = append(result, event)
+		}
+	}
+	for id := range u.series {
+		if event := u.byID[id]; u.mayOverlap(event, from, to) {
+			result = append(result, expand(event, from, to)...)
+		}
+	}
+
+	sortEvents(result)
+	return result
+}
+
+// mayOverlap по границам серии отсекает те, что заведомо не пересекают
+// [from, to), чтобы не разворачивать их. Для обычных событий всегда true.
+func (u *userEvents) mayOverlap(event Event, from, to time.Time) bool {
+	if event.Recurrence == nil {
+		return true
+	}
+	if !event.Date.Before(to.Add(floatingSlack)) {
+		return false
+	}
+	end, ok := u.seriesEnd[event.ID]
+	return !ok || end.After(from.Add(-floatingSlack))
+}
+
+// seriesEnd возвращает конец последнего вхождения конечной серии.
+func seriesEnd(event Event) (time.Time, bool) {
+	r := event.Recurrence
+	span := event.End.Sub(event.Date)
+	if r.Until != nil {
+		return r.Until.Add(span), true
+	}
+	if r.Count == 0 {
+		return time.Time{}, false
+	}
+
+	// с COUNT разворачивание само остановится на последнем вхождении
+	occurrences, err := r.Occurrences(event.Date, event.Date, time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(occurrences) == 0 {
+		return time.Time{}, false
+	}
+	return occurrences[len(occurrences)-1].Add(span), true
+}
+
+// expand возвращает событие или вхождения серии, пересекающиеся с [from, to).
+func expand(event Event, from, to time.Time) []Event {
+	if event.Recurrence == nil {
+		if event.overlaps(from, to) {
+			return []Event{event}
+		}
+		return nil
+	}
+
+	var result []Event
+	windowFrom := from.Add(-event.End.Sub(event.Date) - floatingSlack)
+	occurrences, err := event.Recurrence.Occurrences(event.Date, windowFrom, to.Add(floatingSlack))
+	if err != nil {
+		// выборки не возвращают ошибок: отдаём найденное и оставляем след в логе
+		slog.Error("recurrence expansion truncated", "event_id", event.ID, "user_id", event.UserID, "error", err)
+	}
+	for _, at := range occurrences {
+		if occurrence := event.occurrence(at); occurrence.overlaps(from, to) {
+			result = append(result, occurrence)
+		}
+	}
+	return result
+}
+
+func (u *userEvents) all() []Event {
+	result := make([]Event, 0, len(u.byID))
+	for _, event := range u.byID {
+		result = append(result, event)
+	}
+	sortEvents(result)
+	return result
+}
+
+// sortEvents упорядочивает события по началу, а при равенстве - по ID,
+// чтобы порядок не зависел от обхода map.
+func sortEvents(events []Event) {
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Date.Equal(events[j].Date) {
+			return events[i].ID < events[j].ID
+		}
+		return events[i].Date.Before(events[j].Date)
+	})
+}
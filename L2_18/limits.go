@@ -0,0 +1,304 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bucketIdle - как часто забываются корзины клиентов, переставших делать
+// запросы. Удаляется только уже наполнившаяся корзина: при медленном Rate
+// (burst/rate > bucketIdle) она может пролежать дольше.
+const bucketIdle = 10 * time.Minute
+
+// RouteLimit - ограничения маршрута: Rate запросов в секунду с запасом Burst
+// на клиента и MaxBody байт тела. Нулевой Rate или MaxBody снимает ограничение.
+type RouteLimit struct {
+	Rate    float64
+	Burst   int
+	MaxBody int64
+}
+
+// RouteLimits - переопределения по маршрутам. Ключ - шаблон пути mux
+// ("/users/{uid:[0-9]+}/events") или метод и шаблон ("POST /import").
+type RouteLimits map[string]RouteLimit
+
+// String и Set делают RouteLimits флагом. Формат - записи через ";":
+// "POST /import=body:10485760; /events/search=rate:5,burst:10"; не указанные
+// поля (в RouteLimit они равны -1) берутся из общих настроек (-rate-limit,
+// -rate-burst, -max-body).
+func (l RouteLimits) String() string {
+	entries := make([]string, 0, len(l))
+	for route, limit := range l {
+		var settings []string
+		if limit.Rate >= 0 {
+			settings = append(settings, fmt.Sprintf("rate:%g", limit.Rate))
+		}
+		if limit.Burst >= 0 {
+			settings = append(settings, fmt.Sprintf("burst:%d", limit.Burst))
+		}
+		if limit.MaxBody >= 0 {
+			settings = append(settings, fmt.Sprintf("body:%d", limit.MaxBody))
+		}
+		entries = append(entries, route+"="+strings.Join(settings, ","))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ";")
+}
+
+func (l RouteLimits) Set(value string) error {
+	for route := range l {
+		delete(l, route)
+	}
+	for _, entry := range strings.Split(value, ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		route, settings, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("route limit %q: expected route=setting:value,...", entry)
+		}
+		route = routeLimitKey(route)
+		limit := RouteLimit{Rate: -1, Burst: -1, MaxBody: -1}
+		for _, setting := range strings.Split(settings, ",") {
+			name, raw, _ := strings.Cut(strings.TrimSpace(setting), ":")
+			var err error
+			switch name {
+			case "rate":
+				limit.Rate, err = strconv.ParseFloat(raw, 64)
+				if err == nil && (limit.Rate < 0 || math.IsInf(limit.Rate, 0) || math.IsNaN(limit.Rate)) {
+					err = errors.New("must be a non-negative number")
+				}
+			case "burst":
+				limit.Burst, err = strconv.Atoi(raw)
+				if err == nil && limit.Burst < 0 {
+					err = errors.New("must not be negative")
+				}
+			case "body":
+				limit.MaxBody, err = strconv.ParseInt(raw, 10, 64)
+				if err == nil && limit.MaxBody < 0 {
+					err = errors.New("must not be negative")
+				}
+			default:
+				err = errors.New("unknown setting, expected rate, burst or body")
+			}
+			if err != nil {
+				return fmt.Errorf("route limit %q: %s: %w", route, name, err)
+			}
+		}
+		l[route] = limit
+	}
+	return nil
+}
+
+// routeLimitKey приводит "post  /import" к "POST /import".
+func routeLimitKey(route string) string {
+	fields := strings.Fields(route)
+	if len(fields) == 2 {
+		return strings.ToUpper(fields[0]) + " " + fields[1]
+	}
+	return strings.TrimSpace(route)
+}
+
+// Limiter ограничивает частоту запросов (token bucket на клиента и маршрут) и
+// размер тела. Клиент - аутентифицированный пользователь, а без
+// аутентификации - IP-адрес.
+type Limiter struct {
+	defaults RouteLimit
+	routes   RouteLimits
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// bucket хранит rate и burst маршрута, чтобы sweep знал, наполнилась ли она.
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  float64
+}
+
+func NewLimiter(defaults RouteLimit, routes RouteLimits) *Limiter {
+	return &Limiter{
+		defaults: defaults,
+		routes:   routes,
+		buckets:  make(map[string]*bucket),
+		now:      time.Now,
+	}
+}
+
+// limit возвращает ограничения маршрута запроса и ключ, которым они заданы;
+// поля, не указанные для маршрута, берутся из общих настроек.
+func (l *Limiter) limit(r *http.Request) (RouteLimit, string) {
+	route := routeTemplate(r)
+	key := r.Method + " " + route
+	limit, ok := l.routes[key]
+	if !ok {
+		if limit, ok = l.routes[route]; !ok {
+			return l.defaults, route
+		}
+		key = route
+	}
+	if limit.Rate < 0 {
+		limit.Rate = l.defaults.Rate
+	}
+	if limit.Burst < 0 {
+		limit.Burst = l.defaults.Burst
+	}
+	if limit.MaxBody < 0 {
+		limit.MaxBody = l.defaults.MaxBody
+	}
+	return limit, key
+}
+
+// BodyMiddleware обрезает тело запроса до MaxBody маршрута. Ставится до
+// AuthMiddleware, которая читает JSON-тело в поисках user_id.
+func (l *Limiter) BodyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := l.limit(r)
+		if limit.MaxBody > 0 && r.Body != nil {
+			if r.ContentLength > limit.MaxBody {
+				writeError(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit.MaxBody)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RateMiddleware отвечает 429 с Retry-After, когда клиент исчерпал запас
+// запросов к маршруту. Ставится после AuthMiddleware, чтобы считать запросы
+// по пользователю, а не по адресу; отвергнутые AuthMiddleware запросы
+// считает UnauthenticatedMiddleware.
+func (l *Limiter) RateMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, route := l.limit(r)
+		if limit.Rate <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		wait, ok := l.take(route+"|"+clientKey(r), limit)
+		if !ok {
+			writeRateLimited(w, wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UnauthenticatedMiddleware ограничивает по IP запросы, получившие 401:
+// без неё перебор токенов не упирался бы ни в какой лимит. Ставится перед
+// AuthMiddleware. Корзина адреса тратится только на отказы, поэтому
+// пользователи за общим адресом ограничиваются по отдельности, пока с него
+// не начнут подбирать токены.
+func (l *Limiter) UnauthenticatedMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, route := l.limit(r)
+		if limit.Rate <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := route + "|unauthenticated|" + clientKey(r)
+		if wait, ok := l.use(key, limit, false); !ok {
+			writeRateLimited(w, wait)
+			return
+		}
+		rec := newStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+		if rec.status == http.StatusUnauthorized {
+			l.take(key, limit)
+		}
+	})
+}
+
+func writeRateLimited(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	writeError(w, "rate limit exceeded", http.StatusTooManyRequests)
+}
+
+// take забирает токен из корзины key. Если токенов нет, возвращает, через
+// сколько появится следующий.
+func (l *Limiter) take(key string, limit RouteLimit) (time.Duration, bool) {
+	return l.use(key, limit, true)
+}
+
+// use пополняет корзину key и проверяет, есть ли в ней токен; consume
+// забирает его.
+func (l *Limiter) use(key string, limit RouteLimit, consume bool) (time.Duration, bool) {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	b.rate, b.burst = limit.Rate, burst
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second)), false
+	}
+	if consume {
+		b.tokens--
+	}
+	return 0, true
+}
+
+// sweep раз в bucketIdle удаляет корзины, которые за время простоя уже
+// наполнились: новая корзина даст клиенту столько же, сколько старая.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketIdle {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func clientKey(r *http.Request) string {
+	if principal, ok := PrincipalFromContext(r.Context()); ok {
+		return "user:" + strconv.Itoa(principal.UserID)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// writeBodyError отвечает на ошибку разбора тела: 413, если оно превысило
+// лимит маршрута, иначе 400.
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	writeError(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func newLimitedRouter(limiter *Limiter) *mux.Router {
+	r := mux.NewRouter()
+	r.Use(limiter.BodyMiddleware, limiter.RateMiddleware)
+	NewHandler(NewCalendar()).RegisterRoutes(r)
+	return r
+}
+
+func TestLimiter_RateLimit(t *testing.T) {
+	routes := RouteLimits{}
+	if err := routes.Set("/healthz=rate:0; GET /events_for_day=rate:1,burst:2"); err != nil {
+		t.Fatal(err)
+	}
+	limiter := NewLimiter(RouteLimit{Rate: 100, Burst: 100}, routes)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	r := newLimitedRouter(limiter)
+
+	get := func(target, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	day := "/events_for_day?user_id=1&date=2024-01-01"
+	for i := 0; i < 2; i++ {
+		if rec := get(day, "10.0.0.1:1000"); rec.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+	rec := get(day, "10.0.0.1:1001")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 after burst, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", rec.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(rec.Body.String(), `"code":"rate_limited"`) {
+		t.Errorf("Expected rate_limited code, got %s", rec.Body)
+	}
+
+	if rec := get(day, "10.0.0.2:1000"); rec.Code != http.StatusOK {
+		t.Errorf("Expected other client to be unaffected, got %d", rec.Code)
+	}
+	if rec := get("/events_for_week?user_id=1&date=2024-01-01", "10.0.0.1:1000"); rec.Code != http.StatusOK {
+		t.Errorf("Expected other route to have its own bucket, got %d", rec.Code)
+	}
+	for i := 0; i < 200; i++ {
+		if rec := get("/healthz", "10.0.0.1:1000"); rec.Code != http.StatusOK {
+			t.Fatalf("Expected unlimited /healthz, got %d", rec.Code)
+		}
+	}
+
+	now = now.Add(time.Second)
+	if rec := get(day, "10.0.0.1:1000"); rec.Code != http.StatusOK {
+		t.Errorf("Expected token to refill after a second, got %d", rec.Code)
+	}
+}
+
+func TestLimiter_Unauthenticated(t *testing.T) {
+	auth, _ := NewAPIKeyAuthenticator([]apiKeyEntry{{Key: "alice-key", UserID: 1}})
+	limiter := NewLimiter(RouteLimit{Rate: 1, Burst: 2}, nil)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	r := mux.NewRouter()
+	r.Use(limiter.UnauthenticatedMiddleware, AuthMiddleware(auth), limiter.RateMiddleware)
+	NewHandler(NewCalendar()).RegisterRoutes(r)
+
+	get := func(token, addr string) int {
+		req := httptest.NewRequest("GET", "/events_for_day?user_id=1&date=2024-01-01", nil)
+		req.RemoteAddr = addr
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// успешные запросы не тратят корзину адреса
+	if code := get("alice-key", "10.0.0.1:1000"); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	for i := 0; i < 2; i++ {
+		if code := get("guess", "10.0.0.1:1000"); code != http.StatusUnauthorized {
+			t.Fatalf("Attempt %d: expected 401, got %d", i, code)
+		}
+	}
+	if code := get("guess", "10.0.0.1:1000"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 after repeated 401s, got %d", code)
+	}
+	if code := get("guess", "10.0.0.2:1000"); code != http.StatusUnauthorized {
+		t.Errorf("Expected other address to be unaffected, got %d", code)
+	}
+
+	now = now.Add(time.Second)
+	if code := get("alice-key", "10.0.0.1:1000"); code != http.StatusOK {
+		t.Errorf("Expected 200 once the address bucket refilled, got %d", code)
+	}
+}
+
+func TestLimiter_SweepKeepsSlowBuckets(t *testing.T) {
+	routes := RouteLimits{}
+	if err := routes.Set("GET /events_for_day=rate:0.001,burst:1"); err != nil {
+		t.Fatal(err)
+	}
+	limiter := NewLimiter(RouteLimit{Rate: 100, Burst: 100}, routes)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	r := newLimitedRouter(limiter)
+
+	get := func(target string) int {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		return rec.Code
+	}
+
+	day := "/events_for_day?user_id=1&date=2024-01-01"
+	get(day)
+	get("/events_for_week?user_id=1&date=2024-01-01")
+
+	// токен появится только через 1000 секунд: простой дольше bucketIdle не
+	// должен выдать его раньше
+	now = now.Add(bucketIdle + time.Minute)
+	if code := get(day); code != http.StatusTooManyRequests {
+		t.Errorf("Expected slow bucket to survive the sweep, got %d", code)
+	}
+	if len(limiter.buckets) != 1 {
+		t.Errorf("Expected only the refilled bucket to be swept, got %d buckets", len(limiter.buckets))
+	}
+
+	now = now.Add(1000 * time.Second)
+	if code := get(day); code != http.StatusOK {
+		t.Errorf("Expected token after 1000 seconds, got %d", code)
+	}
+}
+
+func TestLimiter_Body(t *testing.T) {
+	routes := RouteLimits{}
+	if err := routes.Set("POST /import=body:4096"); err != nil {
+		t.Fatal(err)
+	}
+	r := newLimitedRouter(NewLimiter(RouteLimit{MaxBody: 64}, routes))
+
+	post := func(target, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", target, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	long := `{"user_id":1,"date":"2024-01-01","title":"` + strings.Repeat("x", 100) + `"}`
+	if rec := post("/create_event", "application/json", long); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for oversized body, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// без Content-Length превышение обнаруживается только при чтении
+	req := httptest.NewRequest("POST", "/create_event", struct{ *bytes.Reader }{bytes.NewReader([]byte(long))})
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for streamed body, got %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := post("/create_event", "application/json", `{"user_id":1,"date":"2024-01-01","titel":"x"}`); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "titel") {
+		t.Errorf("Expected 400 naming the unknown field, got %d: %s", rec.Code, rec.Body)
+	}
+
+	ics := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:a\r\nDTSTART:20240101T090000Z\r\nSUMMARY:" + strings.Repeat("x", 100) + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	if rec := post("/import?user_id=1", "text/calendar", ics); rec.Code != http.StatusOK {
+		t.Errorf("Expected route override to allow larger import, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestRouteLimits_Set(t *testing.T) {
+	routes := RouteLimits{}
+	if err := routes.Set("post  /import=body:10; /events=rate:2.5"); err != nil {
+		t.Fatal(err)
+	}
+	if got := routes["POST /import"]; got.MaxBody != 10 || got.Rate != -1 || got.Burst != -1 {
+		t.Errorf("Unexpected POST /import limit: %+v", got)
+	}
+	if got := routes.String(); got != "/events=rate:2.5;POST /import=body:10" {
+		t.Errorf("Unexpected String(): %q", got)
+	}
+
+	for _, value := range []string{"/events", "/events=speed:1", "/events=rate:-1", "/events=burst:x"} {
+		if err := (RouteLimits{}).Set(value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
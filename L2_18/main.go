@@ -1,28 +1,215 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
 )
 
 func main() {
-	port := flag.String("port", "8080", "HTTP server port")
-	flag.Parse()
+	cfg, err := LoadConfig(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("Could not load config: %v", err)
+	}
+
+	logger, err := newLogger(cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		log.Fatalf("Could not configure logging: %v", err)
+	}
+	slog.SetDefault(logger)
+
+	storage, err := newStorage(cfg.Storage, cfg.DataDir, cfg.SnapshotEvery)
+	if err != nil {
+		log.Fatalf("Could not open storage: %v", err)
+	}
 
-	calendar := NewCalendar()
+	calendar, err := NewCalendarWithStorage(storage)
+	if err != nil {
+		log.Fatalf("Could not load events: %v", err)
+	}
+	calendar.SetTrashRetention(cfg.TrashRetention)
 	handler := NewHandler(calendar)
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	if cfg.TrashRetention > 0 {
+		go NewTrashJanitor(calendar, cfg.PurgeInterval).Run(ctx)
+	}
+
+	notifier, err := newNotifier(cfg.Notifier, cfg.WebhookURL)
+	if err != nil {
+		log.Fatalf("Could not configure reminders: %v", err)
+	}
+	if notifier != nil {
+		go NewReminderScheduler(calendar, notifier, cfg.ReminderInterval).Run(ctx)
+	}
+
+	auth, err := newAuthenticator(cfg.Auth, cfg.APIKeysFile, cfg.JWTSecret)
+	if err != nil {
+		log.Fatalf("Could not configure authentication: %v", err)
+	}
+
+	metrics := NewMetrics(calendar)
+	limiter := NewLimiter(RouteLimit{Rate: cfg.RateLimit, Burst: cfg.RateBurst, MaxBody: cfg.MaxBody}, cfg.RouteLimits)
+
 	router := mux.NewRouter()
-	router.Use(LoggingMiddleware)
+	router.Use(RequestIDMiddleware, LoggingMiddleware, metrics.Middleware, limiter.BodyMiddleware)
+	wrapUnmatched(router, RequestIDMiddleware, LoggingMiddleware, metrics.Middleware)
+	if auth != nil {
+		router.Use(limiter.UnauthenticatedMiddleware, AuthMiddleware(auth, "/healthz", "/readyz", "/metrics", "/openapi.json"))
+	}
+	router.Use(limiter.RateMiddleware)
+	router.Handle("/metrics", metrics).Methods("GET")
 	handler.RegisterRoutes(router)
+	if auth == nil {
+		NewUI(calendar).RegisterRoutes(router)
+	} else {
+		// формы UI не передают Authorization, и с -auth каждая страница отвечала бы 401
+		slog.Info("web UI disabled: it does not support authentication", "auth", cfg.Auth)
+	}
+
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           router,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("starting server", "port", cfg.Port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != "" {
+		listener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			log.Fatalf("Could not listen for gRPC: %v", err)
+		}
+		grpcServer = NewGRPCServer(calendar, auth, ctx.Done())
+		go func() {
+			slog.Info("starting gRPC server", "port", cfg.GRPCPort)
+			serverErr <- grpcServer.Serve(listener)
+		}()
+	}
 
-	log.Printf("Starting server on port %s", *port)
-	if err := http.ListenAndServe(":"+*port, router); err != nil {
+	select {
+	case err := <-serverErr:
 		log.Fatalf("Could not start server: %v", err)
-		os.Exit(1)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// сначала /readyz отвечает 503, и балансировщик успевает убрать сервер из
+	// ротации, пока он ещё принимает соединения; затем остаток запросов дренируется
+	slog.Info("shutting down", "readiness_grace", cfg.ReadinessGrace, "drain_timeout", cfg.ShutdownTimeout)
+	handler.Shutdown()
+	time.Sleep(cfg.ReadinessGrace)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("shutdown did not complete", "error", err)
+	}
+	if grpcServer != nil {
+		stopGRPC(shutdownCtx, grpcServer)
+	}
+	if err := calendar.Close(); err != nil {
+		slog.Error("could not close storage", "error", err)
+	}
+}
+
+// stopGRPC дожидается завершения вызовов, пока не истечёт ctx, и затем
+// обрывает оставшиеся.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Error("gRPC shutdown did not complete", "error", ctx.Err())
+		server.Stop()
+	}
+}
+
+func newStorage(kind, dataDir string, snapshotEvery int) (Storage, error) {
+	switch kind {
+	case "memory":
+		return NewMemoryStorage(), nil
+	case "file":
+		return NewFileStorage(dataDir, snapshotEvery)
+	default:
+		return nil, fmt.Errorf("unknown storage %q", kind)
+	}
+}
+
+func newNotifier(kind, webhookURL string) (Notifier, error) {
+	switch kind {
+	case "none":
+		return nil, nil
+	case "log":
+		return NewLogNotifier(nil), nil
+	case "webhook":
+		if webhookURL == "" {
+			return nil, fmt.Errorf("webhook notifier requires -webhook-url")
+		}
+		return NewWebhookNotifier(webhookURL, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", kind)
+	}
+}
+
+func newAuthenticator(kind, apiKeysFile, jwtSecret string) (Authenticator, error) {
+	switch kind {
+	case "none":
+		return nil, nil
+	case "apikeys":
+		return LoadAPIKeys(apiKeysFile)
+	case "jwt":
+		if jwtSecret == "" {
+			return nil, fmt.Errorf("jwt auth requires -jwt-secret or $CALENDAR_JWT_SECRET")
+		}
+		return NewJWTAuthenticator([]byte(jwtSecret)), nil
+	default:
+		return nil, fmt.Errorf("unknown auth %q", kind)
+	}
+}
+
+func newLogger(format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
 	}
 }
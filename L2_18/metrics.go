@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// latencyBuckets - границы гистограммы длительности запросов в секундах,
+// те же, что по умолчанию у клиентской библиотеки Prometheus.
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	route  string
+	method string
+	status int
+}
+
+type routeKey struct {
+	route  string
+	method string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, le := range latencyBuckets {
+		if v <= le {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// Metrics собирает метрики HTTP-запросов и отдаёт их вместе с числом событий
+// пользователей в текстовом формате Prometheus.
+type Metrics struct {
+	calendar *Calendar
+
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	latencies map[routeKey]*histogram
+	errors    map[int]uint64
+}
+
+func NewMetrics(calendar *Calendar) *Metrics {
+	return &Metrics{
+		calendar:  calendar,
+		requests:  make(map[requestKey]uint64),
+		latencies: make(map[routeKey]*histogram),
+		errors:    make(map[int]uint64),
+	}
+}
+
+// Middleware учитывает запрос под шаблоном маршрута mux, а не под конкретным
+// путём, чтобы число рядов не зависело от ID в URL.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := newStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+		m.observe(routeTemplate(r), r.Method, rec.status, time.Since(start))
+	})
+}
+
+func (m *Metrics) observe(route, method string, status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{route: route, method: method, status: status}]++
+
+	key := routeKey{route: route, method: method}
+	h, ok := m.latencies[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		m.latencies[key] = h
+	}
+	h.observe(d.Seconds())
+
+	if status >= 400 {
+		m.errors[status]++
+	}
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	m.write(bw)
+	bw.Flush()
+}
+
+func (m *Metrics) write(w *bufio.Writer) {
+	m.mu.Lock()
+	requests := make([]requestKey, 0, len(m.requests))
+	for key := range m.requests {
+		requests = append(requests, key)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		a, b := requests[i], requests[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+
+	// %q экранирует кавычки, обратную косую черту и переводы строк так же,
+	// как того требует текстовый формат для значений меток
+	fmt.Fprintln(w, "# HELP calendar_http_requests_total Total HTTP requests by route, method and status.")
+	fmt.Fprintln(w, "# TYPE calendar_http_requests_total counter")
+	for _, key := range requests {
+		fmt.Fprintf(w, "calendar_http_requests_total{route=%q,method=%q,status=\"%d\"} %d\n",
+			key.route, key.method, key.status, m.requests[key])
+	}
+
+	routes := make([]routeKey, 0, len(m.latencies))
+	for key := range m.latencies {
+		routes = append(routes, key)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].route != routes[j].route {
+			return routes[i].route < routes[j].route
+		}
+		return routes[i].method < routes[j].method
+	})
+
+	fmt.Fprintln(w, "# HELP calendar_http_request_duration_seconds HTTP request latency by route and method.")
+	fmt.Fprintln(w, "# TYPE calendar_http_request_duration_seconds histogram")
+	for _, key := range routes {
+		h := m.latencies[key]
+		labels := fmt.Sprintf("route=%q,method=%q", key.route, key.method)
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(w, "calendar_http_request_duration_seconds_bucket{%s,le=%q} %d\n",
+				labels, strconv.FormatFloat(le, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(w, "calendar_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(w, "calendar_http_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "calendar_http_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+
+	statuses := make([]int, 0, len(m.errors))
+	for status := range m.errors {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+
+	fmt.Fprintln(w, "# HELP calendar_http_errors_total HTTP responses with status 400 and above.")
+	fmt.Fprintln(w, "# TYPE calendar_http_errors_total counter")
+	for _, status := range statuses {
+		fmt.Fprintf(w, "calendar_http_errors_total{status=\"%d\"} %d\n", status, m.errors[status])
+	}
+	m.mu.Unlock()
+
+	counts := m.calendar.EventCounts()
+	users := make([]int, 0, len(counts))
+	for userID := range counts {
+		users = append(users, userID)
+	}
+	sort.Ints(users)
+
+	fmt.Fprintln(w, "# HELP calendar_events Stored events per user, series counted once.")
+	fmt.Fprintln(w, "# TYPE calendar_events gauge")
+	for _, userID := range users {
+		fmt.Fprintf(w, "calendar_events{user_id=\"%d\"} %d\n", userID, counts[userID])
+	}
+}
+
+// wrapUnmatched пропускает ответы 404 и 405 через middleware: mux применяет
+// цепочку Use только к совпавшим маршрутам, и без этого такие запросы не
+// попадали бы ни в логи, ни в метрики.
+func wrapUnmatched(router *mux.Router, middleware ...mux.MiddlewareFunc) {
+	wrap := func(h http.Handler) http.Handler {
+		for i := len(middleware) - 1; i >= 0; i-- {
+			h = middleware[i](h)
+		}
+		return h
+	}
+	router.NotFoundHandler = wrap(http.NotFoundHandler())
+	router.MethodNotAllowedHandler = wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unmatched"
+}
+
+// statusRecorder запоминает код ответа. Flush и Hijack пробрасываются, чтобы
+// SSE и WebSocket работали через middleware.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func newStatusRecorder(w http.ResponseWriter) *statusRecorder {
+	if rec, ok := w.(*statusRecorder); ok {
+		return rec
+	}
+	return &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijacking is not supported")
+	}
+	return h.Hijack()
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestMetrics(t *testing.T) {
+	calendar := NewCalendar()
+	calendar.CreateEvent(1, time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), "Event")
+	metrics := NewMetrics(calendar)
+
+	r := mux.NewRouter()
+	r.Use(RequestIDMiddleware, LoggingMiddleware, metrics.Middleware)
+	wrapUnmatched(r, RequestIDMiddleware, LoggingMiddleware, metrics.Middleware)
+	r.Handle("/metrics", metrics).Methods("GET")
+	NewHandler(calendar).RegisterRoutes(r)
+
+	do := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("/api/v2/users/1/events/1", http.Header{"X-Request-Id": {"trace-42"}})
+	if id := rec.Header().Get("X-Request-ID"); id != "trace-42" {
+		t.Errorf("Expected request ID to be propagated, got %q", id)
+	}
+	do("/api/v2/users/1/events/2", nil)
+	do("/no/such/path", nil)
+	// 404 и 405 mux отдаёт в обход Use, но в метриках они нужны
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("DELETE", "/metrics", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("X-Request-ID") == "" {
+		t.Errorf("Expected 405 through middleware, got %d %v", rec.Code, rec.Header())
+	}
+	if id := do("/healthz", nil).Header().Get("X-Request-ID"); len(id) != 32 {
+		t.Errorf("Expected generated request ID, got %q", id)
+	}
+
+	body := do("/metrics", nil).Body.String()
+	for _, want := range []string{
+		`calendar_http_requests_total{route="/api/v2/users/{uid:[0-9]+}/events/{id:[0-9]+}",method="GET",status="200"} 1`,
+		`calendar_http_requests_total{route="/api/v2/users/{uid:[0-9]+}/events/{id:[0-9]+}",method="GET",status="404"} 1`,
+		`calendar_http_request_duration_seconds_count{route="/healthz",method="GET"} 1`,
+		`calendar_http_requests_total{route="unmatched",method="GET",status="404"} 1`,
+		`calendar_http_errors_total{status="404"} 2`,
+		`calendar_http_errors_total{status="405"} 1`,
+		`calendar_events{user_id="1"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %s\n%s", want, body)
+		}
+	}
+}
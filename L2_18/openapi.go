@@ -0,0 +1,526 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// schema - фрагмент JSON Schema в диалекте OpenAPI 3.0.
+type schema map[string]interface{}
+
+func ref(name string) schema {
+	return schema{"$ref": "#/components/schemas/" + name}
+}
+
+func arrayOf(items schema) schema {
+	return schema{"type": "array", "items": items}
+}
+
+func object(required []string, properties map[string]schema) schema {
+	s := schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+var (
+	stringSchema   = schema{"type": "string"}
+	integerSchema  = schema{"type": "integer"}
+	booleanSchema  = schema{"type": "boolean"}
+	numberSchema   = schema{"type": "number"}
+	dateTimeSchema = schema{"type": "string", "format": "date-time"}
+)
+
+func enum(values ...string) schema {
+	return schema{"type": "string", "enum": values}
+}
+
+// apiParam - параметр операции: в пути, query или заголовке.
+type apiParam struct {
+	name        string
+	in          string
+	description string
+	required    bool
+	schema      schema
+}
+
+func pathParam(name, description string) apiParam {
+	return apiParam{name: name, in: "path", description: description, required: true, schema: integerSchema}
+}
+
+func queryParam(name, description string, s schema) apiParam {
+	return apiParam{name: name, in: "query", description: description, schema: s}
+}
+
+func requiredQuery(name, description string, s schema) apiParam {
+	return apiParam{name: name, in: "query", description: description, required: true, schema: s}
+}
+
+// apiOperation описывает маршрут для /openapi.json. path записан так же, как
+// при регистрации в mux, чтобы тест мог сверить документ с роутером.
+type apiOperation struct {
+	method  string
+	path    string
+	id      string
+	summary string
+	tag     string
+	params  []apiParam
+	// body - схема тела; bodyTypes - принимаемые типы, по умолчанию JSON
+	body      schema
+	bodyTypes []string
+	status    int
+	// result - схема ответа; v1-ответы заворачиваются в {"result": ...}
+	result      schema
+	resultType  string
+	unwrapped   bool
+	description string
+}
+
+var (
+	userIDQuery = requiredQuery("user_id", "Владелец событий.", integerSchema)
+	tzQuery     = queryParam("tz", "Часовой пояс IANA для дат без смещения, по умолчанию UTC.", stringSchema)
+	limitQuery  = queryParam("limit", "Размер страницы.", schema{"type": "integer", "minimum": 1, "maximum": maxPageLimit})
+	cursorQuery = queryParam("cursor", "next_cursor предыдущей страницы.", stringSchema)
+	fromQuery   = requiredQuery("from", "Начало диапазона: YYYY-MM-DD или RFC 3339.", stringSchema)
+	toQuery     = queryParam("to", "Конец диапазона (не включается); по умолчанию from плюс сутки.", stringSchema)
+	dateQuery   = requiredQuery("date", "День в формате YYYY-MM-DD.", schema{"type": "string", "format": "date"})
+	statusQuery = queryParam("status", "Фильтр по ответу на приглашение.", ref("AttendeeStatus"))
+	uidPath     = pathParam("uid", "Владелец событий.")
+	idPath      = pathParam("id", "ID события.")
+	ifMatch     = apiParam{name: "If-Match", in: "header", description: `ETag события ("id-version"); изменение выполнится только для этой версии.`, schema: stringSchema}
+	occurrence  = queryParam("occurrence", "Дата вхождения серии, к которому относится операция.", stringSchema)
+	userIDsList = requiredQuery("user_ids", "ID пользователей через запятую.", stringSchema)
+	cidPath     = pathParam("cid", "ID календаря.")
+	// calendarQuery - фильтр по календарям; 0 - календарь по умолчанию
+	calendarQuery = apiParam{name: "calendar_id", in: "query", description: "ID календарей через запятую или повтором параметра; 0 - календарь по умолчанию.", schema: arrayOf(integerSchema)}
+
+	formTypes = []string{"application/json", "application/x-www-form-urlencoded"}
+)
+
+var apiOperations = []apiOperation{
+	{method: "POST", path: "/create_event", id: "createEvent", tag: "events", summary: "Создать событие",
+		body: ref("EventRequest"), bodyTypes: formTypes, result: ref("Event")},
+	{method: "POST", path: "/update_event", id: "updateEvent", tag: "events", summary: "Изменить событие или одно вхождение серии",
+		body: ref("EventRequest"), bodyTypes: formTypes, result: ref("Event")},
+	{method: "POST", path: "/delete_event", id: "deleteEvent", tag: "events", summary: "Удалить событие или одно вхождение серии",
+		body: ref("EventRequest"), bodyTypes: formTypes, result: stringSchema},
+	{method: "GET", path: "/events_for_day", id: "eventsForDay", tag: "events", summary: "События за день",
+		params: []apiParam{userIDQuery, dateQuery, tzQuery, calendarQuery}, result: arrayOf(ref("Event"))},
+	{method: "GET", path: "/events_for_week", id: "eventsForWeek", tag: "events", summary: "События за неделю, начиная с понедельника",
+		params: []apiParam{userIDQuery, dateQuery, tzQuery, calendarQuery}, result: arrayOf(ref("Event"))},
+	{method: "GET", path: "/events_for_month", id: "eventsForMonth", tag: "events", summary: "События за месяц",
+		params: []apiParam{userIDQuery, dateQuery, tzQuery, calendarQuery}, result: arrayOf(ref("Event"))},
+	{method: "GET", path: "/events", id: "eventsInRange", tag: "events", summary: "События в диапазоне, постранично",
+		params: []apiParam{userIDQuery, fromQuery, toQuery, tzQuery, limitQuery, cursorQuery, calendarQuery}, result: ref("EventPage")},
+	{method: "POST", path: "/events/batch", id: "eventsBatch", tag: "events", summary: "Атомарно применить пакет операций",
+		body: ref("BatchRequest"), result: arrayOf(ref("BatchItemResult"))},
+	{method: "GET", path: "/events/stream", id: "eventsStream", tag: "changes", summary: "Изменения событий как Server-Sent Events",
+		params: []apiParam{userIDQuery, queryParam("last_event_id", "Id последнего полученного изменения, <epoch>-<seq>; то же, что заголовок Last-Event-ID.", stringSchema)},
+		result: ref("Change"), resultType: "text/event-stream", unwrapped: true},
+	{method: "GET", path: "/events/ws", id: "eventsWebSocket", tag: "changes", summary: "Изменения событий через WebSocket",
+		params:      []apiParam{userIDQuery, queryParam("last_event_id", "Id последнего полученного изменения, <epoch>-<seq>.", stringSchema)},
+		status:      http.StatusSwitchingProtocols,
+		description: "После рукопожатия сервер шлёт сообщения StreamMessage."},
+	{method: "GET", path: "/events/search", id: "searchEvents", tag: "events", summary: "Полнотекстовый поиск по событиям",
+		params: []apiParam{userIDQuery, queryParam("q", "Слова запроса; #tag фильтрует по тегу.", stringSchema),
+			{name: "tag", in: "query", description: "Тег; можно повторять.", schema: arrayOf(stringSchema)}, limitQuery},
+		result: arrayOf(ref("SearchResult"))},
+	{method: "GET", path: "/events/history", id: "eventHistory", tag: "history", summary: "История изменений события",
+		params: []apiParam{userIDQuery, requiredQuery("id", "ID события.", integerSchema)}, result: arrayOf(ref("AuditEntry"))},
+	{method: "POST", path: "/events/restore", id: "restoreEvent", tag: "history", summary: "Восстановить удалённое событие",
+		body: ref("EventRequest"), bodyTypes: formTypes, result: ref("Event")},
+	{method: "POST", path: "/events/revert", id: "revertEvent", tag: "history", summary: "Вернуть событие к прежней версии",
+		body: ref("EventRequest"), bodyTypes: formTypes, result: ref("Event")},
+	{method: "GET", path: "/events/trash", id: "trash", tag: "history", summary: "Корзина: удалённые события, которые ещё можно восстановить",
+		params: []apiParam{userIDQuery}, result: arrayOf(ref("TrashedEvent"))},
+	{method: "GET", path: "/export.ics", id: "exportICS", tag: "ical", summary: "Выгрузить события в iCalendar",
+		params: []apiParam{userIDQuery, calendarQuery}, result: stringSchema, resultType: "text/calendar", unwrapped: true},
+	{method: "POST", path: "/import", id: "importICS", tag: "ical", summary: "Загрузить события из iCalendar",
+		params: []apiParam{userIDQuery, queryParam("tz", "Часовой пояс IANA для плавающего времени, если у события нет TZID, а у календаря - X-WR-TIMEZONE; по умолчанию UTC.", stringSchema)}, body: stringSchema, bodyTypes: []string{"text/calendar"}, result: ref("ImportResult")},
+	{method: "POST", path: "/rsvp", id: "rsvp", tag: "invitations", summary: "Ответить на приглашение",
+		body: ref("EventRequest"), bodyTypes: formTypes, result: ref("Event")},
+	{method: "GET", path: "/invitations", id: "invitations", tag: "invitations", summary: "Приглашения пользователя",
+		params: []apiParam{userIDQuery, statusQuery}, result: arrayOf(ref("Event"))},
+	{method: "GET", path: "/freebusy", id: "freeBusy", tag: "scheduling", summary: "Занятое время пользователей",
+		params: []apiParam{userIDsList, fromQuery, toQuery, tzQuery}, result: ref("FreeBusy")},
+	{method: "GET", path: "/free_slots", id: "freeSlots", tag: "scheduling", summary: "Свободные окна, общие для пользователей",
+		params: []apiParam{userIDsList, fromQuery, toQuery, tzQuery,
+			requiredQuery("duration", `Длительность окна, например "30m".`, stringSchema),
+			queryParam("step", "Шаг сетки начала окон, по умолчанию 15m.", stringSchema),
+			queryParam("count", "Сколько окон вернуть, по умолчанию 3.", integerSchema)},
+		result: arrayOf(ref("Interval"))},
+	{method: "GET", path: "/calendars", id: "listCalendars", tag: "calendars", summary: "Свои и открытые пользователю календари",
+		params: []apiParam{userIDQuery}, result: arrayOf(ref("Calendar"))},
+	{method: "POST", path: "/calendars/create", id: "createCalendar", tag: "calendars", summary: "Создать календарь",
+		body: ref("CalendarRequest"), bodyTypes: formTypes, result: ref("Calendar")},
+	{method: "POST", path: "/calendars/rename", id: "renameCalendar", tag: "calendars", summary: "Переименовать календарь",
+		body: ref("CalendarRequest"), bodyTypes: formTypes, result: ref("Calendar")},
+	{method: "POST", path: "/calendars/delete", id: "deleteCalendar", tag: "calendars", summary: "Удалить пустой календарь",
+		body: ref("CalendarRequest"), bodyTypes: formTypes, result: stringSchema},
+	{method: "POST", path: "/calendars/share", id: "shareCalendar", tag: "calendars", summary: "Открыть или закрыть доступ к календарю",
+		body: ref("CalendarRequest"), bodyTypes: formTypes, result: ref("Calendar"),
+		description: `permission "none" закрывает доступ пользователю share_with.`},
+	{method: "GET", path: "/healthz", id: "healthz", tag: "ops", summary: "Процесс жив", result: ref("Health"), unwrapped: true},
+	{method: "GET", path: "/readyz", id: "readyz", tag: "ops", summary: "Сервис готов принимать запросы", result: ref("Health"), unwrapped: true},
+	{method: "GET", path: "/metrics", id: "metrics", tag: "ops", summary: "Метрики в формате Prometheus",
+		result: stringSchema, resultType: "text/plain", unwrapped: true},
+	{method: "GET", path: "/openapi.json", id: "openAPI", tag: "ops", summary: "Этот документ",
+		result: schema{"type": "object"}, unwrapped: true},
+
+	{method: "GET", path: "/api/v2/users/{uid:[0-9]+}/events", id: "v2ListEvents", tag: "v2", summary: "События пользователя или вхождения в диапазоне",
+		params:      []apiParam{uidPath, queryParam("from", "Начало диапазона; без него отдаются сами события и серии.", stringSchema), toQuery, tzQuery, limitQuery, cursorQuery, calendarQuery},
+		result:      ref("EventPage"),
+		unwrapped:   true,
+		description: "Без from серии отдаются одним событием, с from - развёрнутыми вхождениями."},
+	{method: "POST", path: "/api/v2/users/{uid:[0-9]+}/events", id: "v2CreateEvent", tag: "v2", summary: "Создать событие",
+		params: []apiParam{uidPath}, body: ref("EventRequest"), status: http.StatusCreated, result: ref("Event"), unwrapped: true},
+	{method: "GET", path: "/api/v2/users/{uid:[0-9]+}/events/{id:[0-9]+}", id: "v2GetEvent", tag: "v2", summary: "Получить событие",
+		params: []apiParam{uidPath, idPath, {name: "If-None-Match", in: "header", description: "ETag; при совпадении ответ 304.", schema: stringSchema}},
+		result: ref("Event"), unwrapped: true},
+	{method: "PUT", path: "/api/v2/users/{uid:[0-9]+}/events/{id:[0-9]+}", id: "v2ReplaceEvent", tag: "v2", summary: "Заменить событие",
+		params: []apiParam{uidPath, idPath, ifMatch, occurrence}, body: ref("EventRequest"), result: ref("Event"), unwrapped: true,
+		description: "С occurrence вхождение отделяется от серии, ответ 201."},
+	{method: "PATCH", path: "/api/v2/users/{uid:[0-9]+}/events/{id:[0-9]+}", id: "v2PatchEvent", tag: "v2", summary: "Частично изменить событие",
+		params: []apiParam{uidPath, idPath, ifMatch}, body: ref("EventPatch"), bodyTypes: []string{"application/merge-patch+json", "application/json"},
+		result: ref("Event"), unwrapped: true},
+	{method: "DELETE", path: "/api/v2/users/{uid:[0-9]+}/events/{id:[0-9]+}", id: "v2DeleteEvent", tag: "v2", summary: "Удалить событие или вхождение",
+		params: []apiParam{uidPath, idPath, ifMatch, occurrence, tzQuery}, status: http.StatusNoContent},
+	{method: "POST", path: "/api/v2/users/{uid:[0-9]+}/events/{id:[0-9]+}/rsvp", id: "v2RSVP", tag: "v2", summary: "Ответить на приглашение",
+		params: []apiParam{uidPath, idPath}, body: object([]string{"status"}, map[string]schema{"status": ref("AttendeeStatus")}),
+		result: ref("Event"), unwrapped: true},
+	{method: "GET", path: "/api/v2/users/{uid:[0-9]+}/events/{id:[0-9]+}/history", id: "v2EventHistory", tag: "v2", summary: "История изменений события",
+		params: []apiParam{uidPath, idPath}, result: arrayOf(ref("AuditEntry")), unwrapped: true},
+	{method: "POST", path: "/api/v2/users/{uid:[0-9]+}/events/{id:[0-9]+}/restore", id: "v2RestoreEvent", tag: "v2", summary: "Восстановить удалённое событие",
+		params: []apiParam{uidPath, idPath}, result: ref("Event"), unwrapped: true},
+	{method: "POST", path: "/api/v2/users/{uid:[0-9]+}/events/{id:[0-9]+}/revert", id: "v2RevertEvent", tag: "v2", summary: "Вернуть событие к прежней версии",
+		params: []apiParam{uidPath, idPath}, body: object([]string{"version"}, map[string]schema{"version": integerSchema}),
+		result: ref("Event"), unwrapped: true},
+	{method: "GET", path: "/api/v2/users/{uid:[0-9]+}/invitations", id: "v2Invitations", tag: "v2", summary: "Приглашения пользователя, постранично",
+		params: []apiParam{uidPath, statusQuery, limitQuery, cursorQuery}, result: ref("EventPage"), unwrapped: true},
+	{method: "GET", path: "/api/v2/users/{uid:[0-9]+}/trash", id: "v2Trash", tag: "v2", summary: "Корзина пользователя, недавно удалённые первыми",
+		params: []apiParam{uidPath}, result: arrayOf(ref("TrashedEvent")), unwrapped: true},
+	{method: "GET", path: "/api/v2/users/{uid:[0-9]+}/calendars", id: "v2ListCalendars", tag: "v2", summary: "Свои и открытые пользователю календари",
+		params: []apiParam{uidPath}, result: arrayOf(ref("Calendar")), unwrapped: true},
+	{method: "POST", path: "/api/v2/users/{uid:[0-9]+}/calendars", id: "v2CreateCalendar", tag: "v2", summary: "Создать календарь",
+		params: []apiParam{uidPath}, body: calendarNameBody, status: http.StatusCreated, result: ref("Calendar"), unwrapped: true},
+	{method: "GET", path: "/api/v2/users/{uid:[0-9]+}/calendars/{cid:[0-9]+}", id: "v2GetCalendar", tag: "v2", summary: "Получить календарь",
+		params: []apiParam{uidPath, cidPath}, result: ref("Calendar"), unwrapped: true},
+	{method: "PATCH", path: "/api/v2/users/{uid:[0-9]+}/calendars/{cid:[0-9]+}", id: "v2RenameCalendar", tag: "v2", summary: "Переименовать календарь",
+		params: []apiParam{uidPath, cidPath}, body: calendarNameBody, result: ref("Calendar"), unwrapped: true},
+	{method: "DELETE", path: "/api/v2/users/{uid:[0-9]+}/calendars/{cid:[0-9]+}", id: "v2DeleteCalendar", tag: "v2", summary: "Удалить пустой календарь",
+		params: []apiParam{uidPath, cidPath}, status: http.StatusNoContent},
+	{method: "PUT", path: "/api/v2/users/{uid:[0-9]+}/calendars/{cid:[0-9]+}/shares/{sid:[0-9]+}", id: "v2ShareCalendar", tag: "v2", summary: "Открыть календарь пользователю",
+		params: []apiParam{uidPath, cidPath, pathParam("sid", "Пользователь, которому открывается доступ.")},
+		body:   object([]string{"permission"}, map[string]schema{"permission": enum(string(PermissionRead), string(PermissionWrite))}),
+		result: ref("Calendar"), unwrapped: true},
+	{method: "DELETE", path: "/api/v2/users/{uid:[0-9]+}/calendars/{cid:[0-9]+}/shares/{sid:[0-9]+}", id: "v2UnshareCalendar", tag: "v2", summary: "Закрыть доступ к календарю",
+		params: []apiParam{uidPath, cidPath, pathParam("sid", "Пользователь, у которого забирается доступ.")}, status: http.StatusNoContent},
+}
+
+var calendarNameBody = object([]string{"name"}, map[string]schema{"name": stringSchema})
+
+var apiSchemas = map[string]schema{
+	"Event": object([]string{"id", "user_id", "date", "title", "end", "timezone", "uid", "version"}, map[string]schema{
+		"id":              integerSchema,
+		"user_id":         integerSchema,
+		"date":            dateTimeSchema,
+		"title":           stringSchema,
+		"calendar_id":     integerSchema,
+		"end":             dateTimeSchema,
+		"timezone":        stringSchema,
+		"all_day":         booleanSchema,
+		"description":     stringSchema,
+		"location":        stringSchema,
+		"tags":            arrayOf(stringSchema),
+		"reminders":       arrayOf(ref("Reminder")),
+		"attendees":       arrayOf(ref("Attendee")),
+		"response_status": ref("AttendeeStatus"),
+		"uid":             stringSchema,
+		"version":         integerSchema,
+		"recurrence":      ref("Recurrence"),
+		"series_id":       integerSchema,
+		"recurrence_id":   dateTimeSchema,
+	}),
+	"EventRequest": object(nil, map[string]schema{
+		"user_id":     integerSchema,
+		"id":          integerSchema,
+		"calendar_id": schema{"type": "integer", "description": "Календарь события; без него изменение не переносит событие."},
+		"version":     integerSchema,
+		"date":        schema{"type": "string", "description": "YYYY-MM-DD (весь день), RFC 3339 или YYYY-MM-DDTHH:MM[:SS] в поясе timezone."},
+		"title":       stringSchema,
+		"recurrence":  ref("Recurrence"),
+		"rrule":       schema{"type": "string", "description": "Правило RRULE; указывается вместо recurrence."},
+		"occurrence":  stringSchema,
+		"end":         stringSchema,
+		"duration":    schema{"type": "string", "description": `Длительность вместо end, например "1h30m".`},
+		"timezone":    stringSchema,
+		"reminders":   arrayOf(ref("Reminder")),
+		"uid":         stringSchema,
+		"description": stringSchema,
+		"location":    stringSchema,
+		"tags":        arrayOf(stringSchema),
+		"attendees":   arrayOf(integerSchema),
+		"status":      ref("AttendeeStatus"),
+		"strict":      booleanSchema,
+	}),
+	"EventPatch": object(nil, map[string]schema{
+		"title":       stringSchema,
+		"date":        stringSchema,
+		"end":         stringSchema,
+		"duration":    stringSchema,
+		"timezone":    stringSchema,
+		"recurrence":  schema{"allOf": []schema{ref("Recurrence")}, "nullable": true},
+		"rrule":       stringSchema,
+		"reminders":   arrayOf(ref("Reminder")),
+		"uid":         stringSchema,
+		"attendees":   arrayOf(integerSchema),
+		"calendar_id": integerSchema,
+		"description": stringSchema,
+		"location":    stringSchema,
+		"tags":        arrayOf(stringSchema),
+	}),
+	"Recurrence": object([]string{"freq"}, map[string]schema{
+		"freq":     enum(string(FreqDaily), string(FreqWeekly), string(FreqMonthly), string(FreqYearly)),
+		"interval": integerSchema,
+		"by_day":   arrayOf(stringSchema),
+		"count":    integerSchema,
+		"until":    dateTimeSchema,
+		"ex_dates": arrayOf(dateTimeSchema),
+	}),
+	"Reminder": object([]string{"minutes_before"}, map[string]schema{
+		"minutes_before": integerSchema,
+	}),
+	"Attendee": object([]string{"user_id", "status"}, map[string]schema{
+		"user_id": integerSchema,
+		"status":  ref("AttendeeStatus"),
+	}),
+	"Calendar": object([]string{"id", "owner_id", "name"}, map[string]schema{
+		"id":       integerSchema,
+		"owner_id": integerSchema,
+		"name":     schema{"type": "string", "maxLength": maxCalendarName},
+		"shares":   schema{"type": "array", "items": ref("Share"), "description": "Видны только владельцу."},
+		"access":   enum(string(PermissionOwner), string(PermissionWrite), string(PermissionRead)),
+	}),
+	"Share": object([]string{"user_id", "permission"}, map[string]schema{
+		"user_id":    integerSchema,
+		"permission": enum(string(PermissionRead), string(PermissionWrite)),
+	}),
+	"CalendarRequest": object([]string{"user_id"}, map[string]schema{
+		"user_id":    integerSchema,
+		"id":         integerSchema,
+		"name":       stringSchema,
+		"share_with": integerSchema,
+		"permission": enum(string(PermissionRead), string(PermissionWrite), "none"),
+	}),
+	"AttendeeStatus": enum(string(StatusPending), string(StatusAccepted), string(StatusDeclined), string(StatusTentative)),
+	"EventPage": object([]string{"events"}, map[string]schema{
+		"events":      arrayOf(ref("Event")),
+		"next_cursor": stringSchema,
+	}),
+	"Error": object([]string{"error", "code"}, map[string]schema{
+		"error":   stringSchema,
+		"code":    ref("ErrorCode"),
+		"details": arrayOf(ref("FieldError")),
+		"result":  schema{"description": "Для /events/batch - результаты по операциям."},
+	}),
+	"ErrorCode": enum(string(CodeBadRequest), string(CodeValidation), string(CodeNotFound), string(CodeConflict),
+		string(CodeVersionMismatch), string(CodeUnauthenticated), string(CodeForbidden), string(CodeTooLarge),
+		string(CodeRateLimited), string(CodeChangesExpired), string(CodeUnavailable), string(CodeInternal)),
+	"FieldError": object([]string{"field", "message"}, map[string]schema{
+		"field":   stringSchema,
+		"message": stringSchema,
+	}),
+	"TrashedEvent": object([]string{"event", "deleted_at", "deleted_by"}, map[string]schema{
+		"event":      ref("Event"),
+		"deleted_at": dateTimeSchema,
+		"deleted_by": integerSchema,
+		"purge_at":   schema{"type": "string", "format": "date-time", "description": "Когда событие будет удалено окончательно; нет, если срок хранения не ограничен."},
+	}),
+	"AuditEntry": object([]string{"seq", "action", "event_id", "user_id", "actor", "at"}, map[string]schema{
+		"seq":      integerSchema,
+		"action":   enum(string(AuditCreated), string(AuditUpdated), string(AuditDeleted), string(AuditRestored), string(AuditReverted)),
+		"event_id": integerSchema,
+		"user_id":  integerSchema,
+		"actor":    integerSchema,
+		"at":       dateTimeSchema,
+		"before":   ref("Event"),
+		"after":    ref("Event"),
+	}),
+	"SearchResult": object([]string{"event", "score"}, map[string]schema{
+		"event": ref("Event"),
+		"score": numberSchema,
+	}),
+	"Interval": object([]string{"start", "end"}, map[string]schema{
+		"start": dateTimeSchema,
+		"end":   dateTimeSchema,
+	}),
+	"FreeBusy": object([]string{"user_ids", "from", "to", "busy"}, map[string]schema{
+		"user_ids": arrayOf(integerSchema),
+		"from":     dateTimeSchema,
+		"to":       dateTimeSchema,
+		"busy":     arrayOf(ref("Interval")),
+	}),
+	"BatchRequest": object([]string{"operations"}, map[string]schema{
+		"operations": schema{"type": "array", "maxItems": maxBatchSize, "items": ref("BatchOperation")},
+	}),
+	"BatchOperation": schema{"allOf": []schema{
+		object([]string{"op"}, map[string]schema{"op": enum(BatchCreate, BatchUpdate, BatchDelete)}),
+		ref("EventRequest"),
+	}},
+	"BatchItemResult": object([]string{"index", "op", "status"}, map[string]schema{
+		"index":   integerSchema,
+		"op":      stringSchema,
+		"status":  enum(batchApplied, batchFailed, batchRolledBack, batchSkipped),
+		"event":   ref("Event"),
+		"error":   stringSchema,
+		"code":    ref("ErrorCode"),
+		"details": arrayOf(ref("FieldError")),
+	}),
+	"ImportResult": object([]string{"created", "updated", "events"}, map[string]schema{
+		"created": integerSchema,
+		"updated": integerSchema,
+		"skipped": arrayOf(stringSchema),
+		"events":  arrayOf(ref("Event")),
+	}),
+	"Change": object([]string{"seq", "epoch", "type", "user_id", "event_id", "event", "at"}, map[string]schema{
+		"seq":      integerSchema,
+		"epoch":    stringSchema,
+		"type":     enum(string(ChangeCreated), string(ChangeUpdated), string(ChangeDeleted)),
+		"user_id":  integerSchema,
+		"event_id": integerSchema,
+		"event":    ref("Event"),
+		"at":       dateTimeSchema,
+	}),
+	"StreamMessage": object([]string{"type"}, map[string]schema{
+		"type":   enum("change", "error"),
+		"change": ref("Change"),
+		"error":  stringSchema,
+	}),
+	"Health": object([]string{"status"}, map[string]schema{
+		"status": stringSchema,
+		"error":  stringSchema,
+	}),
+}
+
+// muxVarPattern убирает из шаблона mux регулярные выражения переменных:
+// {uid:[0-9]+} -> {uid}.
+var muxVarPattern = regexp.MustCompile(`\{([a-z_]+):[^}]+\}`)
+
+func openAPIPath(muxPath string) string {
+	return muxVarPattern.ReplaceAllString(muxPath, "{$1}")
+}
+
+func (op apiOperation) document() schema {
+	doc := schema{"operationId": op.id, "summary": op.summary, "tags": []string{op.tag}}
+	if op.description != "" {
+		doc["description"] = op.description
+	}
+
+	var params []schema
+	for _, p := range op.params {
+		param := schema{"name": p.name, "in": p.in, "schema": p.schema}
+		if p.description != "" {
+			param["description"] = p.description
+		}
+		if p.required {
+			param["required"] = true
+		}
+		params = append(params, param)
+	}
+	if params != nil {
+		doc["parameters"] = params
+	}
+
+	if op.body != nil {
+		types := op.bodyTypes
+		if types == nil {
+			types = []string{"application/json"}
+		}
+		content := schema{}
+		for _, typ := range types {
+			content[typ] = schema{"schema": op.body}
+		}
+		doc["requestBody"] = schema{"required": true, "content": content}
+	}
+
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := schema{"description": http.StatusText(status)}
+	if op.result != nil {
+		result := op.result
+		if !op.unwrapped {
+			result = object([]string{"result"}, map[string]schema{"result": op.result})
+		}
+		typ := op.resultType
+		if typ == "" {
+			typ = "application/json"
+		}
+		success["content"] = schema{typ: schema{"schema": result}}
+	}
+
+	doc["responses"] = schema{
+		strconv.Itoa(status): success,
+		"default": schema{
+			"description": "Ошибка",
+			"content":     schema{"application/json": schema{"schema": ref("Error")}},
+		},
+	}
+	return doc
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIJSON []byte
+)
+
+// openAPIDocument собирает документ OpenAPI 3.0 из apiOperations и apiSchemas.
+func openAPIDocument() []byte {
+	openAPIOnce.Do(func() {
+		paths := schema{}
+		for _, op := range apiOperations {
+			path := openAPIPath(op.path)
+			item, ok := paths[path].(schema)
+			if !ok {
+				item = schema{}
+				paths[path] = item
+			}
+			item[strings.ToLower(op.method)] = op.document()
+		}
+
+		doc := schema{
+			"openapi": "3.0.3",
+			"info": schema{
+				"title":       "L2_18 calendar",
+				"version":     "2.0.0",
+				"description": "HTTP API календаря. Ответы v1 завёрнуты в {\"result\": ...}, v2 (/api/v2) отдаёт ресурсы как есть и поддерживает ETag.",
+			},
+			"paths": paths,
+			"components": schema{
+				"schemas": apiSchemas,
+				"securitySchemes": schema{
+					"bearerAuth": schema{"type": "http", "scheme": "bearer", "description": "API-ключ или JWT; нужен, если сервер запущен с -auth."},
+				},
+			},
+			// аутентификация включается флагом, поэтому допустимы оба варианта
+			"security": []schema{{}, {"bearerAuth": []string{}}},
+		}
+
+		var err error
+		if openAPIJSON, err = json.MarshalIndent(doc, "", "  "); err != nil {
+			panic(err)
+		}
+	})
+	return openAPIJSON
+}
+
+func (h *Handler) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument())
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// jsonFields возвращает имена JSON-полей структуры, включая встроенные.
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			fields = append(fields, jsonFields(f.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func schemaFields(name string) []string {
+	var fields []string
+	for field := range apiSchemas[name]["properties"].(map[string]schema) {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestOpenAPI_MatchesRoutes(t *testing.T) {
+	r := mux.NewRouter()
+	NewHandler(NewCalendar()).RegisterRoutes(r)
+	// /metrics регистрирует main
+	r.Handle("/metrics", NewMetrics(NewCalendar())).Methods("GET")
+
+	registered := make(map[string]bool)
+	r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			registered[method+" "+path] = true
+		}
+		return nil
+	})
+
+	documented := make(map[string]bool)
+	for _, op := range apiOperations {
+		key := op.method + " " + op.path
+		if documented[key] {
+			t.Errorf("%s is documented twice", key)
+		}
+		documented[key] = true
+		if !registered[key] {
+			t.Errorf("%s is documented but not registered", key)
+		}
+	}
+	for key := range registered {
+		if !documented[key] {
+			t.Errorf("%s is registered but missing from the OpenAPI document", key)
+		}
+	}
+}
+
+func TestOpenAPI_SchemasMatchTypes(t *testing.T) {
+	for name, typ := range map[string]reflect.Type{
+		"Event":           reflect.TypeOf(Event{}),
+		"EventRequest":    reflect.TypeOf(eventRequest{}),
+		"EventPatch":      reflect.TypeOf(eventPatch{}),
+		"Recurrence":      reflect.TypeOf(Recurrence{}),
+		"Attendee":        reflect.TypeOf(Attendee{}),
+		"Calendar":        reflect.TypeOf(UserCalendar{}),
+		"TrashedEvent":    reflect.TypeOf(TrashedEvent{}),
+		"Share":           reflect.TypeOf(Share{}),
+		"CalendarRequest": reflect.TypeOf(calendarRequest{}),
+		"EventPage":       reflect.TypeOf(eventPage{}),
+		"AuditEntry":      reflect.TypeOf(AuditEntry{}),
+		"SearchResult":    reflect.TypeOf(SearchResult{}),
+		"FreeBusy":        reflect.TypeOf(freeBusyResult{}),
+		"BatchItemResult": reflect.TypeOf(batchItemResult{}),
+		"ImportResult":    reflect.TypeOf(importResult{}),
+		"Change":          reflect.TypeOf(Change{}),
+		"Error":           reflect.TypeOf(response{}),
+	} {
+		if got, want := schemaFields(name), jsonFields(typ); !reflect.DeepEqual(got, want) {
+			t.Errorf("Schema %s has fields %v, type %v has %v", name, got, typ, want)
+		}
+	}
+}
+
+func TestOpenAPI_Served(t *testing.T) {
+	r := mux.NewRouter()
+	NewHandler(NewCalendar()).RegisterRoutes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+
+	var doc struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if doc.OpenAPI != "3.0.3" {
+		t.Errorf("Expected OpenAPI 3.0.3, got %q", doc.OpenAPI)
+	}
+	if _, ok := doc.Paths["/api/v2/users/{uid}/events/{id}"]; !ok {
+		t.Error("Expected mux patterns to be stripped from paths")
+	}
+
+	// все $ref должны указывать на существующие схемы
+	for _, match := range strings.Split(rec.Body.String(), `"$ref": "#/components/schemas/`)[1:] {
+		name, _, _ := strings.Cut(match, `"`)
+		if _, ok := apiSchemas[name]; !ok {
+			t.Errorf("Dangling $ref to %s", name)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+	// maxRangeQuery ограничивает окно /events: серии без COUNT/UNTIL бесконечны
+	maxRangeQuery = 366 * 24 * time.Hour
+)
+
+var ErrCursorInvalid = errors.New("invalid cursor")
+
+// eventCursor указывает на последнее отданное событие страницы. Ключ (Date, ID)
+// уникален и для вхождений серий, у которых ID общий.
+type eventCursor struct {
+	date time.Time
+	id   int
+}
+
+func (c eventCursor) String() string {
+	raw := strconv.FormatInt(c.date.UnixNano(), 10) + ":" + strconv.Itoa(c.id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseCursor(s string) (*eventCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrCursorInvalid
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrCursorInvalid
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrCursorInvalid
+	}
+	eventID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, ErrCursorInvalid
+	}
+	return &eventCursor{date: time.Unix(0, n), id: eventID}, nil
+}
+
+func (c eventCursor) before(e Event) bool {
+	if c.date.Equal(e.Date) {
+		return c.id < e.ID
+	}
+	return c.date.Before(e.Date)
+}
+
+type eventPage struct {
+	Events     []Event `json:"events"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// paginate отдаёт до limit событий после cursor; events должны быть
+// упорядочены по (Date, ID).
+func paginate(events []Event, cursor *eventCursor, limit int) eventPage {
+	start := 0
+	if cursor != nil {
+		start = sort.Search(len(events), func(i int) bool {
+			return cursor.before(events[i])
+		})
+	}
+
+	end := start + limit
+	if end > len(events) {
+		end = len(events)
+	}
+
+	page := eventPage{Events: events[start:end]}
+	if page.Events == nil {
+		page.Events = []Event{}
+	}
+	if end < len(events) {
+		last := events[end-1]
+		page.NextCursor = eventCursor{date: last.Date, id: last.ID}.String()
+	}
+	return page
+}
+
+type rangeQuery struct {
+	userID int
+	from   time.Time
+	to     time.Time
+	limit  int
+	cursor *eventCursor
+	// calendars - фильтр calendar_id, пустой - все календари
+	calendars []int
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCalendar_GetEventsInRange(t *testing.T) {
+	calendar := NewCalendar()
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(1, start, "Daily", WithDuration(time.Hour), WithRecurrence(&Recurrence{Freq: FreqDaily}))
+	calendar.CreateEvent(1, start.Add(30*time.Minute), "Overlap", WithDuration(time.Hour))
+	calendar.CreateEvent(1, start.AddDate(0, 0, 10), "Later")
+	calendar.CreateEvent(2, start, "Other user")
+
+	events := calendar.GetEventsInRange(1, start.Add(time.Minute), start.AddDate(0, 0, 3))
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %d", len(events))
+	}
+	if events[0].Title != "Daily" || events[1].Title != "Overlap" {
+		t.Errorf("Expected overlapping events first, got %s and %s", events[0].Title, events[1].Title)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	calendar := NewCalendar()
+
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		calendar.CreateEvent(1, date, "Same time")
+	}
+	calendar.CreateEvent(1, date, "Daily", WithRecurrence(&Recurrence{Freq: FreqDaily, Count: 3}))
+
+	events := calendar.GetEventsInRange(1, date, date.AddDate(0, 0, 7))
+	if len(events) != 8 {
+		t.Fatalf("Expected 8 events, got %d", len(events))
+	}
+
+	var (
+		seen   []Event
+		cursor *eventCursor
+	)
+	for pages := 0; pages < 10; pages++ {
+		page := paginate(events, cursor, 3)
+		seen = append(seen, page.Events...)
+		if page.NextCursor == "" {
+			break
+		}
+		var err error
+		if cursor, err = parseCursor(page.NextCursor); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if len(seen) != len(events) {
+		t.Fatalf("Expected %d events across pages, got %d", len(events), len(seen))
+	}
+	for i := range seen {
+		if seen[i].ID != events[i].ID || !seen[i].Date.Equal(events[i].Date) {
+			t.Errorf("Page order mismatch at %d", i)
+		}
+	}
+
+	if _, err := parseCursor("not a cursor"); err != ErrCursorInvalid {
+		t.Errorf("Expected ErrCursorInvalid, got %v", err)
+	}
+}
+
+func TestHandler_EventsInRange(t *testing.T) {
+	calendar := NewCalendar()
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		calendar.CreateEvent(1, date.AddDate(0, 0, i), "Event")
+	}
+
+	router := mux.NewRouter()
+	NewHandler(calendar).RegisterRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/events?user_id=1&from=2024-01-01&limit=2", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Result eventPage `json:"result"`
+	}
+	json.NewDecoder(rec.Body).Decode(&body)
+	if len(body.Result.Events) != 2 || body.Result.NextCursor == "" {
+		t.Errorf("Expected 2 events and a cursor, got %+v", body.Result)
+	}
+
+	badRequests := []string{
+		"/events?user_id=x&from=2024-01-01",
+		"/events?user_id=1",
+		"/events?user_id=1&from=2024-01-02&to=2024-01-01",
+		"/events?user_id=1&from=2024-01-01&to=2026-01-01",
+		"/events?user_id=1&from=2024-01-01&limit=0",
+		"/events?user_id=1&from=2024-01-01&cursor=bm9wZQ",
+	}
+	for _, url := range badRequests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	FreqDaily   Frequency = "DAILY"
+	FreqWeekly  Frequency = "WEEKLY"
+	FreqMonthly Frequency = "MONTHLY"
+	FreqYearly  Frequency = "YEARLY"
+
+	// защита от бесконечной генерации для правил без COUNT/UNTIL
+	maxRecurrenceSteps = 100000
+	// COUNT разворачивается от начала серии, поэтому ограничен так, чтобы
+	// любое правило укладывалось в maxRecurrenceSteps периодов
+	maxRecurrenceCount = 10000
+)
+
+var (
+	ErrRecurrenceInvalid  = errors.New("invalid recurrence rule")
+	ErrNotRecurring       = errors.New("event is not recurring")
+	ErrOccurrenceNotFound = errors.New("occurrence not found")
+	ErrRecurrenceLimit    = errors.New("recurrence expansion limit exceeded")
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Recurrence - подмножество RRULE из RFC 5545: FREQ, INTERVAL, BYDAY, COUNT,
+// UNTIL и список исключённых дат EXDATE. BYDAY принимает порядковый префикс
+// ("2TU", "-1FR") только для MONTHLY, для YEARLY не поддерживается.
+type Recurrence struct {
+	Freq     Frequency   `json:"freq"`
+	Interval int         `json:"interval,omitempty"`
+	ByDay    []string    `json:"by_day,omitempty"`
+	Count    int         `json:"count,omitempty"`
+	Until    *time.Time  `json:"until,omitempty"`
+	ExDates  []time.Time `json:"ex_dates,omitempty"`
+}
+
+type byDayRule struct {
+	ordinal int
+	weekday time.Weekday
+}
+
+func ParseRRule(s string) (*Recurrence, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	r := &Recurrence{}
+
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrRecurrenceInvalid, part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = Frequency(strings.ToUpper(value))
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad INTERVAL %q", ErrRecurrenceInvalid, value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad COUNT %q", ErrRecurrenceInvalid, value)
+			}
+			r.Count = n
+		case "UNTIL":
+			until, err := parseRRuleTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad UNTIL %q", ErrRecurrenceInvalid, value)
+			}
+			r.Until = &until
+		case "BYDAY":
+			r.ByDay = strings.Split(strings.ToUpper(value), ",")
+		case "WKST":
+			if strings.ToUpper(value) != "MO" {
+				return nil, fmt.Errorf("%w: only WKST=MO is supported", ErrRecurrenceInvalid)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unsupported part %q", ErrRecurrenceInvalid, key)
+		}
+	}
+
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func parseRRuleTime(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
+
+// String возвращает правило в формате RRULE без EXDATE.
+func (r *Recurrence) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		parts = append(parts, "BYDAY="+strings.Join(r.ByDay, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+func (r *Recurrence) validate() error {
+	switch r.Freq {
+	case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+	default:
+		return fmt.Errorf("%w: unknown FREQ %q", ErrRecurrenceInvalid, r.Freq)
+	}
+	if r.Interval < 0 {
+		return fmt.Errorf("%w: INTERVAL must be positive", ErrRecurrenceInvalid)
+	}
+	if r.Count < 0 {
+		return fmt.Errorf("%w: COUNT must be positive", ErrRecurrenceInvalid)
+	}
+	if r.Count > maxRecurrenceCount {
+		return fmt.Errorf("%w: COUNT must not exceed %d", ErrRecurrenceInvalid, maxRecurrenceCount)
+	}
+	if r.Count > 0 && r.Until != nil {
+		return fmt.Errorf("%w: COUNT and UNTIL are mutually exclusive", ErrRecurrenceInvalid)
+	}
+	if len(r.ByDay) > 0 && r.Freq == FreqYearly {
+		return fmt.Errorf("%w: BYDAY is not supported for YEARLY", ErrRecurrenceInvalid)
+	}
+	_, err := r.byDay()
+	return err
+}
+
+func (r *Recurrence) byDay() ([]byDayRule, error) {
+	rules := make([]byDayRule, 0, len(r.ByDay))
+	for _, code := range r.ByDay {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if len(code) < 2 {
+			return nil, fmt.Errorf("%w: bad BYDAY %q", ErrRecurrenceInvalid, code)
+		}
+
+		weekday, ok := weekdayCodes[code[len(code)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("%w: bad BYDAY %q", ErrRecurrenceInvalid, code)
+		}
+
+		rule := byDayRule{weekday: weekday}
+		if prefix := code[:len(code)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("%w: bad BYDAY %q", ErrRecurrenceInvalid, code)
+			}
+			if r.Freq != FreqMonthly {
+				return nil, fmt.Errorf("%w: ordinal BYDAY is only supported for MONTHLY", ErrRecurrenceInvalid)
+			}
+			rule.ordinal = n
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (r *Recurrence) interval() int {
+	if r.Interval < 1 {
+		return 1
+	}
+	return r.Interval
+}
+
+func (r *Recurrence) isExcluded(t time.Time) bool {
+	for _, ex := range r.ExDates {
+		if ex.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Occurrences разворачивает правило, начиная с start, и возвращает вхождения
+// из полуинтервала [from, to). COUNT считается от start с учётом исключённых
+// дат, как того требует RFC 5545. Правила без COUNT разворачиваются сразу с
+// периода, содержащего from. Если за maxRecurrenceSteps периодов интервал не
+// исчерпан, возвращается найденное и ErrRecurrenceLimit.
+func (r *Recurrence) Occurrences(start, from, to time.Time) ([]time.Time, error) {
+	rules, err := r.byDay()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []time.Time
+	count := 0
+
+	first := r.skipTo(start, from)
+	for step := first; step < first+maxRecurrenceSteps; step++ {
+		candidates := r.period(start, step, rules)
+		for _, t := range candidates {
+			if t.Before(start) {
+				continue
+			}
+			if !t.Before(to) {
+				return result, nil
+			}
+			if r.Until != nil && t.After(*r.Until) {
+				return result, nil
+			}
+			if r.Count > 0 && count >= r.Count {
+				return result, nil
+			}
+			count++
+
+			if !t.Before(from) && !r.isExcluded(t) {
+				result = append(result, t)
+			}
+		}
+	}
+	return result, fmt.Errorf("%w: more than %d periods", ErrRecurrenceLimit, maxRecurrenceSteps)
+}
+
+// skipTo возвращает номер периода, с которого стоит начинать разворачивание.
+// Для COUNT нужны все периоды от start; иначе берётся период на один раньше
+// содержащего from, потому что кандидаты недели или месяца могут лежать до from.
+func (r *Recurrence) skipTo(start, from time.Time) int {
+	if r.Count > 0 || !from.After(start) {
+		return 0
+	}
+
+	y1, m1, d1 := start.Date()
+	y2, m2, d2 := from.In(start.Location()).Date()
+	// разница в календарных днях не зависит от перехода на летнее время;
+	// Unix вместо Sub, чтобы не упереться в предел time.Duration
+	days := int((time.Date(y2, m2, d2, 0, 0, 0, 0, time.UTC).Unix() - time.Date(y1, m1, d1, 0, 0, 0, 0, time.UTC).Unix()) / 86400)
+
+	var periods int
+	switch r.Freq {
+	case FreqDaily:
+		periods = days
+	case FreqWeekly:
+		periods = days / 7
+	case FreqMonthly:
+		periods = (y2-y1)*12 + int(m2) - int(m1)
+	case FreqYearly:
+		periods = y2 - y1
+	}
+	if step := periods/r.interval() - 1; step > 0 {
+		return step
+	}
+	return 0
+}
+
+// period возвращает упорядоченные кандидаты step-го периода правила.
+func (r *Recurrence) period(start time.Time, step int, rules []byDayRule) []time.Time {
+	n := step * r.interval()
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	ns, loc := start.Nanosecond(), start.Location()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hh, mm, ss, ns, loc)
+	}
+
+	var candidates []time.Time
+	switch r.Freq {
+	case FreqDaily:
+		t := at(y, m, d+n)
+		if len(rules) == 0 || matchesWeekday(t.Weekday(), rules) {
+			candidates = append(candidates, t)
+		}
+
+	case FreqWeekly:
+		offset := (int(start.Weekday()) + 6) % 7
+		monday := at(y, m, d-offset+7*n)
+		if len(rules) == 0 {
+			candidates = append(candidates, at(y, m, d+7*n))
+			break
+		}
+		for i := 0; i < 7; i++ {
+			t := monday.AddDate(0, 0, i)
+			if matchesWeekday(t.Weekday(), rules) {
+				candidates = append(candidates, t)
+			}
+		}
+
+	case FreqMonthly:
+		first := at(y, m+time.Month(n), 1)
+		if len(rules) == 0 {
+			if t := at(first.Year(), first.Month(), d); t.Month() == first.Month() {
+				candidates = append(candidates, t)
+			}
+			break
+		}
+		candidates = monthlyByDay(first, rules)
+
+	case FreqYearly:
+		if t := at(y+n, m, d); t.Month() == m {
+			candidates = append(candidates, t)
+		}
+	}
+	return candidates
+}
+
+func matchesWeekday(weekday time.Weekday, rules []byDayRule) bool {
+	for _, rule := range rules {
+		if rule.weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func monthlyByDay(first time.Time, rules []byDayRule) []time.Time {
+	var days []time.Time
+	for t := first; t.Month() == first.Month(); t = t.AddDate(0, 0, 1) {
+		days = append(days, t)
+	}
+
+	seen := make(map[int]bool)
+	var result []time.Time
+	for _, rule := range rules {
+		var matching []time.Time
+		for _, t := range days {
+			if t.Weekday() == rule.weekday {
+				matching = append(matching, t)
+			}
+		}
+
+		switch {
+		case rule.ordinal == 0:
+			for _, t := range matching {
+				if !seen[t.Day()] {
+					seen[t.Day()] = true
+					result = append(result, t)
+				}
+			}
+		case rule.ordinal > 0 && rule.ordinal <= len(matching):
+			t := matching[rule.ordinal-1]
+			if !seen[t.Day()] {
+				seen[t.Day()] = true
+				result = append(result, t)
+			}
+		case rule.ordinal < 0 && -rule.ordinal <= len(matching):
+			t := matching[len(matching)+rule.ordinal]
+			if !seen[t.Day()] {
+				seen[t.Day()] = true
+				result = append(result, t)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Before(result[j])
+	})
+	return result
+}
+
+func (r *Recurrence) clone() *Recurrence {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	c.ByDay = append([]string(nil), r.ByDay...)
+	c.ExDates = append([]time.Time(nil), r.ExDates...)
+	if r.Until != nil {
+		until := *r.Until
+		c.Until = &until
+	}
+	return &c
+}
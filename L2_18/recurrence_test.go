@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRRule(t *testing.T) {
+	r, err := ParseRRule("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if r.Freq != FreqWeekly || r.Interval != 2 || r.Count != 10 || len(r.ByDay) != 2 {
+		t.Errorf("Unexpected rule %+v", r)
+	}
+	if r.String() != "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10" {
+		t.Errorf("Unexpected string %s", r.String())
+	}
+
+	invalid := []string{
+		"FREQ=HOURLY",
+		"FREQ=DAILY;COUNT=3;UNTIL=20240101",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=YEARLY;BYDAY=MO",
+		"FREQ=DAILY;BYSETPOS=1",
+	}
+	for _, s := range invalid {
+		if _, err := ParseRRule(s); !errors.Is(err, ErrRecurrenceInvalid) {
+			t.Errorf("%s: expected ErrRecurrenceInvalid, got %v", s, err)
+		}
+	}
+}
+
+func TestRecurrence_Occurrences(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC) // Понедельник
+	from := start
+	to := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	weekly, _ := ParseRRule("FREQ=WEEKLY;BYDAY=MO,FR;COUNT=5")
+	got, _ := weekly.Occurrences(start, from, to)
+	want := []int{1, 5, 8, 12, 15}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d occurrences, got %d", len(want), len(got))
+	}
+	for i, day := range want {
+		if got[i].Day() != day || got[i].Hour() != 9 {
+			t.Errorf("Occurrence %d: expected Jan %d 09:00, got %v", i, day, got[i])
+		}
+	}
+
+	lastFriday, _ := ParseRRule("FREQ=MONTHLY;BYDAY=-1FR")
+	got, _ = lastFriday.Occurrences(start, from, to)
+	if len(got) != 2 || got[0].Day() != 26 || got[1].Day() != 23 {
+		t.Errorf("Expected Jan 26 and Feb 23, got %v", got)
+	}
+
+	monthly := &Recurrence{Freq: FreqMonthly}
+	got, _ = monthly.Occurrences(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), from, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	if len(got) != 3 {
+		t.Errorf("Expected months without 31st to be skipped, got %v", got)
+	}
+
+	daily := &Recurrence{Freq: FreqDaily, Count: 3, ExDates: []time.Time{start.AddDate(0, 0, 1)}}
+	got, _ = daily.Occurrences(start, from, to)
+	if len(got) != 2 || got[1].Day() != 3 {
+		t.Errorf("Expected Jan 1 and Jan 3, got %v", got)
+	}
+}
+
+func TestRecurrence_OccurrencesFarFromStart(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	from := time.Date(2600, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+
+	// без COUNT разворачивание начинается с периода from, а не с 2024 года
+	rules := map[string]int{
+		"FREQ=DAILY":                      7,
+		"FREQ=DAILY;INTERVAL=3":           2,
+		"FREQ=WEEKLY;BYDAY=MO,FR":         2,
+		"FREQ=MONTHLY;BYDAY=1MO":          1,
+		"FREQ=YEARLY;INTERVAL=4":          0,
+		"FREQ=MONTHLY;INTERVAL=1000":      0,
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=SU": 1,
+	}
+	for rule, want := range rules {
+		r, _ := ParseRRule(rule)
+		got, err := r.Occurrences(start, from, to)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", rule, err)
+		}
+		if len(got) != want {
+			t.Errorf("%s: expected %d occurrences, got %v", rule, want, got)
+		}
+	}
+
+	if _, err := ParseRRule("FREQ=DAILY;COUNT=10001"); !errors.Is(err, ErrRecurrenceInvalid) {
+		t.Errorf("Expected COUNT above the limit to be rejected, got %v", err)
+	}
+	huge := &Recurrence{Freq: FreqDaily, Count: maxRecurrenceSteps * 2}
+	if _, err := huge.Occurrences(start, from, to); !errors.Is(err, ErrRecurrenceLimit) {
+		t.Errorf("Expected ErrRecurrenceLimit, got %v", err)
+	}
+}
+
+func TestCalendar_RecurringEventExpansion(t *testing.T) {
+	calendar := NewCalendar()
+
+	start := time.Date(2023, 12, 4, 0, 0, 0, 0, time.UTC) // Понедельник
+	standup, _ := ParseRRule("FREQ=WEEKLY;BYDAY=MO,WE")
+	calendar.CreateEvent(1, start, "Standup", WithRecurrence(standup))
+
+	if events := calendar.GetEventsForDay(1, time.Date(2023, 12, 20, 0, 0, 0, 0, time.UTC)); len(events) != 1 {
+		t.Errorf("Expected 1 event, got %d", len(events))
+	}
+	if events := calendar.GetEventsForWeek(1, time.Date(2023, 12, 13, 0, 0, 0, 0, time.UTC)); len(events) != 2 {
+		t.Errorf("Expected 2 events, got %d", len(events))
+	}
+	events := calendar.GetEventsForMonth(1, start)
+	if len(events) != 8 {
+		t.Fatalf("Expected 8 events, got %d", len(events))
+	}
+	if events[0].RecurrenceID == nil || !events[0].RecurrenceID.Equal(start) {
+		t.Errorf("Expected recurrence id %v, got %v", start, events[0].RecurrenceID)
+	}
+	if events := calendar.GetEventsForMonth(1, time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)); len(events) != 0 {
+		t.Errorf("Expected no events before series start, got %d", len(events))
+	}
+}
+
+func TestCalendar_OccurrenceEditAndDelete(t *testing.T) {
+	calendar := NewCalendar()
+
+	start := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	series, _ := calendar.CreateEvent(1, start, "Daily", WithRecurrence(&Recurrence{Freq: FreqDaily, Count: 5}))
+
+	second := start.AddDate(0, 0, 1)
+	moved := time.Date(2023, 12, 10, 0, 0, 0, 0, time.UTC)
+	detached, err := calendar.UpdateOccurrence(series.ID, 1, second, moved, "Moved")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if detached.SeriesID != series.ID || detached.Recurrence != nil {
+		t.Errorf("Expected detached event of series %d, got %+v", series.ID, detached)
+	}
+
+	if err := calendar.DeleteOccurrence(series.ID, 1, start.AddDate(0, 0, 2)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	events := calendar.GetEventsForMonth(1, start)
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %d", len(events))
+	}
+	if events[3].Title != "Moved" {
+		t.Errorf("Expected moved occurrence last, got %s", events[3].Title)
+	}
+
+	if err := calendar.DeleteOccurrence(series.ID, 1, second); err != ErrOccurrenceNotFound {
+		t.Errorf("Expected ErrOccurrenceNotFound, got %v", err)
+	}
+	if err := calendar.DeleteOccurrence(detached.ID, 1, moved); err != ErrNotRecurring {
+		t.Errorf("Expected ErrNotRecurring, got %v", err)
+	}
+
+	updated, _ := calendar.UpdateEvent(series.ID, 1, start, "Daily renamed", WithRecurrence(&Recurrence{Freq: FreqDaily, Count: 5}))
+	if len(updated.Recurrence.ExDates) != 2 {
+		t.Errorf("Expected exception dates to survive series update, got %v", updated.Recurrence.ExDates)
+	}
+
+	if err := calendar.DeleteEvent(series.ID, 1); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if events := calendar.GetEventsForMonth(1, start); len(events) != 0 {
+		t.Errorf("Expected series and detached occurrences deleted, got %d", len(events))
+	}
+}
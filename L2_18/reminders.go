@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// maxReminderLead ограничивает, насколько заранее можно напомнить о событии,
+// чтобы планировщику хватало окна в неделю.
+const maxReminderLead = 7 * 24 * time.Hour
+
+var ErrReminderInvalid = errors.New("invalid reminder")
+
+type Reminder struct {
+	MinutesBefore int `json:"minutes_before"`
+}
+
+func (r Reminder) lead() time.Duration {
+	return time.Duration(r.MinutesBefore) * time.Minute
+}
+
+func WithReminders(reminders ...Reminder) EventOption {
+	return func(e *Event) {
+		e.Reminders = append([]Reminder(nil), reminders...)
+	}
+}
+
+func validateReminders(reminders []Reminder) error {
+	for _, r := range reminders {
+		if r.MinutesBefore < 0 || r.lead() > maxReminderLead {
+			return fmt.Errorf("%w: minutes_before must be between 0 and %d", ErrReminderInvalid, int(maxReminderLead.Minutes()))
+		}
+	}
+	return nil
+}
+
+type Notification struct {
+	EventID       int        `json:"event_id"`
+	UserID        int        `json:"user_id"`
+	Title         string     `json:"title"`
+	Start         time.Time  `json:"start"`
+	End           time.Time  `json:"end"`
+	RecurrenceID  *time.Time `json:"recurrence_id,omitempty"`
+	MinutesBefore int        `json:"minutes_before"`
+	RemindAt      time.Time  `json:"remind_at"`
+}
+
+// DueReminders возвращает напоминания, время срабатывания которых попадает в
+// (from, to], по всем пользователям и с учётом вхождений серий.
+func (c *Calendar) DueReminders(from, to time.Time) []Notification {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var result []Notification
+	for _, user := range c.users {
+		for _, event := range user.between(from, to.Add(maxReminderLead)) {
+			for _, r := range event.Reminders {
+				remindAt := event.Date.Add(-r.lead())
+				if !remindAt.After(from) || remindAt.After(to) {
+					continue
+				}
+				result = append(result, Notification{
+					EventID:       event.ID,
+					UserID:        event.UserID,
+					Title:         event.Title,
+					Start:         event.Date,
+					End:           event.End,
+					RecurrenceID:  event.RecurrenceID,
+					MinutesBefore: r.MinutesBefore,
+					RemindAt:      remindAt,
+				})
+			}
+		}
+	}
+	return result
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+type LogNotifier struct {
+	logger *slog.Logger
+}
+
+func NewLogNotifier(logger *slog.Logger) *LogNotifier {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.logger.InfoContext(ctx, "reminder",
+		"event_id", notification.EventID,
+		"user_id", notification.UserID,
+		"title", notification.Title,
+		"start", notification.Start.Format(time.RFC3339),
+		"minutes_before", notification.MinutesBefore)
+	return nil
+}
+
+// WebhookNotifier отправляет напоминание POST-запросом с JSON-телом Notification.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string, client *http.Client) *WebhookNotifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &WebhookNotifier{url: url, client: client}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// ReminderScheduler раз в interval отправляет напоминания, наступившие с
+// прошлой проверки. Напоминания, пропущенные пока сервер не работал, не шлются.
+type ReminderScheduler struct {
+	calendar *Calendar
+	notifier Notifier
+	interval time.Duration
+	last     time.Time
+	now      func() time.Time
+}
+
+func NewReminderScheduler(calendar *Calendar, notifier Notifier, interval time.Duration) *ReminderScheduler {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &ReminderScheduler{
+		calendar: calendar,
+		notifier: notifier,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	s.last = s.now()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx, s.now())
+		}
+	}
+}
+
+func (s *ReminderScheduler) tick(ctx context.Context, now time.Time) {
+	for _, n := range s.calendar.DueReminders(s.last, now) {
+		if err := s.notifier.Notify(ctx, n); err != nil {
+			slog.ErrorContext(ctx, "reminder failed", "event_id", n.EventID, "user_id", n.UserID, "error", err)
+		}
+	}
+	s.last = now
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+func TestCalendar_DueReminders(t *testing.T) {
+	calendar := NewCalendar()
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(1, start, "Meeting", WithReminders(Reminder{MinutesBefore: 15}, Reminder{MinutesBefore: 60}))
+	calendar.CreateEvent(2, start, "Daily", WithReminders(Reminder{MinutesBefore: 15}),
+		WithRecurrence(&Recurrence{Freq: FreqDaily}))
+	calendar.CreateEvent(1, start, "Silent")
+
+	due := calendar.DueReminders(start.Add(-20*time.Minute), start.Add(-10*time.Minute))
+	if len(due) != 2 {
+		t.Fatalf("Expected 2 reminders, got %d", len(due))
+	}
+	for _, n := range due {
+		if n.MinutesBefore != 15 || !n.RemindAt.Equal(start.Add(-15*time.Minute)) {
+			t.Errorf("Unexpected reminder %+v", n)
+		}
+	}
+
+	next := start.AddDate(0, 0, 1)
+	due = calendar.DueReminders(next.Add(-20*time.Minute), next.Add(-10*time.Minute))
+	if len(due) != 1 || due[0].RecurrenceID == nil || !due[0].RecurrenceID.Equal(next) {
+		t.Errorf("Expected reminder for the next occurrence, got %+v", due)
+	}
+
+	if _, err := calendar.CreateEvent(1, start, "Too early", WithReminders(Reminder{MinutesBefore: 60 * 24 * 8})); err == nil {
+		t.Error("Expected error for reminder beyond the maximum lead")
+	}
+}
+
+func TestReminderScheduler_Tick(t *testing.T) {
+	calendar := NewCalendar()
+	notifier := &recordingNotifier{}
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(1, start, "Meeting", WithReminders(Reminder{MinutesBefore: 15}))
+
+	scheduler := NewReminderScheduler(calendar, notifier, time.Minute)
+	scheduler.last = start.Add(-30 * time.Minute)
+
+	scheduler.tick(context.Background(), start.Add(-16*time.Minute))
+	scheduler.tick(context.Background(), start.Add(-15*time.Minute))
+	scheduler.tick(context.Background(), start.Add(-14*time.Minute))
+
+	if len(notifier.notifications) != 1 {
+		t.Errorf("Expected exactly 1 notification, got %d", len(notifier.notifications))
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan Notification, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected JSON content type, got %s", r.Header.Get("Content-Type"))
+		}
+		var n Notification
+		json.NewDecoder(r.Body).Decode(&n)
+		received <- n
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL, server.Client())
+	err := notifier.Notify(context.Background(), Notification{EventID: 7, UserID: 1, Title: "Meeting", MinutesBefore: 15})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if n := <-received; n.EventID != 7 || n.Title != "Meeting" {
+		t.Errorf("Unexpected payload %+v", n)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	if err := NewWebhookNotifier(failing.URL, failing.Client()).Notify(context.Background(), Notification{}); err == nil {
+		t.Error("Expected error for non-2xx webhook response")
+	}
+}
+
+func TestLogNotifier(t *testing.T) {
+	var buf bytes.Buffer
+	notifier := NewLogNotifier(slog.New(slog.NewJSONHandler(&buf, nil)))
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	notifier.Notify(context.Background(), Notification{EventID: 7, UserID: 3, Title: "Standup", Start: start, MinutesBefore: 15})
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected JSON log record, got %q", buf.String())
+	}
+	if record["event_id"] != float64(7) || record["user_id"] != float64(3) || record["minutes_before"] != float64(15) {
+		t.Errorf("Expected event_id, user_id and minutes_before attributes, got %v", record)
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxTags      = 20
+	maxTagLength = 64
+
+	// веса полей при ранжировании: совпадение в названии важнее, чем в описании
+	weightTitle       = 3
+	weightTag         = 2
+	weightLocation    = 1.5
+	weightDescription = 1
+	// совпадение по префиксу ценится вдвое меньше точного
+	prefixPenalty = 0.5
+)
+
+var ErrTagInvalid = errors.New("invalid tag")
+
+func WithDescription(description string) EventOption {
+	return func(e *Event) {
+		e.Description = description
+	}
+}
+
+func WithLocation(location string) EventOption {
+	return func(e *Event) {
+		e.Location = location
+	}
+}
+
+// WithTags задаёт теги события; они приводятся к нижнему регистру, ведущий #
+// отбрасывается, повторы удаляются.
+func WithTags(tags ...string) EventOption {
+	return func(e *Event) {
+		e.Tags = nil
+		for _, tag := range tags {
+			tag = normalizeTag(tag)
+			if tag != "" && !containsString(e.Tags, tag) {
+				e.Tags = append(e.Tags, tag)
+			}
+		}
+	}
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+func validateTags(tags []string) error {
+	if len(tags) > maxTags {
+		return fmt.Errorf("%w: at most %d tags are allowed", ErrTagInvalid, maxTags)
+	}
+	for _, tag := range tags {
+		if len(tag) > maxTagLength {
+			return fmt.Errorf("%w: %q is longer than %d bytes", ErrTagInvalid, tag, maxTagLength)
+		}
+		for _, r := range tag {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '.' {
+				return fmt.Errorf("%w: %q may contain only letters, digits, '-', '_' and '.'", ErrTagInvalid, tag)
+			}
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenize разбивает текст на слова в нижнем регистре.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchIndex - инвертированный индекс событий одного пользователя. Термы
+// хранятся ещё и отсортированными, чтобы искать по префиксу двоичным поиском.
+type searchIndex struct {
+	// postings: терм -> ID события -> вес терма в событии
+	postings map[string]map[int]float64
+	terms    []string
+	tags     map[string]map[int]struct{}
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[int]float64),
+		tags:     make(map[string]map[int]struct{}),
+	}
+}
+
+func eventTerms(event Event) map[string]float64 {
+	weights := make(map[string]float64)
+	add := func(text string, weight float64) {
+		for _, term := range tokenize(text) {
+			weights[term] += weight
+		}
+	}
+	add(event.Title, weightTitle)
+	add(event.Location, weightLocation)
+	add(event.Description, weightDescription)
+	for _, tag := range event.Tags {
+		add(tag, weightTag)
+	}
+	return weights
+}
+
+func (s *searchIndex) add(event Event) {
+	for term, weight := range eventTerms(event) {
+		posting, ok := s.postings[term]
+		if !ok {
+			posting = make(map[int]float64)
+			s.postings[term] = posting
+			i := sort.SearchStrings(s.terms, term)
+			s.terms = append(s.terms, "")
+			copy(s.terms[i+1:], s.terms[i:])
+			s.terms[i] = term
+		}
+		posting[event.ID] = weight
+	}
+	for _, tag := range event.Tags {
+		if s.tags[tag] == nil {
+			s.tags[tag] = make(map[int]struct{})
+		}
+		s.tags[tag][event.ID] = struct{}{}
+	}
+}
+
+func (s *searchIndex) remove(event Event) {
+	for term := range eventTerms(event) {
+		posting := s.postings[term]
+		delete(posting, event.ID)
+		if len(posting) == 0 {
+			delete(s.postings, term)
+			if i := sort.SearchStrings(s.terms, term); i < len(s.terms) && s.terms[i] == term {
+				s.terms = append(s.terms[:i], s.terms[i+1:]...)
+			}
+		}
+	}
+	for _, tag := range event.Tags {
+		delete(s.tags[tag], event.ID)
+		if len(s.tags[tag]) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
+
+// match оценивает события по одному слову запроса: точное совпадение терма
+// и совпадения по префиксу, с весом idf.
+func (s *searchIndex) match(word string, total int) map[int]float64 {
+	scores := make(map[int]float64)
+	for i := sort.SearchStrings(s.terms, word); i < len(s.terms) && strings.HasPrefix(s.terms[i], word); i++ {
+		term := s.terms[i]
+		posting := s.postings[term]
+		idf := math.Log(1 + float64(total)/float64(len(posting)))
+		factor := 1.0
+		if term != word {
+			factor = prefixPenalty
+		}
+		for id, weight := range posting {
+			if score := weight * idf * factor; score > scores[id] {
+				scores[id] = score
+			}
+		}
+	}
+	return scores
+}
+
+type SearchResult struct {
+	Event Event   `json:"event"`
+	Score float64 `json:"score"`
+}
+
+// Search ищет события пользователя: каждое слово query должно встретиться в
+// названии, месте, описании или тегах целиком или как префикс, а событие -
+// нести все теги из tags. Слова вида #tag в query тоже считаются
+// тегами. Результаты упорядочены по убыванию релевантности, затем по началу.
+func (c *Calendar) Search(userID int, query string, tags []string, limit int) []SearchResult {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	user, ok := c.users[userID]
+	if !ok {
+		return nil
+	}
+	index := user.search
+
+	tags = append([]string(nil), tags...)
+	var words []string
+	for _, field := range strings.Fields(query) {
+		if strings.HasPrefix(field, "#") {
+			tags = append(tags, field)
+			continue
+		}
+		words = append(words, tokenize(field)...)
+	}
+	if len(words) == 0 && len(tags) == 0 {
+		return nil
+	}
+
+	var candidates map[int]float64
+	for _, word := range words {
+		scores := index.match(word, len(user.byID))
+		if candidates == nil {
+			candidates = scores
+			continue
+		}
+		for id, score := range candidates {
+			if s, ok := scores[id]; ok {
+				candidates[id] = score + s
+			} else {
+				delete(candidates, id)
+			}
+		}
+	}
+
+	for _, tag := range tags {
+		tagged := index.tags[normalizeTag(tag)]
+		if candidates == nil {
+			candidates = make(map[int]float64, len(tagged))
+			for id := range tagged {
+				candidates[id] = 0
+			}
+			continue
+		}
+		for id := range candidates {
+			if _, ok := tagged[id]; !ok {
+				delete(candidates, id)
+			}
+		}
+	}
+
+	results := make([]SearchResult, 0, len(candidates))
+	for id, score := range candidates {
+		results = append(results, SearchResult{Event: user.byID[id], Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.Event.Date.Equal(b.Event.Date) {
+			return a.Event.Date.Before(b.Event.Date)
+		}
+		return a.Event.ID < b.Event.ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// searchEvents - GET /events/search?user_id=&q=&tag=&limit=; tag можно
+// повторять.
+func (h *Handler) searchEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userID, err := strconv.Atoi(query.Get("user_id"))
+	if err != nil {
+		writeCalendarError(w, r, invalidField("user_id", errors.New("must be an integer")))
+		return
+	}
+	if query.Get("q") == "" && len(query["tag"]) == 0 {
+		writeCalendarError(w, r, invalidField("q", errors.New("q or tag is required")))
+		return
+	}
+	limit, _, err := parsePageQuery(query)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+
+	results := h.calendar.Search(userID, query.Get("q"), query["tag"], limit)
+	if results == nil {
+		results = []SearchResult{}
+	}
+	writeJSON(w, response{Result: results}, http.StatusOK)
+}
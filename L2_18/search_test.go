@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCalendar_Search(t *testing.T) {
+	calendar := NewCalendar()
+	day := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	review, _ := calendar.CreateEvent(1, day, "Design review", WithLocation("Room 4"), WithTags("#Work", "design"))
+	standup, _ := calendar.CreateEvent(1, day.AddDate(0, 0, 1), "Standup", WithDescription("Quick design sync"), WithTags("work"))
+	calendar.CreateEvent(1, day.AddDate(0, 0, 2), "Dentist", WithTags("personal"))
+	calendar.CreateEvent(2, day, "Design review")
+
+	results := calendar.Search(1, "design", nil, 0)
+	if len(results) != 2 || results[0].Event.ID != review.ID || results[1].Event.ID != standup.ID {
+		t.Fatalf("Expected title match ranked above description match, got %+v", results)
+	}
+
+	if results := calendar.Search(1, "rev", nil, 0); len(results) != 1 || results[0].Event.ID != review.ID {
+		t.Errorf("Expected prefix match on review, got %+v", results)
+	}
+	if results := calendar.Search(1, "design sync", nil, 0); len(results) != 1 || results[0].Event.ID != standup.ID {
+		t.Errorf("Expected all query words to be required, got %+v", results)
+	}
+	if results := calendar.Search(1, "#work", []string{"Design"}, 0); len(results) != 1 || results[0].Event.ID != review.ID {
+		t.Errorf("Expected tag filters to combine, got %+v", results)
+	}
+	if review.Tags[0] != "work" {
+		t.Errorf("Expected normalized tag, got %q", review.Tags)
+	}
+
+	calendar.UpdateEvent(review.ID, 1, day, "Planning", WithTags("work"))
+	if results := calendar.Search(1, "design", nil, 0); len(results) != 1 || results[0].Event.ID != standup.ID {
+		t.Errorf("Expected index to follow update, got %+v", results)
+	}
+	if results := calendar.Search(1, "plan", nil, 0); len(results) != 1 {
+		t.Errorf("Expected updated title to be indexed, got %+v", results)
+	}
+
+	calendar.DeleteEvent(standup.ID, 1)
+	if results := calendar.Search(1, "", []string{"work"}, 0); len(results) != 1 || results[0].Event.ID != review.ID {
+		t.Errorf("Expected index to follow delete, got %+v", results)
+	}
+}
+
+func TestCalendar_InvalidTag(t *testing.T) {
+	calendar := NewCalendar()
+	if _, err := calendar.CreateEvent(1, time.Now(), "Bad", WithTags("two words")); err == nil {
+		t.Error("Expected error for tag with a space")
+	}
+}
+
+func TestHandler_SearchEvents(t *testing.T) {
+	calendar := NewCalendar()
+	r := mux.NewRouter()
+	NewHandler(calendar).RegisterRoutes(r)
+
+	body := `{"user_id":1,"date":"2024-01-01T10:00:00Z","title":"Quarterly planning","location":"HQ","tags":["work"]}`
+	req := httptest.NewRequest("POST", "/create_event", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/events/search?user_id=1&q=quart&tag=work", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Result []SearchResult `json:"result"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if len(resp.Result) != 1 || resp.Result[0].Event.Location != "HQ" {
+		t.Errorf("Expected one result with location, got %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/events/search?user_id=1", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without q and tag, got %d", rec.Code)
+	}
+}
+
+func TestICS_DescriptionLocationTags(t *testing.T) {
+	calendar := NewCalendar()
+	calendar.CreateEvent(1, time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), "Offsite",
+		WithDescription("Agenda; goals, risks"), WithLocation("Lisbon"), WithTags("work", "travel"))
+
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, calendar.GetEvents(1)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "CATEGORIES:work,travel") {
+		t.Errorf("Expected CATEGORIES in export, got:\n%s", buf.String())
+	}
+
+	events, err := ParseICS(&buf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Description != "Agenda; goals, risks" || events[0].Location != "Lisbon" || len(events[0].Categories) != 2 {
+		t.Errorf("Expected fields to round-trip, got %+v", events)
+	}
+}
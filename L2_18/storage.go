@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	OpPut    = "put"
+	OpDelete = "delete"
+	OpBatch  = "batch"
+	// OpPutCalendar и OpDeleteCalendar меняют именованный календарь (Calendar)
+	OpPutCalendar    = "put_calendar"
+	OpDeleteCalendar = "delete_calendar"
+	// OpPurge стирает снимки окончательно удалённых событий (Purged) из их
+	// записей аудита; сами записи остаются
+	OpPurge = "purge"
+
+	snapshotFile = "snapshot.json"
+	journalFile  = "journal.log"
+)
+
+var (
+	ErrStorageClosed = errors.New("storage closed")
+	// ErrStorageUnavailable оборачивает ошибки записи в хранилище, чтобы их
+	// можно было отличить от ошибок в самом запросе.
+	ErrStorageUnavailable = errors.New("storage unavailable")
+)
+
+// Mutation - одна запись журнала: событие после изменения и nextID на момент записи.
+type Mutation struct {
+	Op     string `json:"op"`
+	Event  Event  `json:"event"`
+	NextID int    `json:"next_id"`
+	// Batch - мутации пакета (Op == OpBatch); пакет занимает одну запись
+	// журнала, поэтому после падения он либо применён целиком, либо отрезан.
+	Batch []Mutation `json:"batch,omitempty"`
+	// Audit - запись аудита об этом изменении
+	Audit *AuditEntry `json:"audit,omitempty"`
+	// Calendar - календарь для OpPutCalendar и OpDeleteCalendar
+	Calendar *UserCalendar `json:"calendar,omitempty"`
+	// Purged - ID событий для OpPurge
+	Purged []int `json:"purged,omitempty"`
+}
+
+type State struct {
+	NextID    int            `json:"next_id"`
+	Events    []Event        `json:"events"`
+	Calendars []UserCalendar `json:"calendars,omitempty"`
+	Audit     []AuditEntry   `json:"audit,omitempty"`
+	// AuditSeq - последний выданный номер записи аудита; после очистки
+	// корзины он может быть больше номеров оставшихся записей
+	AuditSeq int64 `json:"audit_seq,omitempty"`
+}
+
+type Storage interface {
+	Load() (State, error)
+	Apply(m Mutation) error
+	// Ping сообщает, готово ли хранилище принимать записи.
+	Ping() error
+	Close() error
+}
+
+type storeState struct {
+	events    map[int]Event
+	calendars map[int]UserCalendar
+	nextID    int
+	audit     []AuditEntry
+	auditSeq  int64
+}
+
+func newStoreState() *storeState {
+	return &storeState{events: make(map[int]Event), calendars: make(map[int]UserCalendar), nextID: 1}
+}
+
+func (s *storeState) apply(m Mutation) error {
+	switch m.Op {
+	case OpPut:
+		s.events[m.Event.ID] = m.Event
+	case OpDelete:
+		delete(s.events, m.Event.ID)
+	case OpPutCalendar:
+		s.calendars[m.Calendar.ID] = *m.Calendar
+	case OpDeleteCalendar:
+		delete(s.calendars, m.Calendar.ID)
+	case OpPurge:
+		purged := make(map[int]bool, len(m.Purged))
+		for _, id := range m.Purged {
+			purged[id] = true
+		}
+		for i := range s.audit {
+			if purged[s.audit[i].EventID] {
+				s.audit[i].Before, s.audit[i].After = nil, nil
+			}
+		}
+	case OpBatch:
+		for _, item := range m.Batch {
+			if err := s.apply(item); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown mutation op %q", m.Op)
+	}
+	if m.NextID > s.nextID {
+		s.nextID = m.NextID
+	}
+	// журнал может повторно применяться поверх снапшота, где запись уже есть
+	if m.Audit != nil && m.Audit.Seq > s.auditSeq {
+		s.audit = append(s.audit, *m.Audit)
+		s.auditSeq = m.Audit.Seq
+	}
+	return nil
+}
+
+func (s *storeState) snapshot() State {
+	events := make([]Event, 0, len(s.events))
+	for _, e := range s.events {
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+	var calendars []UserCalendar
+	for _, cal := range s.calendars {
+		calendars = append(calendars, cal)
+	}
+	sort.Slice(calendars, func(i, j int) bool {
+		return calendars[i].ID < calendars[j].ID
+	})
+	return State{NextID: s.nextID, Events: events, Calendars: calendars, Audit: append([]AuditEntry(nil), s.audit...), AuditSeq: s.auditSeq}
+}
+
+func (s *storeState) restore(st State) {
+	for _, e := range st.Events {
+		s.events[e.ID] = e
+	}
+	for _, cal := range st.Calendars {
+		s.calendars[cal.ID] = cal
+	}
+	if st.NextID > s.nextID {
+		s.nextID = st.NextID
+	}
+	s.audit = append(s.audit, st.Audit...)
+	if len(st.Audit) > 0 && st.Audit[len(st.Audit)-1].Seq > s.auditSeq {
+		s.auditSeq = st.Audit[len(st.Audit)-1].Seq
+	}
+	if st.AuditSeq > s.auditSeq {
+		s.auditSeq = st.AuditSeq
+	}
+}
+
+type MemoryStorage struct {
+	mu    sync.Mutex
+	state *storeState
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{state: newStoreState()}
+}
+
+func (m *MemoryStorage) Load() (State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.snapshot(), nil
+}
+
+func (m *MemoryStorage) Apply(mut Mutation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.apply(mut)
+}
+
+func (m *MemoryStorage) Ping() error {
+	return nil
+}
+
+func (m *MemoryStorage) Close() error {
+	return nil
+}
+
+// FileStorage пишет каждую мутацию в append-only журнал и раз в snapshotEvery
+// записей сбрасывает полное состояние в снапшот, после чего журнал обнуляется.
+// Повторное применение журнала поверх снапшота идемпотентно, поэтому падение
+// между записью снапшота и обнулением журнала ничего не ломает.
+type FileStorage struct {
+	mu            sync.Mutex
+	dir           string
+	journal       *os.File
+	state         *storeState
+	pending       int
+	snapshotEvery int
+}
+
+func NewFileStorage(dir string, snapshotEvery int) (*FileStorage, error) {
+	if snapshotEvery <= 0 {
+		snapshotEvery = 1000
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	fs := &FileStorage{
+		dir:           dir,
+		state:         newStoreState(),
+		snapshotEvery: snapshotEvery,
+	}
+
+	if err := fs.readSnapshot(); err != nil {
+		return nil, err
+	}
+
+	journal, err := os.OpenFile(filepath.Join(dir, journalFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	fs.journal = journal
+
+	if err := fs.replayJournal(); err != nil {
+		journal.Close()
+		return nil, err
+	}
+
+	return fs, nil
+}
+
+func (fs *FileStorage) readSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(fs.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("read snapshot: %w", err)
+	}
+	fs.state.restore(st)
+	return nil
+}
+
+func (fs *FileStorage) replayJournal() error {
+	reader := bufio.NewReader(fs.journal)
+	var offset int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// недописанная последняя строка - след падения посреди записи, отрезаем её
+			if len(line) > 0 {
+				if err := fs.journal.Truncate(offset); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var m Mutation
+		if err := json.Unmarshal(line, &m); err != nil {
+			return fmt.Errorf("journal at offset %d: %w", offset, err)
+		}
+		if err := fs.state.apply(m); err != nil {
+			return fmt.Errorf("journal at offset %d: %w", offset, err)
+		}
+		offset += int64(len(line))
+		fs.pending++
+	}
+
+	_, err := fs.journal.Seek(offset, io.SeekStart)
+	return err
+}
+
+func (fs *FileStorage) Load() (State, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.state.snapshot(), nil
+}
+
+func (fs *FileStorage) Apply(m Mutation) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.journal == nil {
+		return ErrStorageClosed
+	}
+
+	line, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if _, err := fs.journal.Write(line); err != nil {
+		return err
+	}
+	if err := fs.journal.Sync(); err != nil {
+		return err
+	}
+	if err := fs.state.apply(m); err != nil {
+		return err
+	}
+
+	fs.pending++
+	if fs.pending >= fs.snapshotEvery {
+		// изменение уже в журнале, поэтому сбой снапшота не ошибка вызова:
+		// pending не сброшен, и снапшот повторится на следующем Apply
+		if err := fs.writeSnapshot(); err != nil {
+			slog.Error("snapshot failed", "dir", fs.dir, "pending", fs.pending, "error", err)
+		}
+	}
+	return nil
+}
+
+// Ping проверяет, что журнал открыт, а каталог данных на месте.
+func (fs *FileStorage) Ping() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.journal == nil {
+		return ErrStorageClosed
+	}
+	if _, err := fs.journal.Stat(); err != nil {
+		return err
+	}
+	_, err := os.Stat(fs.dir)
+	return err
+}
+
+// Snapshot принудительно сохраняет снапшот и очищает журнал.
+func (fs *FileStorage) Snapshot() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.journal == nil {
+		return ErrStorageClosed
+	}
+	return fs.writeSnapshot()
+}
+
+func (fs *FileStorage) writeSnapshot() error {
+	data, err := json.Marshal(fs.state.snapshot())
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(fs.dir, snapshotFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(fs.dir, snapshotFile)); err != nil {
+		return err
+	}
+
+	if err := fs.journal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := fs.journal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	fs.pending = 0
+	return nil
+}
+
+func (fs *FileStorage) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.journal == nil {
+		return nil
+	}
+
+	snapErr := fs.writeSnapshot()
+	closeErr := fs.journal.Close()
+	fs.journal = nil
+
+	if snapErr != nil {
+		return snapErr
+	}
+	return closeErr
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStorage_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	date := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	storage, err := NewFileStorage(dir, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	calendar, err := NewCalendarWithStorage(storage)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	calendar.CreateEvent(1, date, "Event 1")
+	second, _ := calendar.CreateEvent(1, date, "Event 2")
+	third, _ := calendar.CreateEvent(1, date, "Event 3")
+	calendar.UpdateEvent(third.ID, 1, date, "Event 3 updated")
+	calendar.DeleteEvent(second.ID, 1)
+
+	// закрываем журнал без снапшота, как при аварийном завершении
+	storage.journal.Close()
+
+	storage, err = NewFileStorage(dir, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	calendar, err = NewCalendarWithStorage(storage)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer calendar.Close()
+
+	events := calendar.GetEventsForDay(1, date)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[1].Title != "Event 3 updated" {
+		t.Errorf("Expected title 'Event 3 updated', got %s", events[1].Title)
+	}
+
+	event, _ := calendar.CreateEvent(1, date, "Event 4")
+	if event.ID != 4 {
+		t.Errorf("Expected ID 4, got %d", event.ID)
+	}
+}
+
+func TestFileStorage_SnapshotFailureKeepsMutation(t *testing.T) {
+	dir := t.TempDir()
+	date := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	storage, err := NewFileStorage(dir, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// непустой каталог на месте снапшота не даёт переименовать временный файл
+	blocker := filepath.Join(dir, snapshotFile)
+	os.MkdirAll(filepath.Join(blocker, "busy"), 0o755)
+	calendar, _ := NewCalendarWithStorage(storage)
+	if _, err := calendar.CreateEvent(1, date, "Event 1"); err != nil {
+		t.Fatalf("Expected journaled create to succeed, got %v", err)
+	}
+	if _, err := calendar.CreateEvent(1, date, "Event 2"); err != nil {
+		t.Fatalf("Expected second create to succeed, got %v", err)
+	}
+	storage.journal.Close()
+
+	// снапшот снова возможен: следующий запуск восстанавливает всё из журнала
+	os.RemoveAll(blocker)
+	storage, err = NewFileStorage(dir, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	calendar, _ = NewCalendarWithStorage(storage)
+	defer calendar.Close()
+	if events := calendar.GetEventsForDay(1, date); len(events) != 2 {
+		t.Errorf("Expected 2 events, got %d", len(events))
+	}
+}
+
+func TestFileStorage_NextIDNotReusedAfterDelete(t *testing.T) {
+	dir := t.TempDir()
+	date := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	storage, _ := NewFileStorage(dir, 100)
+	calendar, _ := NewCalendarWithStorage(storage)
+	event, _ := calendar.CreateEvent(1, date, "Only Event")
+	calendar.DeleteEvent(event.ID, 1)
+	calendar.Close()
+
+	storage, _ = NewFileStorage(dir, 100)
+	calendar, _ = NewCalendarWithStorage(storage)
+	defer calendar.Close()
+
+	event, _ = calendar.CreateEvent(1, date, "New Event")
+	if event.ID != 2 {
+		t.Errorf("Expected ID 2, got %d", event.ID)
+	}
+}
+
+func TestFileStorage_TornJournalTail(t *testing.T) {
+	dir := t.TempDir()
+	date := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	storage, _ := NewFileStorage(dir, 100)
+	calendar, _ := NewCalendarWithStorage(storage)
+	calendar.CreateEvent(1, date, "Event 1")
+	storage.journal.Close()
+
+	f, _ := os.OpenFile(filepath.Join(dir, journalFile), os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"op":"put","event":{"id":2`)
+	f.Close()
+
+	storage, err := NewFileStorage(dir, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	calendar, _ = NewCalendarWithStorage(storage)
+	defer calendar.Close()
+
+	if events := calendar.GetEventsForDay(1, date); len(events) != 1 {
+		t.Errorf("Expected 1 event, got %d", len(events))
+	}
+	event, _ := calendar.CreateEvent(1, date, "Event 2")
+	if event.ID != 2 {
+		t.Errorf("Expected ID 2, got %d", event.ID)
+	}
+}
+
+func TestFileStorage_RestoresTimezone(t *testing.T) {
+	moscow := mustLocation(t, "Europe/Moscow")
+	dir := t.TempDir()
+	start := time.Date(2024, 5, 1, 14, 30, 0, 0, moscow)
+
+	storage, _ := NewFileStorage(dir, 100)
+	calendar, _ := NewCalendarWithStorage(storage)
+	calendar.CreateEvent(1, start, "Sync", WithDuration(45*time.Minute))
+	calendar.Close()
+
+	storage, _ = NewFileStorage(dir, 100)
+	calendar, err := NewCalendarWithStorage(storage)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer calendar.Close()
+
+	events := calendar.GetEvents(1)
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if events[0].Date.Location().String() != "Europe/Moscow" || events[0].AllDay {
+		t.Errorf("Expected timed event in Europe/Moscow, got %+v", events[0])
+	}
+	if !events[0].End.Equal(start.Add(45 * time.Minute)) {
+		t.Errorf("Expected end %v, got %v", start.Add(45*time.Minute), events[0].End)
+	}
+}
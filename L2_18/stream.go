@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// streamHeartbeat - как часто в простаивающий поток пишется комментарий,
+// чтобы прокси не закрывали соединение.
+const streamHeartbeat = 15 * time.Second
+
+// streamParams - пользователь потока и позиция, с которой его возобновить.
+type streamParams struct {
+	userID int
+	epoch  string
+	after  uint64
+}
+
+// parseStreamParams разбирает user_id и позицию возобновления: заголовок
+// Last-Event-ID (его шлёт EventSource при переподключении) или last_event_id.
+func parseStreamParams(r *http.Request) (streamParams, error) {
+	var params streamParams
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		return params, invalidField("user_id", errors.New("must be an integer"))
+	}
+	params.userID = userID
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	if lastID != "" {
+		if params.epoch, params.after, err = parseChangeID(lastID); err != nil {
+			return params, invalidField("last_event_id", errors.New("must be an id of a received change"))
+		}
+	}
+	return params, nil
+}
+
+// eventsStream отдаёт изменения событий как Server-Sent Events. Если
+// пропущенные изменения уже недоступны, отвечает 410: клиенту нужно заново
+// загрузить события и подключиться без Last-Event-ID.
+func (h *Handler) eventsStream(w http.ResponseWriter, r *http.Request) {
+	params, err := parseStreamParams(r)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub, missed, err := h.calendar.Subscribe(params.userID, params.epoch, params.after)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	defer sub.Close()
+
+	// поток живёт дольше WriteTimeout сервера
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, change := range missed {
+		writeSSE(w, change)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.shutdown:
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case change, ok := <-sub.C:
+			if !ok {
+				// подписчик отстал: клиент переподключится с Last-Event-ID
+				return
+			}
+			writeSSE(w, change)
+			flusher.Flush()
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, change Change) {
+	data, _ := json.Marshal(change)
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", change.ID(), change.Type, data)
+}
+
+// streamMessage - сообщение WebSocket-потока: изменение или ошибка.
+type streamMessage struct {
+	Type   string  `json:"type"`
+	Change *Change `json:"change,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// eventsWebSocket отдаёт те же изменения по WebSocket в виде JSON-сообщений.
+// Подписка создаётся до рукопожатия, чтобы на устаревший last_event_id
+// ответить 410, как и SSE.
+func (h *Handler) eventsWebSocket(w http.ResponseWriter, r *http.Request) {
+	params, err := parseStreamParams(r)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	sub, missed, err := h.calendar.Subscribe(params.userID, params.epoch, params.after)
+	if err != nil {
+		writeCalendarError(w, r, err)
+		return
+	}
+	defer sub.Close()
+
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		h.serveWebSocket(ws, sub, missed)
+	}}.ServeHTTP(w, r)
+}
+
+func (h *Handler) serveWebSocket(ws *websocket.Conn, sub *Subscription, missed []Change) {
+	defer ws.Close()
+
+	for i := range missed {
+		if websocket.JSON.Send(ws, streamMessage{Type: string(missed[i].Type), Change: &missed[i]}) != nil {
+			return
+		}
+	}
+
+	// входящие сообщения не нужны, чтение лишь замечает закрытие соединения
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-h.shutdown:
+			return
+		case change, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if websocket.JSON.Send(ws, streamMessage{Type: string(change.Type), Change: &change}) != nil {
+				return
+			}
+		}
+	}
+}
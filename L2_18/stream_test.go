@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/net/websocket"
+)
+
+func TestCalendar_Subscribe(t *testing.T) {
+	calendar := NewCalendar()
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	sub, missed, err := calendar.Subscribe(1, "", 0)
+	if err != nil || len(missed) != 0 {
+		t.Fatalf("Expected empty subscription, got %v %v", missed, err)
+	}
+	defer sub.Close()
+
+	event, _ := calendar.CreateEvent(1, date, "Meeting")
+	calendar.CreateEvent(2, date, "Other user")
+	calendar.UpdateEvent(event.ID, 1, date.Add(time.Hour), "Moved")
+	calendar.DeleteEvent(event.ID, 1)
+
+	var types []ChangeType
+	var first Change
+	for i := 0; i < 3; i++ {
+		select {
+		case change := <-sub.C:
+			if i == 0 {
+				first = change
+			}
+			types = append(types, change.Type)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for change")
+		}
+	}
+	if types[0] != ChangeCreated || types[1] != ChangeUpdated || types[2] != ChangeDeleted {
+		t.Errorf("Unexpected change types %v", types)
+	}
+
+	// возобновление после первого изменения возвращает остальные два
+	resumed, missed, err := calendar.Subscribe(1, first.Epoch, first.Seq)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resumed.Close()
+	if len(missed) != 2 || missed[0].Type != ChangeUpdated {
+		t.Errorf("Expected 2 missed changes, got %+v", missed)
+	}
+
+	if _, _, err := calendar.Subscribe(1, first.Epoch, 100); err != ErrChangesExpired {
+		t.Errorf("Expected ErrChangesExpired for unknown seq, got %v", err)
+	}
+}
+
+func TestCalendar_SubscribeSharedAndRemoved(t *testing.T) {
+	calendar := NewCalendar()
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	work, _ := calendar.CreateCalendar(1, "Work")
+	calendar.ShareCalendar(work.ID, 1, 2, PermissionRead)
+
+	reader, _, _ := calendar.Subscribe(2, "", 0)
+	defer reader.Close()
+	guest, _, _ := calendar.Subscribe(3, "", 0)
+	defer guest.Close()
+
+	next := func(sub *Subscription) Change {
+		t.Helper()
+		select {
+		case change := <-sub.C:
+			return change
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for change")
+			return Change{}
+		}
+	}
+
+	event, _ := calendar.CreateEvent(1, date, "Review", InCalendar(work.ID), WithAttendees(3))
+	if change := next(reader); change.Type != ChangeCreated || change.EventID != event.ID {
+		t.Errorf("Expected calendar reader to see the new event, got %+v", change)
+	}
+	if change := next(guest); change.Type != ChangeCreated {
+		t.Errorf("Expected attendee to see the new event, got %+v", change)
+	}
+
+	// исключённый из приглашённых получает удаление, а не новую редакцию
+	calendar.UpdateEvent(event.ID, 1, date, "Review v2", WithAttendees())
+	if change := next(reader); change.Type != ChangeUpdated || change.Event.Title != "Review v2" {
+		t.Errorf("Expected calendar reader to see the update, got %+v", change)
+	}
+	if change := next(guest); change.Type != ChangeDeleted || change.Event.Title != "Review" {
+		t.Errorf("Expected removed attendee to get a deletion of the old event, got %+v", change)
+	}
+	select {
+	case change := <-guest.C:
+		t.Errorf("Expected no further changes for removed attendee, got %+v", change)
+	default:
+	}
+}
+
+func TestCalendar_SubscribeAfterRestart(t *testing.T) {
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	before := NewCalendar()
+	before.CreateEvent(1, date, "Seen")
+	sub, _, _ := before.Subscribe(1, "", 0)
+	before.CreateEvent(1, date, "Last seen")
+	last := <-sub.C
+	sub.Close()
+
+	// после рестарта seq начинается заново и обгоняет сохранённый клиентом
+	after := NewCalendar()
+	for i := 0; i < 3; i++ {
+		after.CreateEvent(1, date, "Missed")
+	}
+	if _, missed, err := after.Subscribe(1, last.Epoch, last.Seq); err != ErrChangesExpired {
+		t.Errorf("Expected ErrChangesExpired for seq of another run, got %v %v", missed, err)
+	}
+	if _, _, err := after.Subscribe(1, "", last.Seq); err != ErrChangesExpired {
+		t.Errorf("Expected ErrChangesExpired for seq without epoch, got %v", err)
+	}
+}
+
+func TestCalendar_SubscribeSlowConsumer(t *testing.T) {
+	calendar := NewCalendar()
+	sub, _, _ := calendar.Subscribe(1, "", 0)
+
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i <= subscriberBuffer; i++ {
+		calendar.CreateEvent(1, date, "Event")
+	}
+
+	n := 0
+	for range sub.C {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Errorf("Expected %d buffered changes before disconnect, got %d", subscriberBuffer, n)
+	}
+	sub.Close()
+}
+
+func streamServer(calendar *Calendar) *httptest.Server {
+	// через middleware, чтобы проверить проброс Flush и Hijack
+	r := mux.NewRouter()
+	r.Use(RequestIDMiddleware, LoggingMiddleware, NewMetrics(calendar).Middleware)
+	NewHandler(calendar).RegisterRoutes(r)
+	return httptest.NewServer(r)
+}
+
+func TestHandler_EventsStream(t *testing.T) {
+	calendar := NewCalendar()
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(1, date, "Seen")
+	calendar.CreateEvent(1, date, "Missed")
+
+	srv := streamServer(calendar)
+	defer srv.Close()
+
+	epoch := calendar.changes.epoch
+	req, _ := http.NewRequest("GET", srv.URL+"/events/stream?user_id=1", nil)
+	req.Header.Set("Last-Event-ID", epoch+"-1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", ct)
+	}
+
+	calendar.CreateEvent(1, date, "Live")
+
+	lines := bufio.NewScanner(resp.Body)
+	var got []string
+	for len(got) < 4 && lines.Scan() {
+		if line := lines.Text(); line != "" && !strings.HasPrefix(line, "data:") {
+			got = append(got, line)
+		}
+	}
+	// сначала пропущенное изменение, затем новое
+	if strings.Join(got, ",") != "id: "+epoch+"-2,event: created,id: "+epoch+"-3,event: created" {
+		t.Errorf("Unexpected stream %v", got)
+	}
+
+	// seq без epoch мог прийти от прошлого запуска: клиенту нужно пересобрать состояние
+	req.Header.Set("Last-Event-ID", "1")
+	stale, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	stale.Body.Close()
+	if stale.StatusCode != http.StatusGone {
+		t.Errorf("Expected 410 for seq without epoch, got %d", stale.StatusCode)
+	}
+}
+
+func TestHandler_EventsWebSocket(t *testing.T) {
+	calendar := NewCalendar()
+	date := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(1, date, "Missed")
+
+	srv := streamServer(calendar)
+	defer srv.Close()
+
+	// id от прошлого запуска сервера: 410 ещё до рукопожатия
+	resp, err := http.Get(srv.URL + "/events/ws?user_id=1&last_event_id=0badbeef-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGone {
+		t.Fatalf("Expected 410 for id of another run, got %d", resp.StatusCode)
+	}
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/events/ws?user_id=1&last_event_id="+calendar.changes.epoch+"-1", "", srv.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer ws.Close()
+
+	var msg streamMessage
+	calendar.CreateEvent(1, date, "Live")
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	if err := websocket.JSON.Receive(ws, &msg); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if msg.Type != "created" || msg.Change == nil || msg.Change.Event.Title != "Live" {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTimezoneInvalid = errors.New("invalid timezone")
+	ErrEndBeforeStart  = errors.New("event end is before start")
+)
+
+// time.LoadLocation каждый раз читает базу часовых поясов, а при разворачивании
+// серий пояс нужен постоянно
+var locationCache sync.Map
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "UTC" {
+		return time.UTC, nil
+	}
+	if loc, ok := locationCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrTimezoneInvalid, name)
+	}
+	locationCache.Store(name, loc)
+	return loc, nil
+}
+
+// timezoneOf возвращает IANA-имя пояса времени t, а для UTC, Local и
+// фиксированных смещений - "UTC".
+func timezoneOf(t time.Time) string {
+	name := t.Location().String()
+	if name == "Local" || name == "" {
+		return "UTC"
+	}
+	if _, err := loadLocation(name); err != nil {
+		return "UTC"
+	}
+	return name
+}
+
+// normalizeTime приводит Date и End к поясу события и проверяет их.
+func (e *Event) normalizeTime() error {
+	if e.Timezone == "" {
+		e.Timezone = timezoneOf(e.Date)
+	}
+	loc, err := loadLocation(e.Timezone)
+	if err != nil {
+		return err
+	}
+
+	if e.AllDay {
+		// у событий на весь день важна только календарная дата, а не момент
+		e.Date = floatDate(e.Date, loc)
+		if e.End.IsZero() {
+			e.End = e.Date.AddDate(0, 0, 1)
+		} else {
+			e.End = floatDate(e.End, loc)
+		}
+		if !e.End.After(e.Date) {
+			return ErrEndBeforeStart
+		}
+		return nil
+	}
+
+	e.Date = e.Date.In(loc)
+	if e.End.IsZero() {
+		e.End = e.Date
+	}
+	e.End = e.End.In(loc)
+	if e.End.Before(e.Date) {
+		return ErrEndBeforeStart
+	}
+	return nil
+}
+
+// normalizeLoaded восстанавливает пояс после чтения из хранилища: JSON хранит
+// только смещение. События, сохранённые до появления времени и поясов, были
+// датами без времени и становятся событиями на весь день.
+func (e *Event) normalizeLoaded() error {
+	if e.End.IsZero() && e.Timezone == "" {
+		e.AllDay = true
+	}
+	if e.Timezone == "" {
+		e.Timezone = "UTC"
+	}
+	if e.Version == 0 {
+		e.Version = 1
+	}
+	return e.normalizeTime()
+}
+
+// span возвращает границы события в поясе loc. События на весь день
+// "плавают": 31 декабря остаётся 31 декабря в любом поясе.
+func (e Event) span(loc *time.Location) (time.Time, time.Time) {
+	if e.AllDay {
+		return floatDate(e.Date, loc), floatDate(e.End, loc)
+	}
+	return e.Date, e.End
+}
+
+func (e Event) overlaps(from, to time.Time) bool {
+	start, end := e.span(from.Location())
+	if end.Equal(start) {
+		return !start.Before(from) && start.Before(to)
+	}
+	return start.Before(to) && end.After(from)
+}
+
+// floatDate переносит календарную дату t в пояс loc на полночь.
+func floatDate(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return floatDate(t, t.Location())
+}
+
+func daysBetween(a, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	da := time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)
+	db := time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da).Hours() / 24)
+}
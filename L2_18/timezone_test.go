@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone database unavailable: %v", err)
+	}
+	return loc
+}
+
+func TestCalendar_TimedEventInTimezone(t *testing.T) {
+	moscow := mustLocation(t, "Europe/Moscow")
+	calendar := NewCalendar()
+
+	start := time.Date(2024, 1, 2, 1, 30, 0, 0, moscow)
+	event, err := calendar.CreateEvent(1, start, "Night call", WithDuration(45*time.Minute))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if event.Timezone != "Europe/Moscow" {
+		t.Errorf("Expected timezone Europe/Moscow, got %s", event.Timezone)
+	}
+	if !event.End.Equal(start.Add(45 * time.Minute)) {
+		t.Errorf("Expected end %v, got %v", start.Add(45*time.Minute), event.End)
+	}
+
+	// 01:30 по Москве - это ещё 1 января в UTC
+	if events := calendar.GetEventsForDay(1, time.Date(2024, 1, 2, 0, 0, 0, 0, moscow)); len(events) != 1 {
+		t.Errorf("Expected 1 event on Moscow Jan 2, got %d", len(events))
+	}
+	if events := calendar.GetEventsForDay(1, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)); len(events) != 0 {
+		t.Errorf("Expected 0 events on UTC Jan 2, got %d", len(events))
+	}
+	if events := calendar.GetEventsForDay(1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); len(events) != 1 {
+		t.Errorf("Expected 1 event on UTC Jan 1, got %d", len(events))
+	}
+
+	_, err = calendar.CreateEvent(1, start, "Backwards", WithEnd(start.Add(-time.Minute)))
+	if err != ErrEndBeforeStart {
+		t.Errorf("Expected ErrEndBeforeStart, got %v", err)
+	}
+	_, err = calendar.CreateEvent(1, start, "Nowhere", WithTimezone("Mars/Olympus"))
+	if err == nil {
+		t.Error("Expected error for unknown timezone")
+	}
+}
+
+func TestCalendar_EventSpanningMidnight(t *testing.T) {
+	calendar := NewCalendar()
+
+	start := time.Date(2023, 12, 31, 22, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(1, start, "Party", WithDuration(4*time.Hour))
+
+	if events := calendar.GetEventsForDay(1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); len(events) != 1 {
+		t.Errorf("Expected event to overlap Jan 1, got %d", len(events))
+	}
+	if events := calendar.GetEventsForMonth(1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); len(events) != 1 {
+		t.Errorf("Expected event to overlap January, got %d", len(events))
+	}
+}
+
+func TestCalendar_AllDayEventsFloat(t *testing.T) {
+	moscow := mustLocation(t, "Europe/Moscow")
+	calendar := NewCalendar()
+
+	date := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	event, _ := calendar.CreateEvent(1, date, "New Year's Eve", WithAllDay())
+	if !event.End.Equal(date.AddDate(0, 0, 1)) {
+		t.Errorf("Expected all-day end %v, got %v", date.AddDate(0, 0, 1), event.End)
+	}
+
+	if events := calendar.GetEventsForDay(1, time.Date(2023, 12, 31, 0, 0, 0, 0, moscow)); len(events) != 1 {
+		t.Errorf("Expected all-day event on Moscow Dec 31, got %d", len(events))
+	}
+	if events := calendar.GetEventsForDay(1, time.Date(2024, 1, 1, 0, 0, 0, 0, moscow)); len(events) != 0 {
+		t.Errorf("Expected no all-day event on Moscow Jan 1, got %d", len(events))
+	}
+}
+
+func TestCalendar_RecurrenceKeepsLocalTimeAcrossDST(t *testing.T) {
+	newYork := mustLocation(t, "America/New_York")
+	calendar := NewCalendar()
+
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, newYork)
+	calendar.CreateEvent(1, start, "Standup", WithDuration(15*time.Minute),
+		WithRecurrence(&Recurrence{Freq: FreqWeekly}))
+
+	events := calendar.GetEventsForMonth(1, time.Date(2024, 3, 1, 0, 0, 0, 0, newYork))
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %d", len(events))
+	}
+	for _, event := range events {
+		if event.Date.Hour() != 9 || event.End.Sub(event.Date) != 15*time.Minute {
+			t.Errorf("Expected 09:00 for 15m, got %v - %v", event.Date, event.End)
+		}
+	}
+	if events[0].Date.UTC().Hour() == events[1].Date.UTC().Hour() {
+		t.Error("Expected UTC hour to shift after DST change")
+	}
+}
+
+func TestParseEventRequest(t *testing.T) {
+	req := &eventRequest{Date: "2024-05-01T14:30", Duration: "45m", Timezone: "Europe/Moscow"}
+	date, opts, err := parseEventRequest(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	event, err := newEvent(1, 1, date, "Sync", opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if event.Date.Format(time.RFC3339) != "2024-05-01T14:30:00+03:00" {
+		t.Errorf("Unexpected start %s", event.Date.Format(time.RFC3339))
+	}
+	if event.End.Sub(event.Date) != 45*time.Minute {
+		t.Errorf("Unexpected duration %v", event.End.Sub(event.Date))
+	}
+
+	req = &eventRequest{Date: "2024-05-01", End: "2024-05-03"}
+	date, opts, _ = parseEventRequest(req)
+	event, _ = newEvent(1, 1, date, "Conference", opts)
+	if !event.AllDay || daysBetween(event.Date, event.End) != 3 {
+		t.Errorf("Expected 3-day all-day event, got %+v", event)
+	}
+
+	invalid := []*eventRequest{
+		{Date: "2024-05-01T14:30:00Z", End: "2024-05-02"},
+		{Date: "2024-05-01", Duration: "1h"},
+		{Date: "2024-05-01T14:30:00Z", End: "2024-05-01T15:00:00Z", Duration: "1h"},
+		{Date: "tomorrow"},
+		{Date: "2024-05-01", Timezone: "Nowhere/City"},
+	}
+	for _, req := range invalid {
+		if _, _, err := parseEventRequest(req); err == nil {
+			t.Errorf("Expected error for %+v", req)
+		}
+	}
+}
+
+func TestICS_TimezoneRoundTrip(t *testing.T) {
+	moscow := mustLocation(t, "Europe/Moscow")
+	calendar := NewCalendar()
+
+	start := time.Date(2024, 5, 1, 14, 30, 0, 0, moscow)
+	calendar.CreateEvent(1, start, "Sync", WithDuration(45*time.Minute))
+
+	var buf bytes.Buffer
+	WriteICS(&buf, calendar.GetEvents(1))
+	if !strings.Contains(buf.String(), "DTSTART;TZID=Europe/Moscow:20240501T143000") {
+		t.Errorf("Expected TZID DTSTART, got:\n%s", buf.String())
+	}
+
+	events, err := ParseICS(&buf, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	other := NewCalendar()
+	importICSEvents(other, 1, events)
+
+	got := other.GetEvents(1)
+	if len(got) != 1 || got[0].Timezone != "Europe/Moscow" || !got[0].End.Equal(start.Add(45*time.Minute)) {
+		t.Errorf("Unexpected imported events %+v", got)
+	}
+
+	duration, err := parseICSDuration("P1DT2H30M")
+	if err != nil || duration != 26*time.Hour+30*time.Minute {
+		t.Errorf("Expected 26h30m, got %v (%v)", duration, err)
+	}
+}
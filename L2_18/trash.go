@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// TrashedEvent - удалённое событие в корзине. Корзина строится по журналу
+// аудита: событие лежит в ней, пока его последнее изменение - удаление.
+type TrashedEvent struct {
+	Event     Event     `json:"event"`
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy int       `json:"deleted_by"`
+	// PurgeAt - когда событие будет удалено окончательно; пусто, если срок
+	// хранения не ограничен
+	PurgeAt *time.Time `json:"purge_at,omitempty"`
+}
+
+// SetTrashRetention задаёт, сколько удалённые события можно восстановить;
+// 0 хранит их бессрочно.
+func (c *Calendar) SetTrashRetention(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retention = d
+}
+
+// expired сообщает, истёк ли срок хранения события, удалённого в deletedAt.
+func (c *Calendar) expired(deletedAt, now time.Time) bool {
+	return c.retention > 0 && !deletedAt.Add(c.retention).After(now)
+}
+
+// Trash возвращает корзину пользователя, недавно удалённые первыми.
+// Вхождения, удалённые вместе с серией, отдельно не показываются: они
+// восстанавливаются вместе с ней.
+func (c *Calendar) Trash(userID int) []TrashedEvent {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.now()
+	trash := []TrashedEvent{}
+	for id := range c.history {
+		entry, ok := c.lastDeletion(id, userID)
+		if !ok || c.expired(entry.At, now) {
+			continue
+		}
+		if seriesID := entry.Before.SeriesID; seriesID != 0 {
+			if _, ok := c.find(seriesID, userID); !ok {
+				continue
+			}
+		}
+		item := TrashedEvent{Event: *entry.Before, DeletedAt: entry.At, DeletedBy: entry.Actor}
+		if c.retention > 0 {
+			purgeAt := entry.At.Add(c.retention)
+			item.PurgeAt = &purgeAt
+		}
+		trash = append(trash, item)
+	}
+	sort.Slice(trash, func(i, j int) bool {
+		if !trash[i].DeletedAt.Equal(trash[j].DeletedAt) {
+			return trash[i].DeletedAt.After(trash[j].DeletedAt)
+		}
+		return trash[i].Event.ID > trash[j].Event.ID
+	})
+	return trash
+}
+
+// PurgeTrash окончательно удаляет события, срок хранения которых истёк к
+// now, и возвращает их число. Записи аудита остаются: из них стираются только
+// снимки события (Before и After), так что кто и когда менял событие, видно и
+// после очистки, а восстановить его уже нельзя.
+func (c *Calendar) PurgeTrash(now time.Time) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.retention <= 0 {
+		return 0, nil
+	}
+	var ids []int
+	for id, entries := range c.history {
+		last := entries[len(entries)-1]
+		if last.Action == AuditDeleted && last.Before != nil && c.expired(last.At, now) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	sort.Ints(ids)
+
+	if err := c.apply(Mutation{Op: OpPurge, Purged: ids, NextID: c.nextID}); err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		dropSnapshots(c.history[id])
+	}
+	return len(ids), nil
+}
+
+// dropSnapshots стирает снимки события из его записей аудита.
+func dropSnapshots(entries []AuditEntry) {
+	for i := range entries {
+		entries[i].Before = nil
+		entries[i].After = nil
+	}
+}
+
+// TrashJanitor раз в interval окончательно удаляет события, пролежавшие в
+// корзине дольше срока хранения.
+type TrashJanitor struct {
+	calendar *Calendar
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewTrashJanitor(calendar *Calendar, interval time.Duration) *TrashJanitor {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &TrashJanitor{calendar: calendar, interval: interval, now: time.Now}
+}
+
+func (j *TrashJanitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.tick(j.now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *TrashJanitor) tick(now time.Time) {
+	purged, err := j.calendar.PurgeTrash(now)
+	if err != nil {
+		slog.Error("trash purge failed", "error", err)
+		return
+	}
+	if purged > 0 {
+		slog.Info("purged trash", "events", purged)
+	}
+}
+
+// trash - GET /events/trash?user_id=.
+func (h *Handler) trash(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		writeCalendarError(w, r, invalidField("user_id", errors.New("must be an integer")))
+		return
+	}
+	writeJSON(w, response{Result: h.calendar.Trash(userID)}, http.StatusOK)
+}
+
+func (h *Handler) v2Trash(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["uid"])
+	writeJSON(w, h.calendar.Trash(userID), http.StatusOK)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCalendar_TrashAndPurge(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	calendar := NewCalendar()
+	calendar.now = func() time.Time { return now }
+	calendar.SetTrashRetention(7 * 24 * time.Hour)
+	date := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+
+	old, _ := calendar.CreateEvent(1, date, "Old")
+	series, _ := calendar.CreateEvent(1, date, "Standup", WithRecurrence(&Recurrence{Freq: FreqDaily, Count: 5}))
+	calendar.UpdateOccurrence(series.ID, 1, date.AddDate(0, 0, 1), date.AddDate(0, 0, 1).Add(time.Hour), "Moved standup")
+	kept, _ := calendar.CreateEvent(1, date, "Kept")
+
+	calendar.DeleteEvent(old.ID, 1)
+	now = now.Add(3 * 24 * time.Hour)
+	calendar.DeleteEvent(series.ID, 1)
+
+	trash := calendar.Trash(1)
+	if len(trash) != 2 || trash[0].Event.ID != series.ID || trash[1].Event.ID != old.ID {
+		t.Fatalf("Expected series then old event without detached occurrence, got %+v", trash)
+	}
+	if want := trash[1].DeletedAt.Add(7 * 24 * time.Hour); trash[1].PurgeAt == nil || !trash[1].PurgeAt.Equal(want) {
+		t.Errorf("Expected purge at %v, got %v", want, trash[1].PurgeAt)
+	}
+	if len(calendar.Trash(2)) != 0 {
+		t.Error("Expected another user's trash to be empty")
+	}
+
+	// срок хранения old истёк, но уборщик ещё не приходил
+	now = now.Add(5 * 24 * time.Hour)
+	if _, err := calendar.RestoreEvent(old.ID, 1); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound for expired event, got %v", err)
+	}
+	if trash := calendar.Trash(1); len(trash) != 1 || trash[0].Event.ID != series.ID {
+		t.Errorf("Expected only series in trash, got %+v", trash)
+	}
+
+	purged, err := calendar.PurgeTrash(now)
+	if err != nil || purged != 1 {
+		t.Fatalf("Expected 1 purged event, got %d (%v)", purged, err)
+	}
+	history, err := calendar.History(old.ID, 1)
+	if err != nil || len(history) != 2 || history[1].Action != AuditDeleted {
+		t.Fatalf("Expected audit entries to survive the purge, got %+v (%v)", history, err)
+	}
+	for _, entry := range history {
+		if entry.Before != nil || entry.After != nil {
+			t.Errorf("Expected snapshots to be purged, got %+v", entry)
+		}
+	}
+	if _, err := calendar.RestoreEvent(old.ID, 1); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected purged event to be unrestorable, got %v", err)
+	}
+	if purged, _ := calendar.PurgeTrash(now); purged != 0 {
+		t.Errorf("Expected purged event not to be purged again, got %d", purged)
+	}
+	if _, err := calendar.History(kept.ID, 1); err != nil {
+		t.Errorf("Expected live event history to stay, got %v", err)
+	}
+
+	if _, err := calendar.RestoreEvent(series.ID, 1); err != nil {
+		t.Fatalf("Expected series to be restorable, got %v", err)
+	}
+	if events := calendar.GetEventsForDay(1, date.AddDate(0, 0, 1)); len(events) != 1 || events[0].Title != "Moved standup" {
+		t.Errorf("Expected detached occurrence restored with series, got %v", events)
+	}
+}
+
+func TestFileStorage_PurgeSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	date := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+
+	storage, _ := NewFileStorage(dir, 100)
+	calendar, _ := NewCalendarWithStorage(storage)
+	calendar.now = func() time.Time { return now }
+	calendar.SetTrashRetention(time.Hour)
+	event, _ := calendar.CreateEvent(1, date, "Gone")
+	calendar.DeleteEvent(event.ID, 1)
+	if purged, _ := calendar.PurgeTrash(now.Add(2 * time.Hour)); purged != 1 {
+		t.Fatalf("Expected 1 purged event, got %d", purged)
+	}
+	storage.journal.Close()
+
+	storage, _ = NewFileStorage(dir, 100)
+	calendar, err := NewCalendarWithStorage(storage)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer calendar.Close()
+
+	history, err := calendar.History(event.ID, 1)
+	if err != nil || len(history) != 2 || history[0].After != nil || history[1].Before != nil {
+		t.Errorf("Expected audit entries without snapshots after restart, got %+v (%v)", history, err)
+	}
+	// номера аудита не выдаются повторно после очистки
+	next, _ := calendar.CreateEvent(1, date, "Next")
+	if history, _ := calendar.History(next.ID, 1); history[0].Seq != 3 {
+		t.Errorf("Expected audit seq 3, got %d", history[0].Seq)
+	}
+}
+
+func TestHandlerV2_Trash(t *testing.T) {
+	router := v2Router(NewCalendar())
+
+	v2Do(router, "POST", "/api/v2/users/1/events", `{"date":"2024-01-01T10:00:00Z","title":"Oops"}`, nil)
+	if rec := v2Do(router, "DELETE", "/api/v2/users/1/events/1", "", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", rec.Code)
+	}
+
+	rec := v2Do(router, "GET", "/api/v2/users/1/trash", "", nil)
+	var trash []TrashedEvent
+	if err := json.Unmarshal(rec.Body.Bytes(), &trash); err != nil || len(trash) != 1 || trash[0].Event.Title != "Oops" {
+		t.Fatalf("Expected deleted event in trash, got %s", rec.Body)
+	}
+	if trash[0].PurgeAt != nil {
+		t.Errorf("Expected no purge time without retention, got %v", trash[0].PurgeAt)
+	}
+
+	v2Do(router, "POST", "/api/v2/users/1/events/1/restore", "", nil)
+	if rec := v2Do(router, "GET", "/api/v2/users/1/trash", "", nil); rec.Body.String() != "[]\n" {
+		t.Errorf("Expected empty trash after restore, got %s", rec.Body)
+	}
+}
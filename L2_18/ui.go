@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var uiTemplates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+var (
+	weekdayNames = [7]string{"Воскресенье", "Понедельник", "Вторник", "Среда", "Четверг", "Пятница", "Суббота"}
+	monthNames   = [12]string{"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь", "Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь"}
+)
+
+// UI - HTML-страницы календаря для людей: сетка месяца и повестка недели с
+// формами создания, изменения и удаления. Формы не умеют передавать
+// Authorization, поэтому с -auth UI не подключается.
+type UI struct {
+	calendar *Calendar
+	now      func() time.Time
+}
+
+func NewUI(calendar *Calendar) *UI {
+	return &UI{calendar: calendar, now: time.Now}
+}
+
+func (u *UI) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/ui/month", u.month).Methods("GET")
+	r.HandleFunc("/ui/week", u.week).Methods("GET")
+	r.HandleFunc("/ui/events/create", u.createEvent).Methods("POST")
+	r.HandleFunc("/ui/events/update", u.updateEvent).Methods("POST")
+	r.HandleFunc("/ui/events/delete", u.deleteEvent).Methods("POST")
+}
+
+// uiEvent - событие в часовом поясе страницы вместе со значениями полей
+// формы изменения.
+type uiEvent struct {
+	Event
+	Start, End time.Time
+	// Occurrence - вхождение серии, к которому относится форма; пусто у
+	// обычных событий и отделённых вхождений
+	Occurrence string
+	InputType  string
+	DateValue  string
+	EndValue   string
+}
+
+type uiDay struct {
+	Date     time.Time
+	InPeriod bool
+	Today    bool
+	Link     string
+	Events   []uiEvent
+}
+
+type uiPage struct {
+	View     string
+	Heading  string
+	UserID   int
+	TZ       string
+	Date     time.Time
+	Error    string
+	Self     string
+	Prev     string
+	Next     string
+	Other    string
+	Weeks    [][]uiDay
+	Days     []uiDay
+	Weekdays [7]string
+}
+
+func uiLink(view string, userID int, date time.Time, tz string) string {
+	q := url.Values{"user_id": {strconv.Itoa(userID)}, "date": {date.Format("2006-01-02")}}
+	if tz != "UTC" {
+		q.Set("tz", tz)
+	}
+	return "/ui/" + view + "?" + q.Encode()
+}
+
+// parseUIQuery разбирает user_id, date и tz страницы; без date показывается
+// сегодняшний день.
+func (u *UI) parseUIQuery(r *http.Request) (uiPage, error) {
+	query := r.URL.Query()
+	userID, err := strconv.Atoi(query.Get("user_id"))
+	if err != nil || userID <= 0 {
+		return uiPage{}, invalidField("user_id", errors.New("must be a positive integer"))
+	}
+	loc, err := loadLocation(query.Get("tz"))
+	if err != nil {
+		return uiPage{}, invalidField("tz", err)
+	}
+	date := startOfDay(u.now().In(loc))
+	if s := query.Get("date"); s != "" {
+		if date, err = time.ParseInLocation("2006-01-02", s, loc); err != nil {
+			return uiPage{}, invalidField("date", errors.New("invalid date format, expected YYYY-MM-DD"))
+		}
+	}
+	return uiPage{UserID: userID, TZ: loc.String(), Date: date, Error: query.Get("error"), Weekdays: weekdayNames}, nil
+}
+
+func (u *UI) month(w http.ResponseWriter, r *http.Request) {
+	page, err := u.parseUIQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	loc := page.Date.Location()
+	first := time.Date(page.Date.Year(), page.Date.Month(), 1, 0, 0, 0, 0, loc)
+	// сетка - целые недели с понедельника перед первым числом; события
+	// соседних месяцев на её краях тоже показываются
+	gridStart := first.AddDate(0, 0, -((int(first.Weekday()) + 6) % 7))
+	gridEnd := gridStart
+	for gridEnd.Before(first.AddDate(0, 1, 0)) {
+		gridEnd = gridEnd.AddDate(0, 0, 7)
+	}
+	events := u.calendar.GetEventsInRange(page.UserID, gridStart, gridEnd)
+
+	page.View = "month"
+	page.Heading = monthNames[first.Month()-1] + " " + strconv.Itoa(first.Year())
+	page.Self = uiLink("month", page.UserID, first, page.TZ)
+	page.Prev = uiLink("month", page.UserID, first.AddDate(0, -1, 0), page.TZ)
+	page.Next = uiLink("month", page.UserID, first.AddDate(0, 1, 0), page.TZ)
+	page.Other = uiLink("week", page.UserID, first, page.TZ)
+
+	for day := gridStart; day.Before(gridEnd); {
+		week := make([]uiDay, 7)
+		for i := range week {
+			week[i] = u.day(day, page, events)
+			week[i].InPeriod = day.Month() == first.Month()
+			day = day.AddDate(0, 0, 1)
+		}
+		page.Weeks = append(page.Weeks, week)
+	}
+	u.render(w, page)
+}
+
+func (u *UI) week(w http.ResponseWriter, r *http.Request) {
+	page, err := u.parseUIQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	monday := page.Date.AddDate(0, 0, -((int(page.Date.Weekday()) + 6) % 7))
+	events := u.calendar.GetEventsForWeek(page.UserID, monday)
+
+	page.View = "week"
+	page.Heading = monday.Format("02.01") + " - " + monday.AddDate(0, 0, 6).Format("02.01.2006")
+	page.Self = uiLink("week", page.UserID, monday, page.TZ)
+	page.Prev = uiLink("week", page.UserID, monday.AddDate(0, 0, -7), page.TZ)
+	page.Next = uiLink("week", page.UserID, monday.AddDate(0, 0, 7), page.TZ)
+	page.Other = uiLink("month", page.UserID, monday, page.TZ)
+	for i := 0; i < 7; i++ {
+		day := u.day(monday.AddDate(0, 0, i), page, events)
+		day.InPeriod = true
+		page.Days = append(page.Days, day)
+	}
+	u.render(w, page)
+}
+
+// day собирает события, пересекающиеся с днём date. Многодневное событие
+// попадает в каждый свой день.
+func (u *UI) day(date time.Time, page uiPage, events []Event) uiDay {
+	next := date.AddDate(0, 0, 1)
+	today := startOfDay(u.now().In(date.Location()))
+	result := uiDay{Date: date, Today: date.Equal(today), Link: uiLink("week", page.UserID, date, page.TZ)}
+	for _, event := range events {
+		start, end := event.Date.In(date.Location()), event.End.In(date.Location())
+		if event.AllDay {
+			// дни события на весь день не зависят от часового пояса страницы
+			start = time.Date(event.Date.Year(), event.Date.Month(), event.Date.Day(), 0, 0, 0, 0, date.Location())
+			end = start.AddDate(0, 0, daysBetween(event.Date, event.End))
+		}
+		if !start.Before(next) || (end.After(start) && !end.After(date)) || (!end.After(start) && start.Before(date)) {
+			continue
+		}
+		result.Events = append(result.Events, uiEventFor(event, start, end))
+	}
+	return result
+}
+
+func uiEventFor(event Event, start, end time.Time) uiEvent {
+	view := uiEvent{Event: event, Start: start, InputType: "datetime-local"}
+	if end.After(start) {
+		view.End = end
+	}
+	if event.Recurrence != nil && event.RecurrenceID != nil {
+		view.Occurrence = event.RecurrenceID.Format(time.RFC3339)
+	}
+	if event.AllDay {
+		view.InputType = "date"
+		view.DateValue = start.Format("2006-01-02")
+		view.EndValue = end.AddDate(0, 0, -1).Format("2006-01-02")
+		return view
+	}
+	view.DateValue = start.Format("2006-01-02T15:04")
+	if !view.End.IsZero() {
+		view.EndValue = end.Format("2006-01-02T15:04")
+	}
+	return view
+}
+
+func (u *UI) render(w http.ResponseWriter, page uiPage) {
+	var buf bytes.Buffer
+	if err := uiTemplates.ExecuteTemplate(&buf, "layout", page); err != nil {
+		slog.Error("render page", "view", page.View, "error", err)
+		http.Error(w, "could not render page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+// uiForm - разобранная форма страницы: поля события в формате eventRequest и
+// адрес, куда вернуться.
+type uiForm struct {
+	req    eventRequest
+	loc    *time.Location
+	back   string
+	userID int
+}
+
+func parseUIForm(r *http.Request) (uiForm, error) {
+	var form uiForm
+	if err := r.ParseForm(); err != nil {
+		return form, err
+	}
+	form.back = r.PostForm.Get("return")
+	// возвращаемся только на свои страницы
+	if !strings.HasPrefix(form.back, "/ui/") || strings.HasPrefix(form.back, "/ui//") {
+		form.back = ""
+	}
+
+	var err error
+	if form.userID, err = strconv.Atoi(r.PostForm.Get("user_id")); err != nil {
+		return form, invalidField("user_id", errors.New("must be an integer"))
+	}
+	if form.loc, err = loadLocation(r.PostForm.Get("tz")); err != nil {
+		return form, invalidField("tz", err)
+	}
+	if form.back == "" {
+		form.back = uiLink("week", form.userID, time.Now().In(form.loc), form.loc.String())
+	}
+
+	form.req = eventRequest{
+		UserID:     form.userID,
+		Title:      r.PostForm.Get("title"),
+		Date:       r.PostForm.Get("date"),
+		End:        r.PostForm.Get("end"),
+		Duration:   r.PostForm.Get("duration"),
+		Location:   r.PostForm.Get("location"),
+		Occurrence: r.PostForm.Get("occurrence"),
+	}
+	form.req.ID, _ = strconv.Atoi(r.PostForm.Get("id"))
+	form.req.Version, _ = strconv.Atoi(r.PostForm.Get("version"))
+	return form, nil
+}
+
+// finish возвращает браузер на страницу формы (POST/Redirect/GET); ошибка
+// показывается на ней.
+func (u *UI) finish(w http.ResponseWriter, r *http.Request, form uiForm, err error) {
+	back := form.back
+	if err != nil {
+		if back == "" {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sep := "?"
+		if strings.Contains(back, "?") {
+			sep = "&"
+		}
+		back += sep + url.Values{"error": {err.Error()}}.Encode()
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// pageTime переводит время из формы, заданное в часовом поясе страницы, в
+// формат eventRequest; даты без времени остаются датами.
+func pageTime(value string, loc *time.Location) string {
+	t, allDay, err := parseEventTime(value, loc)
+	if err != nil || allDay {
+		return value
+	}
+	return t.Format(time.RFC3339)
+}
+
+func (u *UI) createEvent(w http.ResponseWriter, r *http.Request) {
+	form, err := parseUIForm(r)
+	if err == nil {
+		req := form.req
+		req.Timezone = form.loc.String()
+		var date time.Time
+		var opts []EventOption
+		if date, opts, err = parseEventRequest(&req); err == nil {
+			_, err = u.calendar.CreateEvent(form.userID, date, req.Title, opts...)
+		}
+	}
+	u.finish(w, r, form, err)
+}
+
+// updateEvent меняет название и время события, остальные поля сохраняются.
+// Для вхождения серии изменение отделяет его от серии.
+func (u *UI) updateEvent(w http.ResponseWriter, r *http.Request) {
+	form, err := parseUIForm(r)
+	if err == nil {
+		err = u.update(form)
+	}
+	u.finish(w, r, form, err)
+}
+
+func (u *UI) update(form uiForm) error {
+	current, err := u.calendar.GetEvent(form.req.ID, form.userID)
+	if err != nil {
+		return err
+	}
+	req := requestFromEvent(current)
+	req.Title = form.req.Title
+	req.Date = pageTime(form.req.Date, form.loc)
+	req.End = pageTime(form.req.End, form.loc)
+	if form.req.Occurrence != "" {
+		req.Recurrence = nil
+	}
+	date, opts, err := parseEventRequest(req)
+	if err != nil {
+		return err
+	}
+
+	if form.req.Occurrence == "" {
+		if form.req.Version != 0 {
+			opts = append(opts, IfVersion(form.req.Version))
+		}
+		_, err = u.calendar.UpdateEvent(current.ID, form.userID, date, req.Title, opts...)
+		return err
+	}
+	occurrence, err := time.Parse(time.RFC3339, form.req.Occurrence)
+	if err != nil {
+		return invalidField("occurrence", errors.New("invalid occurrence format, expected RFC 3339"))
+	}
+	_, err = u.calendar.UpdateOccurrence(current.ID, form.userID, occurrence, date, req.Title, opts...)
+	return err
+}
+
+func (u *UI) deleteEvent(w http.ResponseWriter, r *http.Request) {
+	form, err := parseUIForm(r)
+	if err == nil {
+		if form.req.Occurrence != "" {
+			var occurrence time.Time
+			if occurrence, err = time.Parse(time.RFC3339, form.req.Occurrence); err != nil {
+				err = invalidField("occurrence", errors.New("invalid occurrence format, expected RFC 3339"))
+			} else {
+				err = u.calendar.DeleteOccurrence(form.req.ID, form.userID, occurrence)
+			}
+		} else {
+			err = u.calendar.DeleteEventIfVersion(form.req.ID, form.userID, form.req.Version)
+		}
+	}
+	u.finish(w, r, form, err)
+}
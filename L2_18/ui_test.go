@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func uiRouter(calendar *Calendar) *mux.Router {
+	r := mux.NewRouter()
+	ui := NewUI(calendar)
+	ui.now = func() time.Time { return time.Date(2024, 3, 14, 12, 0, 0, 0, time.UTC) }
+	ui.RegisterRoutes(r)
+	return r
+}
+
+func uiGet(r http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	return rec
+}
+
+func uiPost(r http.Handler, path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUI_MonthAndWeek(t *testing.T) {
+	calendar := NewCalendar()
+	calendar.CreateEvent(1, time.Date(2024, 3, 14, 9, 0, 0, 0, time.UTC), "<Planning>", WithDuration(time.Hour))
+	calendar.CreateEvent(1, time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC), "Trip", WithAllDay(), WithEnd(time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)))
+	calendar.CreateEvent(1, time.Date(2024, 2, 27, 10, 0, 0, 0, time.UTC), "Retro", WithDuration(time.Hour))
+	router := uiRouter(calendar)
+
+	rec := uiGet(router, "/ui/month?user_id=1&tz=Europe/Moscow")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("Expected HTML page, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "Март 2024") || !strings.Contains(body, "12:00</span> &lt;Planning&gt;") {
+		t.Errorf("Expected current month with escaped event in page timezone, got %s", body)
+	}
+	// 30 и 31 марта в сетке месяца, 1 апреля - уже в следующей
+	if n := strings.Count(body, "Trip"); n != 2 {
+		t.Errorf("Expected all-day event on 2 days of the month, got %d", n)
+	}
+	// сетка начинается 26 февраля, и события этих дней тоже видны
+	if !strings.Contains(body, "Retro") {
+		t.Error("Expected event from the previous month's days of the grid")
+	}
+	if !strings.Contains(body, `href="/ui/month?date=2024-02-01&amp;tz=Europe%2FMoscow&amp;user_id=1"`) {
+		t.Error("Expected link to the previous month")
+	}
+
+	rec = uiGet(router, "/ui/week?user_id=1&date=2024-03-31")
+	body = rec.Body.String()
+	if !strings.Contains(body, "25.03 - 31.03.2024") || strings.Count(body, "<strong>Trip</strong>") != 2 {
+		t.Errorf("Expected week of March 25 with trip on weekend, got %s", body)
+	}
+
+	for _, path := range []string{"/ui/week", "/ui/week?user_id=1&date=14.03.2024", "/ui/month?user_id=1&tz=Mars/Olympus"} {
+		if rec := uiGet(router, path); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestUI_Forms(t *testing.T) {
+	calendar := NewCalendar()
+	router := uiRouter(calendar)
+	back := "/ui/week?user_id=1&date=2024-03-11&tz=Europe%2FMoscow"
+	page := url.Values{"user_id": {"1"}, "tz": {"Europe/Moscow"}, "return": {back}}
+	with := func(fields url.Values) url.Values {
+		form := url.Values{}
+		for k, v := range page {
+			form[k] = v
+		}
+		for k, v := range fields {
+			form[k] = v
+		}
+		return form
+	}
+
+	rec := uiPost(router, "/ui/events/create", with(url.Values{"title": {"Review"}, "date": {"2024-03-14T10:00"}, "duration": {"30m"}}))
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != back {
+		t.Fatalf("Expected redirect back, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	events := calendar.GetEvents(1)
+	if len(events) != 1 || !events[0].Date.Equal(time.Date(2024, 3, 14, 7, 0, 0, 0, time.UTC)) || events[0].Timezone != "Europe/Moscow" {
+		t.Fatalf("Expected event at 10:00 Moscow time, got %v", events)
+	}
+	event := events[0]
+
+	uiPost(router, "/ui/events/update", with(url.Values{"id": {"1"}, "version": {"1"}, "title": {"Review v2"}, "date": {"2024-03-14T11:00"}, "end": {"2024-03-14T12:00"}}))
+	updated, _ := calendar.GetEvent(event.ID, 1)
+	if updated.Title != "Review v2" || updated.Version != 2 || !updated.End.Equal(time.Date(2024, 3, 14, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected updated event %+v", updated)
+	}
+
+	// устаревшая форма не затирает изменения, ошибка показывается на странице
+	rec = uiPost(router, "/ui/events/delete", with(url.Values{"id": {"1"}, "version": {"1"}}))
+	location := rec.Header().Get("Location")
+	if !strings.HasPrefix(location, back+"&error=") {
+		t.Fatalf("Expected redirect with error, got %q", location)
+	}
+	if body := uiGet(router, location).Body.String(); !strings.Contains(body, `<div class="error">`) {
+		t.Error("Expected error banner on the page")
+	}
+	uiPost(router, "/ui/events/delete", with(url.Values{"id": {"1"}, "version": {"2"}}))
+	if _, err := calendar.GetEvent(event.ID, 1); err == nil {
+		t.Error("Expected event to be deleted")
+	}
+
+	// возврат только на страницы UI
+	rec = uiPost(router, "/ui/events/create", url.Values{"user_id": {"1"}, "return": {"//evil.example/ui/"}, "title": {"x"}, "date": {"2024-03-14"}})
+	if location := rec.Header().Get("Location"); !strings.HasPrefix(location, "/ui/week?") {
+		t.Errorf("Expected redirect to own page, got %q", location)
+	}
+}
+
+func TestUI_Occurrence(t *testing.T) {
+	calendar := NewCalendar()
+	router := uiRouter(calendar)
+	start := time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC)
+	series, _ := calendar.CreateEvent(1, start, "Standup", WithDuration(15*time.Minute), WithRecurrence(&Recurrence{Freq: FreqDaily, Count: 5}))
+
+	body := uiGet(router, "/ui/week?user_id=1&date=2024-03-11").Body.String()
+	if strings.Count(body, "Удалить это повторение") != 5 {
+		t.Fatalf("Expected 5 occurrence forms, got page %s", body)
+	}
+
+	form := url.Values{"user_id": {"1"}, "id": {"1"}, "occurrence": {"2024-03-12T09:00:00Z"}, "title": {"Moved"}, "date": {"2024-03-12T10:00"}, "end": {"2024-03-12T10:15"}}
+	uiPost(router, "/ui/events/update", form)
+	form = url.Values{"user_id": {"1"}, "id": {"1"}, "occurrence": {"2024-03-13T09:00:00Z"}}
+	uiPost(router, "/ui/events/delete", form)
+
+	events := calendar.GetEventsForWeek(1, start)
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events after detach and delete, got %v", events)
+	}
+	if events[1].Title != "Moved" || events[1].SeriesID != series.ID || events[1].Date.Hour() != 10 {
+		t.Errorf("Expected detached occurrence at 10:00, got %+v", events[1])
+	}
+}
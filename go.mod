@@ -6,11 +6,15 @@ require (
 	github.com/beevik/ntp v1.4.3
 	github.com/gorilla/mux v1.8.1
 	github.com/spf13/cobra v1.10.2
-	golang.org/x/net v0.25.0
+	golang.org/x/net v0.38.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250324211829-b45e905df463
+	google.golang.org/grpc v1.73.0
+	google.golang.org/protobuf v1.36.6
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
-	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
 )